@@ -4,31 +4,44 @@ import { useState, useEffect } from 'react'
 import { useRouter } from 'next/navigation'
 import Link from 'next/link'
 import { Container } from '@/components/Container'
-import { api, type User, type Order } from '@/lib/api'
+import { TelegramLoginButton } from '@/components/TelegramLoginButton'
+import { api, saveSession, clearSession, hasSession, type User, type Order, type Session, type TelegramAuthData } from '@/lib/api'
 
 export default function AccountPage() {
   const router = useRouter()
   const [user, setUser] = useState<User | null>(null)
   const [orders, setOrders] = useState<Order[]>([])
+  const [sessions, setSessions] = useState<Session[]>([])
   const [loading, setLoading] = useState(true)
   const [error, setError] = useState<string | null>(null)
   const [isAuthorized, setIsAuthorized] = useState(false)
+  const [verifyMessage, setVerifyMessage] = useState<string | null>(null)
+  const [passwordMessage, setPasswordMessage] = useState<string | null>(null)
+  // 2FA: секрет на время настройки, коды восстановления показываем один раз
+  const [totpSetup, setTotpSetup] = useState<{ secret: string; uri: string } | null>(null)
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null)
+  const [twoFactorMessage, setTwoFactorMessage] = useState<string | null>(null)
+  const [dataMessage, setDataMessage] = useState<string | null>(null)
+  // Привязка Telegram: пароль вводится до нажатия на виджет, reauth — уже в колбэке виджета
+  const [telegramPassword, setTelegramPassword] = useState('')
+  const [telegramMessage, setTelegramMessage] = useState<string | null>(null)
 
   useEffect(() => {
     async function loadAccount() {
-      const token = localStorage.getItem('access_token')
-      if (!token) {
+      if (!hasSession()) {
         router.push('/login')
         return
       }
 
       try {
-        const [userData, ordersData] = await Promise.all([
+        const [userData, ordersData, sessionsData] = await Promise.all([
           api.getAccount(),
           api.getOrders().catch(() => ({ items: [] })), // Заказы могут быть пустыми
+          api.getSessions().catch(() => ({ items: [] })),
         ])
         setUser(userData.user)
         setOrders(ordersData.items || [])
+        setSessions(sessionsData.items || [])
         setIsAuthorized(true)
       } catch (e) {
         const errorMsg = e instanceof Error ? e.message : 'Ошибка загрузки данных'
@@ -47,8 +60,151 @@ export default function AccountPage() {
     loadAccount()
   }, [])
 
-  const handleLogout = () => {
-    localStorage.removeItem('access_token')
+  const handleResendVerification = async () => {
+    try {
+      await api.resendVerification()
+      setVerifyMessage('Письмо отправлено, проверьте почту')
+    } catch (err: any) {
+      setVerifyMessage(err?.message || 'Не удалось отправить письмо')
+    }
+  }
+
+  const handleChangePassword = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    const form = e.currentTarget
+    const formData = new FormData(form)
+    try {
+      await api.changePassword(
+        formData.get('currentPassword') as string,
+        formData.get('newPassword') as string,
+      )
+      form.reset()
+      setPasswordMessage('Пароль изменён. На других устройствах нужно будет войти заново')
+    } catch (err: any) {
+      setPasswordMessage(err?.message || 'Не удалось сменить пароль')
+    }
+  }
+
+  // Настройка 2FA требует недавнего ввода пароля — сначала reauth, потом setup
+  const handleSetupTwoFactor = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    const formData = new FormData(e.currentTarget)
+    try {
+      const tokens = await api.reauthenticate(formData.get('password') as string)
+      saveSession(tokens)
+      setTotpSetup(await api.setupTwoFactor())
+      setTwoFactorMessage(null)
+    } catch (err: any) {
+      setTwoFactorMessage(err?.message || 'Не удалось начать настройку')
+    }
+  }
+
+  const handleEnableTwoFactor = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    const formData = new FormData(e.currentTarget)
+    try {
+      const res = await api.enableTwoFactor(formData.get('code') as string)
+      saveSession(res)
+      setRecoveryCodes(res.recoveryCodes)
+      setTotpSetup(null)
+      setUser((prev) => (prev ? { ...prev, totpEnabled: true } : prev))
+      setTwoFactorMessage(null)
+    } catch (err: any) {
+      setTwoFactorMessage(err?.message || 'Неверный код')
+    }
+  }
+
+  const handleDisableTwoFactor = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    const formData = new FormData(e.currentTarget)
+    try {
+      const tokens = await api.reauthenticate(formData.get('password') as string)
+      saveSession(tokens)
+      await api.disableTwoFactor(formData.get('code') as string)
+      setUser((prev) => (prev ? { ...prev, totpEnabled: false } : prev))
+      setRecoveryCodes(null)
+      setTwoFactorMessage('Двухфакторная аутентификация выключена')
+    } catch (err: any) {
+      setTwoFactorMessage(err?.message || 'Не удалось выключить')
+    }
+  }
+
+  const handleLinkTelegram = async (data: TelegramAuthData) => {
+    try {
+      const tokens = await api.reauthenticate(telegramPassword)
+      saveSession(tokens)
+      const res = await api.linkTelegram(data)
+      setUser(res.user)
+      setTelegramPassword('')
+      setTelegramMessage(null)
+    } catch (err: any) {
+      setTelegramMessage(err?.message || 'Не удалось привязать Telegram')
+    }
+  }
+
+  const handleUnlinkTelegram = async () => {
+    try {
+      const res = await api.unlinkTelegram()
+      setUser(res.user)
+      setTelegramMessage(null)
+    } catch (err: any) {
+      setTelegramMessage(err?.message || 'Не удалось отвязать Telegram')
+    }
+  }
+
+  // Выгрузка данных: сервер отдаёт JSON, сохраняем его файлом
+  const handleExportData = async () => {
+    try {
+      const data = await api.exportAccountData()
+      const blob = new Blob([JSON.stringify(data, null, 2)], { type: 'application/json' })
+      const url = URL.createObjectURL(blob)
+      const link = document.createElement('a')
+      link.href = url
+      link.download = `socialsh-data-${new Date().toISOString().slice(0, 10)}.json`
+      link.click()
+      URL.revokeObjectURL(url)
+      setDataMessage(null)
+    } catch (err: any) {
+      setDataMessage(err?.message || 'Не удалось выгрузить данные')
+    }
+  }
+
+  // Удаление аккаунта: у аккаунта с email — reauth паролем; вошедшим через Telegram
+  // хватает свежего входа (сервер сам ответит, если вход был давно)
+  const handleDeleteAccount = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    if (!confirm('Удалить аккаунт? Это нельзя отменить: профиль удалится, в заказах не останется ваших контактов.')) return
+    const formData = new FormData(e.currentTarget)
+    try {
+      if (user?.email) {
+        saveSession(await api.reauthenticate(formData.get('password') as string))
+      }
+      await api.deleteAccount()
+      clearSession()
+      router.push('/')
+      router.refresh()
+    } catch (err: any) {
+      setDataMessage(err?.message || 'Не удалось удалить аккаунт')
+    }
+  }
+
+  const handleRevokeSession = async (session: Session) => {
+    if (session.current) {
+      return handleLogout()
+    }
+    await api.revokeSession(session.id).catch(() => {})
+    setSessions((prev) => prev.filter((s) => s.id !== session.id))
+  }
+
+  const handleRevokeOtherSessions = async () => {
+    await api.revokeOtherSessions().catch(() => {})
+    setSessions((prev) => prev.filter((s) => s.current))
+  }
+
+  const handleLogout = async () => {
+    // Токен удаляем в любом случае, даже если сервер недоступен
+    await api.logout().catch(() => {})
+    clearSession()
     router.push('/')
     router.refresh()
   }
@@ -104,10 +260,165 @@ export default function AccountPage() {
           <h2 style={{ fontSize: '1.25rem', marginBottom: '1rem' }}>Профиль</h2>
           <div style={{ padding: '1.5rem', border: '1px solid var(--line)', marginBottom: '2rem' }}>
             <p><strong>Имя:</strong> {user?.name || 'Не указано'}</p>
-            <p><strong>Email:</strong> {user?.email}</p>
+            <p>
+              <strong>Email:</strong> {user?.email || 'Не указан'}
+              {user?.email && !user.emailVerified && ' (не подтверждён)'}
+            </p>
+            {user?.email && !user.emailVerified && (
+              <p style={{ fontSize: '0.875rem', color: 'var(--muted)' }}>
+                Мы отправили ссылку для подтверждения на вашу почту.{' '}
+                <button
+                  onClick={handleResendVerification}
+                  style={{
+                    background: 'transparent',
+                    border: 'none',
+                    padding: 0,
+                    color: 'var(--fg)',
+                    textDecoration: 'underline',
+                    cursor: 'pointer',
+                  }}
+                >
+                  Отправить ещё раз
+                </button>
+                {verifyMessage && <span> — {verifyMessage}</span>}
+              </p>
+            )}
             <p><strong>Роль:</strong> {user?.role}</p>
           </div>
 
+          <h2 style={{ fontSize: '1.25rem', marginBottom: '1rem' }}>Смена пароля</h2>
+          <form
+            onSubmit={handleChangePassword}
+            style={{ padding: '1.5rem', border: '1px solid var(--line)', marginBottom: '2rem', display: 'flex', flexDirection: 'column', gap: '0.75rem', maxWidth: '24rem' }}
+          >
+            <input type="password" name="currentPassword" required placeholder="Текущий пароль" />
+            <input type="password" name="newPassword" required minLength={8} maxLength={72} placeholder="Новый пароль" />
+            <button
+              type="submit"
+              style={{
+                padding: '0.5rem 1rem',
+                background: 'transparent',
+                border: '1px solid var(--line)',
+                color: 'var(--fg)',
+                fontFamily: 'var(--font-mono)',
+                cursor: 'pointer',
+              }}
+            >
+              Сменить пароль
+            </button>
+            {passwordMessage && <p style={{ fontSize: '0.875rem', color: 'var(--muted)' }}>{passwordMessage}</p>}
+          </form>
+
+          <h2 style={{ fontSize: '1.25rem', marginBottom: '1rem' }}>Telegram</h2>
+          <div style={{ padding: '1.5rem', border: '1px solid var(--line)', marginBottom: '2rem', display: 'flex', flexDirection: 'column', gap: '0.75rem', maxWidth: '32rem' }}>
+            {user?.telegramId ? (
+              <>
+                <p>Привязан{user.telegramUsername && <> — @{user.telegramUsername}</>}. Можно входить через Telegram.</p>
+                <button
+                  onClick={handleUnlinkTelegram}
+                  style={{ alignSelf: 'flex-start', padding: '0.5rem 1rem', background: 'transparent', border: '1px solid var(--line)', color: 'var(--fg)', fontFamily: 'var(--font-mono)', cursor: 'pointer' }}
+                >
+                  Отвязать
+                </button>
+              </>
+            ) : (
+              <>
+                <p>Привяжите Telegram, чтобы входить без пароля. Введите пароль и нажмите кнопку.</p>
+                <input
+                  type="password"
+                  required
+                  placeholder="Пароль"
+                  value={telegramPassword}
+                  onChange={(e) => setTelegramPassword(e.target.value)}
+                />
+                <TelegramLoginButton onAuth={handleLinkTelegram} />
+              </>
+            )}
+            {telegramMessage && <p style={{ fontSize: '0.875rem', color: 'var(--muted)' }}>{telegramMessage}</p>}
+          </div>
+
+          <h2 style={{ fontSize: '1.25rem', marginBottom: '1rem' }}>Двухфакторная аутентификация</h2>
+          <div style={{ padding: '1.5rem', border: '1px solid var(--line)', marginBottom: '2rem', display: 'flex', flexDirection: 'column', gap: '0.75rem', maxWidth: '32rem' }}>
+            {recoveryCodes && (
+              <div>
+                <p>Сохраните коды восстановления — каждый срабатывает один раз, больше мы их не покажем:</p>
+                <pre style={{ fontFamily: 'var(--font-mono)' }}>{recoveryCodes.join('\n')}</pre>
+              </div>
+            )}
+            {user?.totpEnabled ? (
+              <form onSubmit={handleDisableTwoFactor} style={{ display: 'flex', flexDirection: 'column', gap: '0.75rem' }}>
+                <p>Включена. Чтобы выключить, введите пароль и код из приложения.</p>
+                <input type="password" name="password" required placeholder="Пароль" />
+                <input type="text" name="code" required placeholder="Код из приложения" autoComplete="one-time-code" />
+                <button type="submit" style={{ padding: '0.5rem 1rem', background: 'transparent', border: '1px solid var(--line)', color: 'var(--fg)', fontFamily: 'var(--font-mono)', cursor: 'pointer' }}>
+                  Выключить
+                </button>
+              </form>
+            ) : totpSetup ? (
+              <form onSubmit={handleEnableTwoFactor} style={{ display: 'flex', flexDirection: 'column', gap: '0.75rem' }}>
+                <p>Добавьте аккаунт в приложение-аутентификатор по ссылке или вручную ключом:</p>
+                <a href={totpSetup.uri} style={{ wordBreak: 'break-all' }}>{totpSetup.uri}</a>
+                <code style={{ fontFamily: 'var(--font-mono)' }}>{totpSetup.secret}</code>
+                <input type="text" name="code" required placeholder="Код из приложения" autoComplete="one-time-code" />
+                <button type="submit" style={{ padding: '0.5rem 1rem', background: 'transparent', border: '1px solid var(--line)', color: 'var(--fg)', fontFamily: 'var(--font-mono)', cursor: 'pointer' }}>
+                  Включить
+                </button>
+              </form>
+            ) : (
+              <form onSubmit={handleSetupTwoFactor} style={{ display: 'flex', flexDirection: 'column', gap: '0.75rem' }}>
+                <p>Вход будет требовать код из приложения (Google Authenticator, 1Password и т.п.).</p>
+                <input type="password" name="password" required placeholder="Пароль" />
+                <button type="submit" style={{ padding: '0.5rem 1rem', background: 'transparent', border: '1px solid var(--line)', color: 'var(--fg)', fontFamily: 'var(--font-mono)', cursor: 'pointer' }}>
+                  Настроить
+                </button>
+              </form>
+            )}
+            {twoFactorMessage && <p style={{ fontSize: '0.875rem', color: 'var(--muted)' }}>{twoFactorMessage}</p>}
+          </div>
+
+          <h2 style={{ fontSize: '1.25rem', marginBottom: '1rem' }}>Где выполнен вход</h2>
+          <div style={{ padding: '1.5rem', border: '1px solid var(--line)', marginBottom: '2rem', display: 'flex', flexDirection: 'column', gap: '1rem' }}>
+            {sessions.map((session) => (
+              <div key={session.id} style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', gap: '1rem' }}>
+                <div>
+                  <div>{session.userAgent || 'Неизвестное устройство'}{session.current && ' (это устройство)'}</div>
+                  <div style={{ fontSize: '0.875rem', color: 'var(--muted)' }}>
+                    {session.ip} · активность {new Date(session.lastUsedAt).toLocaleString('ru-RU')}
+                  </div>
+                </div>
+                <button
+                  onClick={() => handleRevokeSession(session)}
+                  style={{
+                    padding: '0.5rem 1rem',
+                    background: 'transparent',
+                    border: '1px solid var(--line)',
+                    color: 'var(--fg)',
+                    fontFamily: 'var(--font-mono)',
+                    cursor: 'pointer',
+                  }}
+                >
+                  Завершить
+                </button>
+              </div>
+            ))}
+            {sessions.length > 1 && (
+              <button
+                onClick={handleRevokeOtherSessions}
+                style={{
+                  alignSelf: 'flex-start',
+                  padding: '0.5rem 1rem',
+                  background: 'transparent',
+                  border: '1px solid var(--line)',
+                  color: 'var(--fg)',
+                  fontFamily: 'var(--font-mono)',
+                  cursor: 'pointer',
+                }}
+              >
+                Выйти на всех других устройствах
+              </button>
+            )}
+          </div>
+
           <h2 style={{ fontSize: '1.25rem', marginBottom: '1rem' }}>Заказы ({orders.length})</h2>
           {orders.length === 0 ? (
             <p className="lead">У вас пока нет заказов</p>
@@ -141,6 +452,25 @@ export default function AccountPage() {
               ))}
             </div>
           )}
+
+          <h2 style={{ fontSize: '1.25rem', margin: '2rem 0 1rem' }}>Мои данные</h2>
+          <div style={{ padding: '1.5rem', border: '1px solid var(--line)', display: 'flex', flexDirection: 'column', gap: '0.75rem', maxWidth: '32rem' }}>
+            <p>Скачайте всё, что магазин о вас хранит: профиль, заказы, адреса доставки, устройства входа.</p>
+            <button
+              onClick={handleExportData}
+              style={{ alignSelf: 'flex-start', padding: '0.5rem 1rem', background: 'transparent', border: '1px solid var(--line)', color: 'var(--fg)', fontFamily: 'var(--font-mono)', cursor: 'pointer' }}
+            >
+              Скачать данные
+            </button>
+            <form onSubmit={handleDeleteAccount} style={{ display: 'flex', flexDirection: 'column', gap: '0.75rem' }}>
+              <p>Удаление аккаунта необратимо. Заказы останутся в учёте магазина, но без ваших имени и контактов.</p>
+              {user?.email && <input type="password" name="password" required placeholder="Пароль" />}
+              <button type="submit" style={{ alignSelf: 'flex-start', padding: '0.5rem 1rem', background: 'transparent', border: '1px solid var(--line)', color: 'var(--fg)', fontFamily: 'var(--font-mono)', cursor: 'pointer' }}>
+                Удалить аккаунт
+              </button>
+            </form>
+            {dataMessage && <p style={{ fontSize: '0.875rem', color: 'var(--muted)' }}>{dataMessage}</p>}
+          </div>
         </div>
       </Container>
     </section>
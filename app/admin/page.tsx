@@ -1,19 +1,29 @@
 'use client'
 
 import { useState, useEffect } from 'react'
-import { api, type Product, type GalleryItem, type Page } from '@/lib/api'
+import { api, saveSession, clearSession, hasSession, type Product, type GalleryItem, type Page } from '@/lib/api'
 import styles from './page.module.css'
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:3001'
 
+// Какое право нужно для вкладки (роли и права — backend/internal/auth)
+const TAB_PERMISSIONS = {
+  products: 'products:write',
+  gallery: 'gallery:write',
+  pages: 'pages:write',
+} as const
+type Tab = keyof typeof TAB_PERMISSIONS
+
 export default function AdminPage() {
-  const [token, setToken] = useState<string | null>(null)
+  const [signedIn, setSignedIn] = useState(false)
   const [products, setProducts] = useState<Product[]>([])
   const [gallery, setGallery] = useState<GalleryItem[]>([])
   const [pages, setPages] = useState<Page[]>([])
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState<string | null>(null)
-  const [activeTab, setActiveTab] = useState<'products' | 'gallery' | 'pages'>('products')
+  const [activeTab, setActiveTab] = useState<Tab>('products')
+  const [permissions, setPermissions] = useState<string[]>([])
+  const [mfaChallenge, setMfaChallenge] = useState<string | null>(null)
   const [editingProduct, setEditingProduct] = useState<Product | null>(null)
   const [editingGallery, setEditingGallery] = useState<GalleryItem | null>(null)
   const [editingPage, setEditingPage] = useState<Page | null>(null)
@@ -26,28 +36,24 @@ export default function AdminPage() {
   const [uploadingGalleryImage, setUploadingGalleryImage] = useState(false)
 
   useEffect(() => {
-    const savedToken = localStorage.getItem('access_token')
-    if (savedToken) {
-      checkAdminAndSetToken(savedToken)
+    if (hasSession()) {
+      checkAdmin()
     }
   }, [])
 
-  const checkAdminAndSetToken = async (t: string) => {
+  const checkAdmin = async () => {
     try {
-      // Временно устанавливаем токен для проверки
-      const oldToken = localStorage.getItem('access_token')
-      localStorage.setItem('access_token', t)
-      
       const res = await api.isAdmin()
       if (res.isAdmin) {
-        setToken(t)
+        setPermissions(res.permissions)
+        setSignedIn(true)
       } else {
-        setToken(null)
-        localStorage.removeItem('access_token')
+        setSignedIn(false)
+        clearSession()
       }
     } catch (err) {
-      setToken(null)
-      localStorage.removeItem('access_token')
+      setSignedIn(false)
+      clearSession()
     }
   }
 
@@ -59,16 +65,22 @@ export default function AdminPage() {
     const formData = new FormData(e.currentTarget)
     const email = formData.get('email') as string
     const password = formData.get('password') as string
+    const code = formData.get('code') as string
 
     try {
-      const data = await api.signIn(email, password)
-      setToken(data.access)
-      localStorage.setItem('access_token', data.access)
+      const data = mfaChallenge ? await api.verifyMfa(mfaChallenge, code) : await api.signIn(email, password)
+      if (data.mfaRequired && data.challenge) {
+        setMfaChallenge(data.challenge)
+        return
+      }
+      saveSession(data)
+      setSignedIn(true)
 
       const adminRes = await api.isAdmin()
       if (!adminRes.isAdmin) {
         throw new Error('У вас нет прав администратора')
       }
+      setPermissions(adminRes.permissions)
     } catch (err: any) {
       setError(err.message || 'Ошибка входа')
     } finally {
@@ -77,7 +89,7 @@ export default function AdminPage() {
   }
 
   const loadProducts = async () => {
-    if (!token) return
+    if (!signedIn) return
     setLoading(true)
     setError(null)
     try {
@@ -87,8 +99,8 @@ export default function AdminPage() {
       const errorMsg = err?.message || 'Ошибка загрузки товаров'
       // Если 401/403 - токен невалидный или нет прав, выходим
       if (errorMsg.includes('401') || errorMsg.includes('403') || errorMsg.includes('Unauthorized') || errorMsg.includes('Forbidden')) {
-        setToken(null)
-        localStorage.removeItem('access_token')
+        setSignedIn(false)
+        clearSession()
         setError('Сессия истекла. Войдите заново.')
       } else {
         setError(errorMsg)
@@ -99,7 +111,7 @@ export default function AdminPage() {
   }
 
   const loadGallery = async () => {
-    if (!token) return
+    if (!signedIn) return
     setLoading(true)
     setError(null)
     try {
@@ -108,8 +120,8 @@ export default function AdminPage() {
     } catch (err: any) {
       const errorMsg = err?.message || 'Ошибка загрузки галереи'
       if (errorMsg.includes('401') || errorMsg.includes('403')) {
-        setToken(null)
-        localStorage.removeItem('access_token')
+        setSignedIn(false)
+        clearSession()
         setError('Сессия истекла. Войдите заново.')
       } else {
         setError(errorMsg)
@@ -120,7 +132,7 @@ export default function AdminPage() {
   }
 
   const loadPages = async () => {
-    if (!token) return
+    if (!signedIn) return
     setLoading(true)
     setError(null)
     try {
@@ -129,8 +141,8 @@ export default function AdminPage() {
     } catch (err: any) {
       const errorMsg = err?.message || 'Ошибка загрузки страниц'
       if (errorMsg.includes('401') || errorMsg.includes('403')) {
-        setToken(null)
-        localStorage.removeItem('access_token')
+        setSignedIn(false)
+        clearSession()
         setError('Сессия истекла. Войдите заново.')
       } else {
         setError(errorMsg)
@@ -140,11 +152,20 @@ export default function AdminPage() {
     }
   }
 
+  // Вкладки, на которые у роли есть права; открытая вкладка недоступна — переключаем на первую доступную
+  const allowedTabs = (Object.keys(TAB_PERMISSIONS) as Tab[]).filter((tab) => permissions.includes(TAB_PERMISSIONS[tab]))
   useEffect(() => {
-    if (token && activeTab === 'products') loadProducts()
-    if (token && activeTab === 'gallery') loadGallery()
-    if (token && activeTab === 'pages') loadPages()
-  }, [token, activeTab])
+    if (allowedTabs.length > 0 && !allowedTabs.includes(activeTab)) {
+      setActiveTab(allowedTabs[0])
+    }
+  }, [permissions])
+
+  useEffect(() => {
+    if (!signedIn || !allowedTabs.includes(activeTab)) return
+    if (signedIn && activeTab === 'products') loadProducts()
+    if (signedIn && activeTab === 'gallery') loadGallery()
+    if (signedIn && activeTab === 'pages') loadPages()
+  }, [signedIn, activeTab, permissions])
 
   const handleImageUpload = async (files: FileList | null) => {
     if (!files || files.length === 0) return
@@ -154,24 +175,7 @@ export default function AdminPage() {
 
     try {
       const uploadPromises = Array.from(files).map(async (file) => {
-        const formData = new FormData()
-        formData.append('file', file)
-
-        const token = localStorage.getItem('access_token')
-        const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:3001'}/api/admin/upload/product`, {
-          method: 'POST',
-          headers: {
-            'Authorization': `Bearer ${token}`,
-          },
-          body: formData,
-        })
-
-        if (!response.ok) {
-          const error = await response.json()
-          throw new Error(error.error || 'Ошибка загрузки')
-        }
-
-        const data = await response.json()
+        const data = await api.adminUploadImage('product', file)
         return data.url
       })
 
@@ -195,25 +199,7 @@ export default function AdminPage() {
     setError(null)
 
     try {
-      const file = files[0]
-      const formData = new FormData()
-      formData.append('file', file)
-
-      const token = localStorage.getItem('access_token')
-      const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:3001'}/api/admin/upload/gallery`, {
-        method: 'POST',
-        headers: {
-          'Authorization': `Bearer ${token}`,
-        },
-        body: formData,
-      })
-
-      if (!response.ok) {
-        const error = await response.json()
-        throw new Error(error.error || 'Ошибка загрузки')
-      }
-
-      const data = await response.json()
+      const data = await api.adminUploadImage('gallery', files[0])
       setUploadedGalleryImage(data.url)
     } catch (err: any) {
       setError(err.message || 'Ошибка загрузки изображения')
@@ -430,13 +416,19 @@ export default function AdminPage() {
     }
   }
 
-  if (!token) {
+  if (!signedIn) {
     return (
       <div className={styles.loginContainer}>
         <h1>Админ-панель</h1>
         <form onSubmit={handleLogin} className={styles.loginForm}>
-          <input type="email" name="email" placeholder="Email" required />
-          <input type="password" name="password" placeholder="Пароль" required />
+          {mfaChallenge ? (
+            <input type="text" name="code" placeholder="Код из приложения" required autoComplete="one-time-code" autoFocus />
+          ) : (
+            <>
+              <input type="email" name="email" placeholder="Email" required />
+              <input type="password" name="password" placeholder="Пароль" required />
+            </>
+          )}
           <button type="submit" disabled={loading}>
             {loading ? 'Вход...' : 'Войти'}
           </button>
@@ -451,19 +443,25 @@ export default function AdminPage() {
     <div className={styles.container}>
       <header className={styles.header}>
         <h1>Админ-панель</h1>
-        <button onClick={() => { setToken(null); localStorage.removeItem('access_token') }}>Выйти</button>
+        <button onClick={() => { api.logout().catch(() => {}).finally(() => { setSignedIn(false); clearSession() }) }}>Выйти</button>
       </header>
 
       <nav className={styles.tabs}>
-        <button className={activeTab === 'products' ? styles.active : ''} onClick={() => setActiveTab('products')}>
-          Товары
-        </button>
-        <button className={activeTab === 'gallery' ? styles.active : ''} onClick={() => setActiveTab('gallery')}>
-          Галерея
-        </button>
-        <button className={activeTab === 'pages' ? styles.active : ''} onClick={() => setActiveTab('pages')}>
-          Страницы
-        </button>
+        {allowedTabs.includes('products') && (
+          <button className={activeTab === 'products' ? styles.active : ''} onClick={() => setActiveTab('products')}>
+            Товары
+          </button>
+        )}
+        {allowedTabs.includes('gallery') && (
+          <button className={activeTab === 'gallery' ? styles.active : ''} onClick={() => setActiveTab('gallery')}>
+            Галерея
+          </button>
+        )}
+        {allowedTabs.includes('pages') && (
+          <button className={activeTab === 'pages' ? styles.active : ''} onClick={() => setActiveTab('pages')}>
+            Страницы
+          </button>
+        )}
       </nav>
 
       {error && <div className={styles.error}>{error}</div>}
@@ -23,14 +23,14 @@ export default function CartPage() {
     setCart(getCart())
   }, [])
 
-  const handleRemove = (productId: string) => {
-    removeFromCart(productId)
+  const handleRemove = (productId: string, variantId?: string) => {
+    removeFromCart(productId, variantId)
     setCart(getCart())
     window.dispatchEvent(new Event('cartUpdated'))
   }
 
-  const handleQuantityChange = (productId: string, quantity: number) => {
-    updateCartItemQuantity(productId, quantity)
+  const handleQuantityChange = (productId: string, quantity: number, variantId?: string) => {
+    updateCartItemQuantity(productId, quantity, variantId)
     setCart(getCart())
     window.dispatchEvent(new Event('cartUpdated'))
   }
@@ -61,7 +61,7 @@ export default function CartPage() {
         <div className={styles.cart}>
           <div className={styles.items}>
             {cart.map((item) => (
-              <div key={item.productId} className={styles.item}>
+              <div key={`${item.productId}:${item.variantId || ''}`} className={styles.item}>
                 <Link href={`/shop/${item.slug}`} className={styles.imageLink}>
                   <Image
                     src={getImageUrl(item.image)}
@@ -82,7 +82,7 @@ export default function CartPage() {
 
                 <div className={styles.quantity}>
                   <button
-                    onClick={() => handleQuantityChange(item.productId, item.quantity - 1)}
+                    onClick={() => handleQuantityChange(item.productId, item.quantity - 1, item.variantId)}
                     className={styles.quantityButton}
                     disabled={item.quantity <= 1}
                   >
@@ -90,7 +90,7 @@ export default function CartPage() {
                   </button>
                   <span className={styles.quantityValue}>{item.quantity}</span>
                   <button
-                    onClick={() => handleQuantityChange(item.productId, item.quantity + 1)}
+                    onClick={() => handleQuantityChange(item.productId, item.quantity + 1, item.variantId)}
                     className={styles.quantityButton}
                   >
                     +
@@ -102,7 +102,7 @@ export default function CartPage() {
                 </div>
 
                 <button
-                  onClick={() => handleRemove(item.productId)}
+                  onClick={() => handleRemove(item.productId, item.variantId)}
                   className={styles.removeButton}
                   aria-label="Удалить"
                 >
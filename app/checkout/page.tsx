@@ -1,18 +1,27 @@
 'use client'
 
-import { useState, useEffect } from 'react'
+import { useState, useEffect, useRef } from 'react'
 import { useRouter } from 'next/navigation'
 import { Container } from '@/components/Container'
 import { getCart, clearCart, getCartTotalPrice, type CartItem } from '@/lib/cart'
-import { api, formatPrice } from '@/lib/api'
+import { api, formatPrice, type ShippingMethod, type ShippingQuote } from '@/lib/api'
 import styles from './page.module.css'
 
+// Человекочитаемые названия зон доставки (ключи — zone из правил способов доставки)
+const ZONE_TITLES: Record<string, string> = {
+  moscow: 'Москва',
+  russia: 'Россия',
+}
+
 export default function CheckoutPage() {
   const router = useRouter()
   const [cart, setCart] = useState<CartItem[]>([])
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState<string | null>(null)
   const [success, setSuccess] = useState(false)
+  // Один ключ на попытку оформления: если сеть оборвалась и покупатель жмёт ещё раз,
+  // сервер вернёт уже созданный заказ, а не создаст второй
+  const idempotencyKey = useRef(crypto.randomUUID())
 
   const [formData, setFormData] = useState({
     name: '',
@@ -23,6 +32,12 @@ export default function CheckoutPage() {
     comment: '',
   })
 
+  // Доставка: зоны берём из правил способов, стоимость считает сервер
+  const [shippingMethods, setShippingMethods] = useState<ShippingMethod[]>([])
+  const [zone, setZone] = useState('')
+  const [quotes, setQuotes] = useState<ShippingQuote[]>([])
+  const [methodId, setMethodId] = useState('')
+
   useEffect(() => {
     const cartItems = getCart()
     if (cartItems.length === 0) {
@@ -30,8 +45,35 @@ export default function CheckoutPage() {
       return
     }
     setCart(cartItems)
+
+    api.getShippingMethods()
+      .then((res) => setShippingMethods(res.items))
+      .catch(() => setShippingMethods([]))
   }, [router])
 
+  useEffect(() => {
+    if (!zone || cart.length === 0) {
+      setQuotes([])
+      return
+    }
+    api.quoteShipping({
+      zone,
+      items: cart.map((item) => ({
+        productId: item.productId,
+        variantId: item.variantId,
+        quantity: item.quantity,
+      })),
+    })
+      .then((res) => setQuotes(res.items))
+      .catch(() => setQuotes([]))
+    setMethodId('')
+  }, [zone, cart])
+
+  const zones = Array.from(
+    new Set(shippingMethods.flatMap((m) => m.rules.map((r) => r.zone)).filter((z) => z !== '*'))
+  )
+  const selectedQuote = quotes.find((q) => q.methodId === methodId && q.available)
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
     setLoading(true)
@@ -41,6 +83,7 @@ export default function CheckoutPage() {
       await api.createOrder({
         items: cart.map((item) => ({
           productId: item.productId,
+          variantId: item.variantId,
           quantity: item.quantity,
           price: item.price,
         })),
@@ -52,8 +95,9 @@ export default function CheckoutPage() {
           address: formData.address,
         },
         comment: formData.comment,
+        shipping: methodId ? { methodId, zone } : undefined,
         total: getCartTotalPrice(),
-      })
+      }, idempotencyKey.current)
 
       clearCart()
       setSuccess(true)
@@ -63,6 +107,10 @@ export default function CheckoutPage() {
         router.push('/')
       }, 3000)
     } catch (err: any) {
+      // Сервер ответил ошибкой (а не сеть упала) — следующая попытка будет уже другим запросом
+      if (!(err instanceof TypeError)) {
+        idempotencyKey.current = crypto.randomUUID()
+      }
       setError(err.message || 'Ошибка при оформлении заказа')
     } finally {
       setLoading(false)
@@ -83,7 +131,8 @@ export default function CheckoutPage() {
     )
   }
 
-  const total = getCartTotalPrice()
+  const shippingCost = selectedQuote?.cost ?? 0
+  const total = getCartTotalPrice() + shippingCost
 
   return (
     <section className="section">
@@ -142,6 +191,40 @@ export default function CheckoutPage() {
               />
             </div>
 
+            {shippingMethods.length > 0 && (
+              <>
+                <h3>Доставка</h3>
+
+                <div className={styles.field}>
+                  <label htmlFor="zone">
+                    Регион <span className={styles.required}>*</span>
+                  </label>
+                  <select id="zone" required value={zone} onChange={(e) => setZone(e.target.value)}>
+                    <option value="">Выберите регион</option>
+                    {zones.map((z) => (
+                      <option key={z} value={z}>
+                        {ZONE_TITLES[z] || z}
+                      </option>
+                    ))}
+                  </select>
+                </div>
+
+                {quotes.map((q) => (
+                  <label key={q.methodId} className={styles.field}>
+                    <input
+                      type="radio"
+                      name="shippingMethod"
+                      required
+                      disabled={!q.available}
+                      checked={methodId === q.methodId}
+                      onChange={() => setMethodId(q.methodId)}
+                    />{' '}
+                    {q.title} — {q.available ? (q.cost === 0 ? 'бесплатно' : formatPrice(q.cost)) : 'недоступно'}
+                  </label>
+                ))}
+              </>
+            )}
+
             <div className={styles.field}>
               <label htmlFor="address">Адрес доставки</label>
               <textarea
@@ -181,6 +264,12 @@ export default function CheckoutPage() {
                 </div>
               ))}
             </div>
+            {selectedQuote && (
+              <div className={styles.summaryItem}>
+                <span>Доставка: {selectedQuote.title}</span>
+                <span>{formatPrice(selectedQuote.cost)}</span>
+              </div>
+            )}
             <div className={styles.totalRow}>
               <span>Итого:</span>
               <span className={styles.total}>{formatPrice(total)}</span>
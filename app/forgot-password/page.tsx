@@ -0,0 +1,72 @@
+'use client'
+
+import { useState } from 'react'
+import Link from 'next/link'
+import { Container } from '@/components/Container'
+import { api } from '@/lib/api'
+import styles from './page.module.css'
+
+export default function ForgotPasswordPage() {
+  const [loading, setLoading] = useState(false)
+  const [error, setError] = useState<string | null>(null)
+  const [sent, setSent] = useState(false)
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    setLoading(true)
+    setError(null)
+
+    const formData = new FormData(e.currentTarget)
+    const email = formData.get('email') as string
+
+    try {
+      await api.forgotPassword(email)
+      setSent(true)
+    } catch (err: any) {
+      setError(err?.message || 'Не удалось отправить письмо')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <section className="section">
+      <Container size="wide">
+        <div className="kicker">авторизация</div>
+        <h1 className="h2">Восстановление пароля</h1>
+
+        {sent ? (
+          <p className="lead">
+            Если аккаунт с таким email существует, мы отправили на него письмо со ссылкой
+            для сброса пароля. Ссылка действует 1 час.
+          </p>
+        ) : (
+          <form onSubmit={handleSubmit} className={styles.form}>
+            <div className={styles.field}>
+              <label htmlFor="email">Email</label>
+              <input
+                type="email"
+                id="email"
+                name="email"
+                required
+                placeholder="your@email.com"
+              />
+            </div>
+
+            {error && <div className={styles.error}>{error}</div>}
+
+            <button type="submit" disabled={loading} className={styles.submit}>
+              {loading ? 'Отправка...' : 'Отправить ссылку'}
+            </button>
+
+            <div className={styles.links}>
+              <p>
+                Вспомнили пароль? <Link href="/login">Войти</Link>
+              </p>
+            </div>
+          </form>
+        )}
+      </Container>
+    </section>
+  )
+}
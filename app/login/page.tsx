@@ -1,16 +1,21 @@
 'use client'
 
-import { useState } from 'react'
+import { useState, useRef } from 'react'
 import { useRouter } from 'next/navigation'
 import Link from 'next/link'
 import { Container } from '@/components/Container'
-import { api } from '@/lib/api'
+import { TelegramLoginButton } from '@/components/TelegramLoginButton'
+import { api, saveSession, type TelegramAuthData } from '@/lib/api'
 import styles from './page.module.css'
 
 export default function LoginPage() {
   const router = useRouter()
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState<string | null>(null)
+  // challenge из ответа sign-in, если у аккаунта включена 2FA — тогда спрашиваем код
+  const [challenge, setChallenge] = useState<string | null>(null)
+  const [magicLinkMessage, setMagicLinkMessage] = useState<string | null>(null)
+  const emailRef = useRef<HTMLInputElement>(null)
 
   const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
     e.preventDefault()
@@ -20,10 +25,15 @@ export default function LoginPage() {
     const formData = new FormData(e.currentTarget)
     const email = formData.get('email') as string
     const password = formData.get('password') as string
+    const code = formData.get('code') as string
 
     try {
-      const data = await api.signIn(email, password)
-      localStorage.setItem('access_token', data.access)
+      const data = challenge ? await api.verifyMfa(challenge, code) : await api.signIn(email, password)
+      if (data.mfaRequired && data.challenge) {
+        setChallenge(data.challenge)
+        return
+      }
+      saveSession(data)
       router.push('/account')
       router.refresh()
     } catch (err: any) {
@@ -40,6 +50,45 @@ export default function LoginPage() {
     }
   }
 
+  // Вход по ссылке из письма: nonce сохраняем в этом браузере — без него ссылка не сработает
+  const handleMagicLink = async () => {
+    const email = emailRef.current?.value.trim() || ''
+    if (!email) {
+      setError('Введите email — пришлём на него ссылку для входа')
+      return
+    }
+    setError(null)
+    const bytes = crypto.getRandomValues(new Uint8Array(32))
+    const nonce = Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('')
+    localStorage.setItem('magic_link_nonce', nonce)
+    try {
+      await api.requestMagicLink(email, nonce)
+      setMagicLinkMessage('Если аккаунт с таким email есть, мы отправили ссылку для входа. Откройте её в этом браузере')
+    } catch (err: any) {
+      setError(err?.message || 'Не удалось отправить ссылку')
+    }
+  }
+
+  // Вход через Telegram: дальше так же, как после пароля (2FA → шаг с кодом)
+  const handleTelegram = async (data: TelegramAuthData) => {
+    setLoading(true)
+    setError(null)
+    try {
+      const res = await api.telegramSignIn(data)
+      if (res.mfaRequired && res.challenge) {
+        setChallenge(res.challenge)
+        return
+      }
+      saveSession(res)
+      router.push('/account')
+      router.refresh()
+    } catch (err: any) {
+      setError(err?.message || 'Не удалось войти через Telegram')
+    } finally {
+      setLoading(false)
+    }
+  }
+
   return (
     <section className="section">
       <Container size="wide">
@@ -47,18 +96,26 @@ export default function LoginPage() {
         <h1 className="h2">Вход</h1>
 
         <form onSubmit={handleSubmit} className={styles.form}>
-          <div className={styles.field}>
+          {challenge && (
+            <div className={styles.field}>
+              <label htmlFor="code">Код из приложения или код восстановления</label>
+              <input type="text" id="code" name="code" required autoComplete="one-time-code" autoFocus />
+            </div>
+          )}
+
+          <div className={styles.field} hidden={!!challenge}>
             <label htmlFor="email">Email</label>
             <input
               type="email"
               id="email"
               name="email"
+              ref={emailRef}
               required
               placeholder="your@email.com"
             />
           </div>
 
-          <div className={styles.field}>
+          <div className={styles.field} hidden={!!challenge}>
             <label htmlFor="password">Пароль</label>
             <input
               type="password"
@@ -75,10 +132,22 @@ export default function LoginPage() {
             {loading ? 'Вход...' : 'Войти'}
           </button>
 
+          {!challenge && (
+            <button type="button" onClick={handleMagicLink} className={styles.submit}>
+              Войти по ссылке из письма
+            </button>
+          )}
+          {magicLinkMessage && <p>{magicLinkMessage}</p>}
+
+          {!challenge && <TelegramLoginButton onAuth={handleTelegram} />}
+
           <div className={styles.links}>
             <p>
               Нет аккаунта? <Link href="/signup">Зарегистрироваться</Link>
             </p>
+            <p>
+              <Link href="/forgot-password">Забыли пароль?</Link>
+            </p>
           </div>
         </form>
       </Container>
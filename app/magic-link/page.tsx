@@ -0,0 +1,96 @@
+'use client'
+
+import { useEffect, useRef, useState, Suspense } from 'react'
+import { useRouter, useSearchParams } from 'next/navigation'
+import Link from 'next/link'
+import { Container } from '@/components/Container'
+import { api, saveSession, type AuthTokens } from '@/lib/api'
+
+function MagicLinkStatus() {
+  const router = useRouter()
+  const searchParams = useSearchParams()
+  const token = searchParams.get('token') || ''
+  const [error, setError] = useState<string | null>(null)
+  // challenge — у аккаунта включена 2FA, нужен код из приложения
+  const [challenge, setChallenge] = useState<string | null>(null)
+  // Ссылка одноразовая — в dev-режиме React вызывает эффект дважды, второй запрос её бы "сжёг"
+  const started = useRef(false)
+
+  const finish = (tokens: AuthTokens) => {
+    localStorage.removeItem('magic_link_nonce')
+    saveSession(tokens)
+    router.push('/account')
+    router.refresh()
+  }
+
+  useEffect(() => {
+    if (started.current) return
+    started.current = true
+
+    // nonce лежит в браузере, где запрашивали ссылку; в другом браузере его нет
+    const nonce = localStorage.getItem('magic_link_nonce')
+    if (!token || !nonce) {
+      setError('Ссылку нужно открыть в том же браузере, где вы запрашивали вход')
+      return
+    }
+    api.verifyMagicLink(token, nonce)
+      .then((res) => {
+        if (res.mfaRequired && res.challenge) {
+          setChallenge(res.challenge)
+          return
+        }
+        finish(res)
+      })
+      .catch((err: any) => setError(err?.message || 'Не удалось войти'))
+  }, [token])
+
+  const handleCode = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    const formData = new FormData(e.currentTarget)
+    try {
+      const res = await api.verifyMfa(challenge!, formData.get('code') as string)
+      finish(res)
+    } catch (err: any) {
+      setError(err?.message || 'Неверный код')
+    }
+  }
+
+  if (challenge) {
+    return (
+      <form onSubmit={handleCode} style={{ display: 'flex', flexDirection: 'column', gap: '0.75rem', maxWidth: '24rem' }}>
+        <label htmlFor="code">Код из приложения или код восстановления</label>
+        <input type="text" id="code" name="code" required autoComplete="one-time-code" autoFocus />
+        {error && <p>{error}</p>}
+        <button type="submit" style={{ padding: '0.5rem 1rem', background: 'transparent', border: '1px solid var(--line)', color: 'var(--fg)', fontFamily: 'var(--font-mono)', cursor: 'pointer' }}>
+          Войти
+        </button>
+      </form>
+    )
+  }
+
+  if (error) {
+    return (
+      <p className="lead">
+        {error}. Запросить новую ссылку можно на странице <Link href="/login">входа</Link>.
+      </p>
+    )
+  }
+
+  return <p className="lead">Входим...</p>
+}
+
+export default function MagicLinkPage() {
+  return (
+    <section className="section">
+      <Container size="wide">
+        <div className="kicker">авторизация</div>
+        <h1 className="h2">Вход по ссылке</h1>
+
+        {/* useSearchParams требует Suspense при статической сборке */}
+        <Suspense fallback={null}>
+          <MagicLinkStatus />
+        </Suspense>
+      </Container>
+    </section>
+  )
+}
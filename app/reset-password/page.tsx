@@ -0,0 +1,107 @@
+'use client'
+
+import { useState, Suspense } from 'react'
+import { useRouter, useSearchParams } from 'next/navigation'
+import Link from 'next/link'
+import { Container } from '@/components/Container'
+import { api, clearSession } from '@/lib/api'
+import styles from './page.module.css'
+
+function ResetPasswordForm() {
+  const router = useRouter()
+  const searchParams = useSearchParams()
+  const token = searchParams.get('token') || ''
+  const [loading, setLoading] = useState(false)
+  const [error, setError] = useState<string | null>(null)
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    setError(null)
+
+    const formData = new FormData(e.currentTarget)
+    const password = formData.get('password') as string
+    const confirm = formData.get('confirm') as string
+    if (password !== confirm) {
+      setError('Пароли не совпадают')
+      return
+    }
+
+    setLoading(true)
+    try {
+      await api.resetPassword(token, password)
+      // Все сессии на сервере отозваны — старый токен тоже больше не нужен
+      clearSession()
+      router.push('/login')
+    } catch (err: any) {
+      setError(err?.message || 'Не удалось сменить пароль')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  if (!token) {
+    return (
+      <p className="lead">
+        Ссылка неполная. <Link href="/forgot-password">Запросите новую</Link>.
+      </p>
+    )
+  }
+
+  return (
+    <form onSubmit={handleSubmit} className={styles.form}>
+      <div className={styles.field}>
+        <label htmlFor="password">Новый пароль</label>
+        <input
+          type="password"
+          id="password"
+          name="password"
+          required
+          minLength={8}
+          maxLength={72}
+          placeholder="••••••••"
+        />
+      </div>
+
+      <div className={styles.field}>
+        <label htmlFor="confirm">Повторите пароль</label>
+        <input
+          type="password"
+          id="confirm"
+          name="confirm"
+          required
+          minLength={8}
+          maxLength={72}
+          placeholder="••••••••"
+        />
+      </div>
+
+      {error && <div className={styles.error}>{error}</div>}
+
+      <button type="submit" disabled={loading} className={styles.submit}>
+        {loading ? 'Сохранение...' : 'Сменить пароль'}
+      </button>
+
+      <div className={styles.links}>
+        <p>
+          Ссылка устарела? <Link href="/forgot-password">Запросить новую</Link>
+        </p>
+      </div>
+    </form>
+  )
+}
+
+export default function ResetPasswordPage() {
+  return (
+    <section className="section">
+      <Container size="wide">
+        <div className="kicker">авторизация</div>
+        <h1 className="h2">Новый пароль</h1>
+
+        {/* useSearchParams требует Suspense при статической сборке */}
+        <Suspense fallback={null}>
+          <ResetPasswordForm />
+        </Suspense>
+      </Container>
+    </section>
+  )
+}
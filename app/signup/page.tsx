@@ -1,16 +1,17 @@
 'use client'
 
-import { useState } from 'react'
+import { useRef, useState } from 'react'
 import { useRouter } from 'next/navigation'
 import Link from 'next/link'
 import { Container } from '@/components/Container'
-import { api } from '@/lib/api'
+import { api, saveSession } from '@/lib/api'
 import styles from './page.module.css'
 
 export default function SignupPage() {
   const router = useRouter()
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState<string | null>(null)
+  const idempotencyKey = useRef(crypto.randomUUID())
 
   const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
     e.preventDefault()
@@ -29,11 +30,15 @@ export default function SignupPage() {
     }
 
     try {
-      const data = await api.signUp(email, password, name)
-      localStorage.setItem('access_token', data.access)
+      const data = await api.signUp(email, password, name, idempotencyKey.current)
+      saveSession(data)
       router.push('/account')
       router.refresh()
     } catch (err: any) {
+      // Сервер ответил ошибкой (а не сеть упала) — следующая попытка будет уже другим запросом
+      if (!(err instanceof TypeError)) {
+        idempotencyKey.current = crypto.randomUUID()
+      }
       const errorMsg = err?.message || 'Ошибка регистрации'
       // Обрабатываем разные типы ошибок
       if (errorMsg.includes('409') || errorMsg.includes('уже существует')) {
@@ -0,0 +1,63 @@
+'use client'
+
+import { useEffect, useState, Suspense } from 'react'
+import { useSearchParams } from 'next/navigation'
+import Link from 'next/link'
+import { Container } from '@/components/Container'
+import { api } from '@/lib/api'
+
+function VerifyEmailStatus() {
+  const searchParams = useSearchParams()
+  const token = searchParams.get('token') || ''
+  const [status, setStatus] = useState<'loading' | 'ok' | 'error'>('loading')
+  const [error, setError] = useState<string | null>(null)
+
+  useEffect(() => {
+    if (!token) {
+      setStatus('error')
+      setError('Ссылка неполная')
+      return
+    }
+    api.confirmEmail(token)
+      .then(() => setStatus('ok'))
+      .catch((err: any) => {
+        setStatus('error')
+        setError(err?.message || 'Не удалось подтвердить email')
+      })
+  }, [token])
+
+  if (status === 'loading') {
+    return <p className="lead">Проверяем ссылку...</p>
+  }
+
+  if (status === 'error') {
+    return (
+      <p className="lead">
+        {error}. Новое письмо можно запросить в <Link href="/account">личном кабинете</Link>.
+      </p>
+    )
+  }
+
+  return (
+    <p className="lead">
+      Email подтверждён! Заказы, оформленные на этот адрес, теперь видны в{' '}
+      <Link href="/account">личном кабинете</Link>.
+    </p>
+  )
+}
+
+export default function VerifyEmailPage() {
+  return (
+    <section className="section">
+      <Container size="wide">
+        <div className="kicker">аккаунт</div>
+        <h1 className="h2">Подтверждение email</h1>
+
+        {/* useSearchParams требует Suspense при статической сборке */}
+        <Suspense fallback={null}>
+          <VerifyEmailStatus />
+        </Suspense>
+      </Container>
+    </section>
+  )
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,9 +11,12 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
+	"socialsh/backend/internal/auth"
 	"socialsh/backend/internal/config"
 	"socialsh/backend/internal/db"
 	"socialsh/backend/internal/handlers"
+	"socialsh/backend/internal/notify"
+	"socialsh/backend/internal/payments"
 	"socialsh/backend/internal/repository"
 	"socialsh/backend/internal/routes"
 )
@@ -35,6 +39,25 @@ import (
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("config: %v", err)
+	}
+
+	// Ключи подписи access-токенов: HS256 с JWT_SECRET или PEM-ключи из JWT_KEYS_DIR
+	keys, err := auth.KeySetFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+	handlers.Keys = keys
+
+	// AUTH_MODE=cookie: токены браузеру — HttpOnly-куками (см. handlers.respondTokens)
+	if cfg.AuthMode == config.AuthModeCookie {
+		handlers.CookieAuth = &handlers.CookieSettings{
+			Domain:   cfg.CookieDomain,
+			Secure:   cfg.CookieSecure,
+			SameSite: cfg.CookieSameSite,
+		}
+	}
 
 	// Подключаемся к Postgres
 	dsn := cfg.PostgresDSN()
@@ -48,6 +71,17 @@ func main() {
 	store := repository.NewStore(sqlDB)
 	handlers.Repo = store
 
+	// Уведомления о заказах: хендлеры пишут в outbox, воркер отправляет с ретраями
+	outbox := notify.NewOutbox(store.Notifications, notify.FromConfig(cfg)...)
+	handlers.Notify = outbox
+	go outbox.Run(context.Background())
+
+	// Письма покупателям (сброс пароля и т.п.): SMTP или лог, если SMTP не настроен
+	handlers.Mailer = notify.MailerFromConfig(cfg)
+
+	// Онлайн-оплата: провайдеры из конфига (без настроек список пустой)
+	handlers.Payments = payments.FromConfig(cfg)
+
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
@@ -63,11 +97,17 @@ func main() {
 	// Middleware
 	app.Use(recover.New())
 	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
+	corsConfig := cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PATCH,DELETE,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
-	}))
+		AllowHeaders: "Origin,Content-Type,Accept,Authorization,Idempotency-Key,X-CSRF-Token,X-Auth-Transport",
+	}
+	if cfg.AuthMode == config.AuthModeCookie {
+		// Куки ходят только с credentials, а с ними "*" запрещён — перечисляем origin'ы фронта
+		corsConfig.AllowOrigins = cfg.AllowedOrigins()
+		corsConfig.AllowCredentials = true
+	}
+	app.Use(cors.New(corsConfig))
 
 	// Статические файлы (загруженные изображения)
 	app.Static("/uploads", "./uploads")
@@ -146,7 +186,7 @@ func main() {
 		return c.SendString(swaggerHTML)
 	})
 
-	routes.Register(app, cfg.JwtSecret, cfg.RefreshSecret)
+	routes.Register(app, cfg)
 
 	log.Printf("🚀 Server starting on :%s", cfg.Port)
 	if err := app.Listen(":" + cfg.Port); err != nil {
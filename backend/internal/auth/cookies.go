@@ -0,0 +1,31 @@
+package auth
+
+// Режим AUTH_MODE=cookie: токены лежат в куках, а не в localStorage фронта.
+//
+// Как читается:
+//  1. access_token — HttpOnly, Path=/: JS до него не дотянется, XSS токен не украдёт.
+//  2. refresh_token — HttpOnly, Path=/api/auth: уходит только на /auth/refresh и logout.
+//  3. csrf_token — обычная кука (JS её читает) + тот же токен в теле ответа на вход.
+//     Браузер приложит куки сессии и к запросу с чужого сайта, но заголовок
+//     X-CSRF-Token чужой сайт выставить не может — он не знает значения.
+//     Совпали кука и заголовок — запрос от нашего фронта (double-submit).
+//  4. Клиенты с заголовком Authorization: Bearer кук не шлют — CSRF им не грозит,
+//     для них всё работает как раньше.
+
+const (
+	AccessCookie  = "access_token"
+	RefreshCookie = "refresh_token"
+	CSRFCookie    = "csrf_token"
+
+	// CSRFHeader — заголовок, в котором фронт повторяет значение csrf_token.
+	CSRFHeader = "X-CSRF-Token"
+
+	// RefreshCookiePath — refresh-кука нужна только роутам /api/auth/*.
+	RefreshCookiePath = "/api/auth"
+)
+
+// Откуда пришёл access-токен запроса (c.Locals("authVia")).
+const (
+	ViaBearer = "bearer"
+	ViaCookie = "cookie"
+)
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"socialsh/backend/internal/config"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Ключи подписи access-токенов.
+//
+// Два режима:
+//   - HS256 с JWT_SECRET — как раньше, проверить токен может только тот, кто знает секрет;
+//   - JWT_KEYS_DIR — каталог с PEM-ключами EdDSA (Ed25519) или RS256 (RSA ≥ 2048).
+//     Имя файла без .pem — kid. Подписывает ключ JWT_SIGNING_KID, остальные
+//     только проверяют — так делается ротация:
+//       1. кладём новый ключ рядом со старым, рестарт — новый уже в JWKS;
+//       2. переключаем JWT_SIGNING_KID на новый;
+//       3. через 15 минут (срок access-токена) старый можно удалить.
+//     Публичные части отдаются на /.well-known/jwks.json — другие сервисы
+//     проверяют токены без общего секрета.
+// ═══════════════════════════════════════════════════════════════
+
+// minRSABits — RSA короче этого считаем небезопасным.
+const minRSABits = 2048
+
+// signingKey — один ключ: kid, алгоритм, закрытая часть (nil — только проверка) и открытая.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private any // []byte для HMAC, ed25519.PrivateKey, *rsa.PrivateKey
+	public  any // []byte для HMAC, ed25519.PublicKey, *rsa.PublicKey
+}
+
+// KeySet — ключ, которым подписываем, и все ключи, которым верим (по kid).
+type KeySet struct {
+	signing *signingKey
+	byID    map[string]*signingKey
+}
+
+// NewHMACKeySet — режим HS256 с общим секретом. kid не ставится, JWKS пустой.
+func NewHMACKeySet(secret string) *KeySet {
+	k := &signingKey{method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+	return &KeySet{signing: k, byID: map[string]*signingKey{"": k}}
+}
+
+// KeySetFromConfig — JWT_KEYS_DIR задан → асимметричные ключи из каталога, иначе HS256 с JWT_SECRET.
+func KeySetFromConfig(cfg *config.Config) (*KeySet, error) {
+	if cfg.JwtKeysDir == "" {
+		if cfg.JwtSecret == "" {
+			return nil, errors.New("не задан ни JWT_KEYS_DIR, ни JWT_SECRET")
+		}
+		return NewHMACKeySet(cfg.JwtSecret), nil
+	}
+	return LoadKeySet(cfg.JwtKeysDir, cfg.JwtSigningKID)
+}
+
+// LoadKeySet — прочитать все *.pem из dir. PRIVATE KEY — может подписывать,
+// PUBLIC KEY — только проверка (ключ, выведенный из ротации).
+func LoadKeySet(dir, signingKID string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("jwt keys: %w", err)
+	}
+
+	ks := &KeySet{byID: map[string]*signingKey{}}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := loadPEMKey(kid, file)
+		if err != nil {
+			return nil, fmt.Errorf("jwt keys: %s: %w", file, err)
+		}
+		ks.byID[kid] = key
+	}
+	if len(ks.byID) == 0 {
+		return nil, fmt.Errorf("jwt keys: в %s нет ни одного *.pem", dir)
+	}
+
+	signing, ok := ks.byID[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("jwt keys: ключа JWT_SIGNING_KID=%q нет в %s", signingKID, dir)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("jwt keys: %q — открытый ключ, подписывать им нельзя", signingKID)
+	}
+	ks.signing = signing
+	return ks, nil
+}
+
+// loadPEMKey — разобрать PEM: PKCS#8 закрытый ключ или PKIX открытый.
+func loadPEMKey(kid, file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("не PEM")
+	}
+
+	var public crypto.PublicKey
+	var private any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("неподдерживаемый тип ключа")
+		}
+		private, public = parsed, signer.Public()
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("неподдерживаемый блок %q (нужен PRIVATE KEY или PUBLIC KEY)", block.Type)
+	}
+
+	key := &signingKey{id: kid, private: private, public: public}
+	switch pub := public.(type) {
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA-ключ короче %d бит", minRSABits)
+		}
+		key.method = jwt.SigningMethodRS256
+	default:
+		return nil, errors.New("поддерживаются только Ed25519 и RSA")
+	}
+	return key, nil
+}
+
+// Sign — подписать claims текущим ключом, kid — в заголовке.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	if ks.signing.id != "" {
+		token.Header["kid"] = ks.signing.id
+	}
+	return token.SignedString(ks.signing.private)
+}
+
+// Parse — проверить подпись и срок токена, вернуть claims.
+// Ключ выбирается по kid, алгоритм токена обязан совпадать с алгоритмом ключа —
+// иначе можно было бы подписать HS256 открытым ключом RSA.
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := ks.byID[kid]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный kid %q", kid)
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("неверный метод подписи")
+		}
+		return key.public, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("невалидный или просроченный токен")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("не удалось прочитать claims из токена")
+	}
+	return claims, nil
+}
+
+// JWK — открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+}
+
+// JWKS — открытые ключи всех kid (HMAC-секрет, разумеется, не публикуется).
+func (ks *KeySet) JWKS() []JWK {
+	keys := []JWK{}
+	for _, k := range ks.byID {
+		b64 := base64.RawURLEncoding.EncodeToString
+		switch pub := k.public.(type) {
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{Kty: "OKP", Kid: k.id, Use: "sig", Alg: k.method.Alg(), Crv: "Ed25519", X: b64(pub)})
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{Kty: "RSA", Kid: k.id, Use: "sig", Alg: k.method.Alg(),
+				N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
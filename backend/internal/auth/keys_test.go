@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testKeys — каталог с ключами для LoadKeySet: ed (Ed25519), rsa (RSA-2048)
+// и old — открытая часть ключа, выведенного из ротации.
+type testKeys struct {
+	dir    string
+	ed     ed25519.PrivateKey
+	rsa    *rsa.PrivateKey
+	old    ed25519.PrivateKey
+	edPEM  []byte // открытая часть ed в PEM — так её видит любой через JWKS
+	rsaPEM []byte
+}
+
+func writeTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	k := &testKeys{dir: t.TempDir()}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.ed, k.rsa, k.old = edKey, rsaKey, oldKey
+
+	writePrivatePEM(t, filepath.Join(k.dir, "ed.pem"), edKey)
+	writePrivatePEM(t, filepath.Join(k.dir, "rsa.pem"), rsaKey)
+	writePublicPEM(t, filepath.Join(k.dir, "old.pem"), oldKey.Public())
+	k.edPEM = publicPEM(t, edKey.Public())
+	k.rsaPEM = publicPEM(t, &rsaKey.PublicKey)
+	return k
+}
+
+func writePrivatePEM(t *testing.T, file string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writePublicPEM(t *testing.T, file string, key any) {
+	t.Helper()
+	if err := os.WriteFile(file, publicPEM(t, key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func publicPEM(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// signWith — токен с произвольным алгоритмом и kid ("" — без kid), подписанный key.
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeySetParse(t *testing.T) {
+	k := writeTestKeys(t)
+	ks, err := LoadKeySet(k.dir, "ed")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	claims := validClaims()
+	expired := jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(-time.Minute).Unix()}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"EdDSA текущим ключом", signWith(t, jwt.SigningMethodEdDSA, "ed", k.ed, claims), true},
+		{"RS256 вторым ключом", signWith(t, jwt.SigningMethodRS256, "rsa", k.rsa, claims), true},
+		{"ключ из ротации (только открытая часть)", signWith(t, jwt.SigningMethodEdDSA, "old", k.old, claims), true},
+
+		// alg confusion: HMAC-подпись открытым ключом, который публикуется в JWKS
+		{"HS256 с kid RSA-ключа", signWith(t, jwt.SigningMethodHS256, "rsa", k.rsaPEM, claims), false},
+		{"HS256 с kid Ed25519-ключа", signWith(t, jwt.SigningMethodHS256, "ed", k.edPEM, claims), false},
+		{"HS256 с kid Ed25519, секрет — сырые байты ключа",
+			signWith(t, jwt.SigningMethodHS256, "ed", []byte(k.ed.Public().(ed25519.PublicKey)), claims), false},
+		{"RS256 с kid Ed25519-ключа", signWith(t, jwt.SigningMethodRS256, "ed", k.rsa, claims), false},
+		{"EdDSA с kid RSA-ключа", signWith(t, jwt.SigningMethodEdDSA, "rsa", k.ed, claims), false},
+
+		{"alg none", signWith(t, jwt.SigningMethodNone, "ed", jwt.UnsafeAllowNoneSignatureType, claims), false},
+		{"неизвестный kid", signWith(t, jwt.SigningMethodEdDSA, "nope", k.ed, claims), false},
+		{"без kid", signWith(t, jwt.SigningMethodEdDSA, "", k.ed, claims), false},
+		{"чужой ключ с нашим kid", signWith(t, jwt.SigningMethodEdDSA, "ed", k.old, claims), false},
+		{"просроченный", signWith(t, jwt.SigningMethodEdDSA, "ed", k.ed, expired), false},
+		{"мусор", "not.a.jwt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ks.Parse(tt.token)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				if got["sub"] != "user-1" {
+					t.Errorf("sub = %v", got["sub"])
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Parse принял токен: %v", got)
+			}
+		})
+	}
+}
+
+func TestKeySetSignRoundTrip(t *testing.T) {
+	k := writeTestKeys(t)
+	for _, kid := range []string{"ed", "rsa"} {
+		ks, err := LoadKeySet(k.dir, kid)
+		if err != nil {
+			t.Fatalf("LoadKeySet(%s): %v", kid, err)
+		}
+		token, err := ks.Sign(validClaims())
+		if err != nil {
+			t.Fatalf("Sign(%s): %v", kid, err)
+		}
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Header["kid"] != kid {
+			t.Errorf("kid = %v, want %s", parsed.Header["kid"], kid)
+		}
+		if _, err := ks.Parse(token); err != nil {
+			t.Errorf("Parse своего токена (%s): %v", kid, err)
+		}
+	}
+}
+
+func TestHMACKeySetParse(t *testing.T) {
+	ks := NewHMACKeySet("secret")
+	k := writeTestKeys(t)
+	claims := validClaims()
+
+	own, err := ks.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"свой токен", own, true},
+		{"чужой секрет", signWith(t, jwt.SigningMethodHS256, "", []byte("other"), claims), false},
+		{"HS512 тем же секретом", signWith(t, jwt.SigningMethodHS512, "", []byte("secret"), claims), false},
+		{"RS256", signWith(t, jwt.SigningMethodRS256, "", k.rsa, claims), false},
+		{"alg none", signWith(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, claims), false},
+		{"с kid", signWith(t, jwt.SigningMethodHS256, "ed", []byte("secret"), claims), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ks.Parse(tt.token)
+			if tt.ok && err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("Parse принял токен")
+			}
+		})
+	}
+
+	if jwks := ks.JWKS(); len(jwks) != 0 {
+		t.Errorf("HMAC-секрет попал в JWKS: %v", jwks)
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	k := writeTestKeys(t)
+
+	if _, err := LoadKeySet(k.dir, "old"); err == nil {
+		t.Error("открытым ключом нельзя подписывать")
+	}
+	if _, err := LoadKeySet(k.dir, "missing"); err == nil {
+		t.Error("JWT_SIGNING_KID без файла принят")
+	}
+	if _, err := LoadKeySet(t.TempDir(), "ed"); err == nil {
+		t.Error("пустой каталог принят")
+	}
+
+	weak := t.TempDir()
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePrivatePEM(t, filepath.Join(weak, "weak.pem"), weakKey)
+	if _, err := LoadKeySet(weak, "weak"); err == nil {
+		t.Error("RSA-1024 принят")
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	k := writeTestKeys(t)
+	ks, err := LoadKeySet(k.dir, "ed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks) != 3 {
+		t.Fatalf("JWKS = %v, want 3 ключа", jwks)
+	}
+	want := map[string]string{"ed": "EdDSA", "old": "EdDSA", "rsa": "RS256"}
+	for _, key := range jwks {
+		if want[key.Kid] != key.Alg {
+			t.Errorf("ключ %s: alg = %s, want %s", key.Kid, key.Alg, want[key.Kid])
+		}
+		if key.Kid == "rsa" && (key.N == "" || key.E == "") || key.Kid != "rsa" && key.X == "" {
+			t.Errorf("ключ %s без открытой части: %+v", key.Kid, key)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package auth — кто что может в админке (роли и их права) и второй фактор входа (TOTP).
+//
+// Роль хранится у юзера в users.role и попадает в access-токен (claim role).
+// Права в токен не пишем — они выводятся из роли здесь, поэтому поменять
+// набор прав роли можно без перевыпуска токенов.
+package auth
+
+import "sort"
+
+// Permission — право на раздел админки, формат "<ресурс>:<действие>".
+type Permission string
+
+const (
+	ProductsWrite  Permission = "products:write"  // товары, варианты, остатки, фото товаров
+	GalleryWrite   Permission = "gallery:write"   // галерея и её фото
+	PagesWrite     Permission = "pages:write"     // инфо-страницы
+	OrdersRead     Permission = "orders:read"     // список и карточка заказа
+	OrdersWrite    Permission = "orders:write"    // смена статуса (а потом и возвраты)
+	DiscountsWrite Permission = "discounts:write" // промокоды
+	ShippingWrite  Permission = "shipping:write"  // способы доставки
+	UsersManage    Permission = "users:manage"    // пользователи, роли, блокировки входа
+)
+
+// Роли. RoleUser — обычный покупатель, прав в админке нет.
+const (
+	RoleUser           = "user"
+	RoleContentEditor  = "content-editor"
+	RoleCatalogManager = "catalog-manager"
+	RoleOrderManager   = "order-manager"
+	RoleOwner          = "owner"
+	// RoleAdmin — старая единственная админская роль, равна owner.
+	// Оставлена, чтобы существующие аккаунты с role = 'admin' не потеряли доступ.
+	RoleAdmin = "admin"
+)
+
+// allPermissions — всё, что есть; достаётся owner.
+var allPermissions = []Permission{
+	ProductsWrite, GalleryWrite, PagesWrite,
+	OrdersRead, OrdersWrite,
+	DiscountsWrite, ShippingWrite,
+	UsersManage,
+}
+
+// rolePermissions — роль → права. Роли нет в карте — прав нет.
+var rolePermissions = map[string][]Permission{
+	RoleContentEditor:  {GalleryWrite, PagesWrite},
+	RoleCatalogManager: {ProductsWrite, DiscountsWrite, ShippingWrite},
+	RoleOrderManager:   {OrdersRead, OrdersWrite},
+	RoleOwner:          allPermissions,
+	RoleAdmin:          allPermissions,
+}
+
+// Permissions — права роли (отсортированы, чтобы ответ API был стабильным).
+func Permissions(role string) []Permission {
+	perms := append([]Permission{}, rolePermissions[role]...)
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// Has — есть ли у роли право perm.
+func Has(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaff — есть ли у роли хоть какой-то доступ в админку.
+func IsStaff(role string) bool {
+	return len(rolePermissions[role]) > 0
+}
+
+// IsOwner — полный доступ (owner или старый admin).
+func IsOwner(role string) bool {
+	return role == RoleOwner || role == RoleAdmin
+}
+
+// ValidRole — можно ли назначить роль из админки.
+// Старый admin не назначаем — вместо него owner.
+func ValidRole(role string) bool {
+	if role == RoleUser {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok && role != RoleAdmin
+}
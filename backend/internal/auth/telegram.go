@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Проверка данных Telegram Login Widget (https://core.telegram.org/widgets/login).
+//
+// Как читается:
+//  1. Виджет отдаёт фронту объект юзера: id, first_name, last_name, username,
+//     photo_url, auth_date и hash. Фронт пересылает его нам как есть.
+//  2. data-check-string — все поля, кроме hash, в виде "key=value",
+//     отсортированные по ключу и склеенные через \n.
+//  3. hash = hex(HMAC-SHA256(data-check-string, ключ = SHA256(токен бота))).
+//     Подделать его без токена бота нельзя.
+//  4. auth_date — когда юзер нажал кнопку. Старые данные не принимаем,
+//     иначе однажды утёкший payload работал бы вечно.
+
+// telegramClockSkew — насколько auth_date может быть "в будущем" из-за расхождения часов.
+const telegramClockSkew = time.Minute
+
+var (
+	// ErrTelegramHash — подпись не сошлась (данные подделаны или другой бот).
+	ErrTelegramHash = errors.New("неверная подпись данных Telegram")
+	// ErrTelegramExpired — auth_date слишком старый (или из будущего).
+	ErrTelegramExpired = errors.New("данные Telegram устарели, войдите заново")
+)
+
+// TelegramIdentity — проверенный юзер Telegram.
+type TelegramIdentity struct {
+	ID        int64
+	Username  string
+	FirstName string
+	LastName  string
+}
+
+// DisplayName — имя для нового аккаунта: "Имя Фамилия", иначе username.
+func (t *TelegramIdentity) DisplayName() string {
+	name := strings.TrimSpace(t.FirstName + " " + t.LastName)
+	if name == "" {
+		name = t.Username
+	}
+	if name == "" {
+		name = "Telegram " + strconv.FormatInt(t.ID, 10)
+	}
+	return name
+}
+
+// VerifyTelegramLogin — проверить подпись и свежесть данных виджета.
+// fields — все поля объекта юзера строками (числа — как пришли, без форматирования).
+func VerifyTelegramLogin(botToken string, fields map[string]string, maxAge time.Duration, now time.Time) (*TelegramIdentity, error) {
+	hash := fields["hash"]
+	if hash == "" {
+		return nil, ErrTelegramHash
+	}
+
+	// 2. data-check-string
+	pairs := make([]string, 0, len(fields))
+	for k, v := range fields {
+		if k != "hash" {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	sort.Strings(pairs)
+
+	// 3. HMAC с ключом SHA256(токен бота)
+	key := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+	want := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(hash))) {
+		return nil, ErrTelegramHash
+	}
+
+	// 4. Свежесть
+	authDate, err := strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil {
+		return nil, ErrTelegramExpired
+	}
+	age := now.Sub(time.Unix(authDate, 0))
+	if age > maxAge || age < -telegramClockSkew {
+		return nil, ErrTelegramExpired
+	}
+
+	id, err := strconv.ParseInt(fields["id"], 10, 64)
+	if err != nil || id <= 0 {
+		return nil, errors.New("в данных Telegram нет id")
+	}
+	return &TelegramIdentity{
+		ID:        id,
+		Username:  fields["username"],
+		FirstName: fields["first_name"],
+		LastName:  fields["last_name"],
+	}, nil
+}
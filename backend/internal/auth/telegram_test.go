@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Фикстуры посчитаны отдельно от кода (HMAC-SHA256 по алгоритму из документации виджета)
+// для этого токена бота — тест не повторяет реализацию, а сверяется с ней.
+const testBotToken = "123456789:AAFakeBotTokenForTests_0123456789ab"
+
+// testAuthDate — auth_date фикстур (2025-10-09 08:53:20 UTC).
+const testAuthDate = 1760000000
+
+func telegramPayload() map[string]string {
+	return map[string]string{
+		"id":         "424242",
+		"first_name": "Иван",
+		"last_name":  "Петров",
+		"username":   "ivan_p",
+		"photo_url":  "https://t.me/i/userpic/320/ivan.jpg",
+		"auth_date":  "1760000000",
+		"hash":       "4da487bc069eec0cb7961f16779ddb2547164f57df8238eb324a66cf5d7edd11",
+	}
+}
+
+func TestVerifyTelegramLogin(t *testing.T) {
+	now := time.Unix(testAuthDate, 0).Add(time.Minute)
+
+	identity, err := VerifyTelegramLogin(testBotToken, telegramPayload(), 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("VerifyTelegramLogin: %v", err)
+	}
+	want := TelegramIdentity{ID: 424242, Username: "ivan_p", FirstName: "Иван", LastName: "Петров"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+	if got := identity.DisplayName(); got != "Иван Петров" {
+		t.Errorf("DisplayName = %q", got)
+	}
+
+	// Только обязательные поля (без фамилии, username и фото) — подпись по тому, что пришло
+	minimal := map[string]string{
+		"id":         "424242",
+		"first_name": "Иван",
+		"auth_date":  "1760000000",
+		"hash":       "d9340b4d27b5522baa0f009b1876e4fd7b9ad95bed31b753973968948220119d",
+	}
+	if _, err := VerifyTelegramLogin(testBotToken, minimal, 24*time.Hour, now); err != nil {
+		t.Fatalf("минимальный payload: %v", err)
+	}
+
+	// Hash в верхнем регистре — тоже валидный hex
+	upper := telegramPayload()
+	upper["hash"] = strings.ToUpper(upper["hash"])
+	if _, err := VerifyTelegramLogin(testBotToken, upper, 24*time.Hour, now); err != nil {
+		t.Fatalf("hash в верхнем регистре: %v", err)
+	}
+}
+
+func TestVerifyTelegramLoginTampered(t *testing.T) {
+	now := time.Unix(testAuthDate, 0).Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		token  string
+		mutate func(p map[string]string)
+	}{
+		{"подменён id", testBotToken, func(p map[string]string) { p["id"] = "1" }},
+		{"подменён username", testBotToken, func(p map[string]string) { p["username"] = "admin" }},
+		{"сдвинут auth_date", testBotToken, func(p map[string]string) { p["auth_date"] = "1760000600" }},
+		{"добавлено поле", testBotToken, func(p map[string]string) { p["is_admin"] = "true" }},
+		{"удалено поле", testBotToken, func(p map[string]string) { delete(p, "photo_url") }},
+		{"нет hash", testBotToken, func(p map[string]string) { delete(p, "hash") }},
+		{"hash обрезан", testBotToken, func(p map[string]string) { p["hash"] = p["hash"][:32] }},
+		{"другой бот", "987654321:AAOtherBotToken", func(p map[string]string) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := telegramPayload()
+			tt.mutate(payload)
+			_, err := VerifyTelegramLogin(tt.token, payload, 24*time.Hour, now)
+			if !errors.Is(err, ErrTelegramHash) {
+				t.Fatalf("VerifyTelegramLogin = %v, want ErrTelegramHash", err)
+			}
+		})
+	}
+}
+
+func TestVerifyTelegramLoginFreshness(t *testing.T) {
+	authDate := time.Unix(testAuthDate, 0)
+	maxAge := 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantErr error
+	}{
+		{"только что", authDate, nil},
+		{"на границе maxAge", authDate.Add(maxAge), nil},
+		{"устарел", authDate.Add(maxAge + time.Second), ErrTelegramExpired},
+		{"устарел на год", authDate.AddDate(1, 0, 0), ErrTelegramExpired},
+		{"из будущего в пределах расхождения часов", authDate.Add(-telegramClockSkew), nil},
+		{"из будущего", authDate.Add(-telegramClockSkew - time.Second), ErrTelegramExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyTelegramLogin(testBotToken, telegramPayload(), maxAge, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyTelegramLogin = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyTelegramLoginWithoutID(t *testing.T) {
+	// Подпись верная, но id нет — такой payload не превращается в аккаунт
+	payload := map[string]string{
+		"first_name": "Иван",
+		"auth_date":  "1760000000",
+		"hash":       "29712e4d47770e7bb27a455bccf96cd0297eee4502f9bee5062bed80f22b419b",
+	}
+	_, err := VerifyTelegramLogin(testBotToken, payload, 24*time.Hour, time.Unix(testAuthDate, 0))
+	if err == nil || errors.Is(err, ErrTelegramHash) || errors.Is(err, ErrTelegramExpired) {
+		t.Fatalf("VerifyTelegramLogin = %v, want ошибку про id", err)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP по RFC 6238 — то, что понимают Google Authenticator, 1Password и т.п.:
+// HMAC-SHA1, шаг 30 секунд, 6 цифр.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew — сколько соседних шагов принимаем (часы телефона могут отставать)
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret — случайный секрет 160 бит в base32 (как его вводят в приложение вручную).
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("totp secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPURI — otpauth:// ссылка для QR-кода.
+// Пример: otpauth://totp/SocialSh:ivan@mail.ru?secret=...&issuer=SocialSh
+func TOTPURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + q.Encode()
+}
+
+// ValidateTOTP — проверить код на момент now.
+// Возвращает номер шага, которому код соответствует: вызывающий сохраняет его
+// и не принимает шаги не новее сохранённого — так один код нельзя использовать дважды.
+func ValidateTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	step := now.Unix() / totpPeriod
+	for d := int64(-totpSkew); d <= totpSkew; d++ {
+		if hmac.Equal([]byte(hotp(key, step+d)), []byte(code)) {
+			return step + d, true
+		}
+	}
+	return 0, false
+}
+
+// hotp — код для счётчика counter (RFC 4226, dynamic truncation).
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// NewRecoveryCodes — n одноразовых кодов восстановления вида "k7m2q-9xw4d"
+// (на случай потери телефона). Хранить — только хеш (utils.HashToken).
+func NewRecoveryCodes(n int) ([]string, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567" // base32: 32 символа — ровно 5 бит на байт
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("recovery codes: %w", err)
+		}
+		for j := range b {
+			b[j] = alphabet[b[j]&31]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode — привести введённый код к виду из NewRecoveryCodes
+// (регистр, пробелы, дефис где угодно или без него), чтобы хеш совпал.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.Join(strings.Fields(code), ""), "-", ""))
+	if len(code) == 10 {
+		return code[:5] + "-" + code[5:]
+	}
+	return code
+}
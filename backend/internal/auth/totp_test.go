@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"encoding/base32"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret — ключ тестовых векторов RFC 6238 для SHA1 ("12345678901234567890") в base32.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// Векторы из приложения B RFC 6238 (SHA1). В RFC коды 8-значные,
+// у нас 6 цифр — это младшие 6 цифр того же числа.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, v := range rfc6238Vectors {
+		if got := hotp(key, v.unix/totpPeriod); got != v.code {
+			t.Errorf("hotp(T=%d) = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateTOTPRFC6238Vectors(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, now)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s, T=%d) = %d, %v; want %d, true", v.code, v.unix, step, ok, v.unix/totpPeriod)
+		}
+	}
+}
+
+// Принимаем код соседнего шага (часы телефона спешат или отстают на ±30с), но не дальше.
+func TestValidateTOTPSkew(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name  string
+		delta int64
+		ok    bool
+	}{
+		{"текущий шаг", 0, true},
+		{"предыдущий шаг", -1, true},
+		{"следующий шаг", 1, true},
+		{"два шага назад", -2, false},
+		{"два шага вперёд", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfc6238Secret, hotp(key, step+tt.delta), now)
+			if ok != tt.ok {
+				t.Fatalf("ValidateTOTP = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != step+tt.delta {
+				t.Errorf("шаг = %d, want %d — вызывающий сохраняет именно шаг кода", got, step+tt.delta)
+			}
+		})
+	}
+}
+
+// Повтор кода отсекает вызывающий: принимает шаг, только если он новее последнего
+// принятого (TwoFactorSQLRepo.UseStep). Здесь проверяем, что ValidateTOTP даёт для этого
+// нужный шаг: тот же код в пределах окна — тот же шаг, а не "новый".
+func TestValidateTOTPReusedStep(t *testing.T) {
+	key := []byte("12345678901234567890")
+	var lastStep int64
+	accept := func(code string, now time.Time) bool {
+		step, ok := ValidateTOTP(rfc6238Secret, code, now)
+		if !ok || step <= lastStep {
+			return false
+		}
+		lastStep = step
+		return true
+	}
+
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	code := hotp(key, step)
+
+	if !accept(code, now) {
+		t.Fatal("первый ввод кода не принят")
+	}
+	if accept(code, now) {
+		t.Error("тот же код принят повторно")
+	}
+	if accept(code, now.Add(totpPeriod*time.Second)) {
+		t.Error("тот же код принят на следующем шаге (в окне расхождения часов)")
+	}
+	if accept(hotp(key, step-1), now) {
+		t.Error("код предыдущего шага принят после более нового")
+	}
+	if !accept(hotp(key, step+1), now.Add(totpPeriod*time.Second)) {
+		t.Error("код следующего шага не принят")
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name, secret, code string
+	}{
+		{"неверный код", rfc6238Secret, "000000"},
+		{"5 цифр", rfc6238Secret, "50471"},
+		{"8 цифр из RFC", rfc6238Secret, "14050471"},
+		{"секрет не base32", "not base32!", "050471"},
+		{"пустой секрет", "", "050471"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok {
+				t.Fatal("ValidateTOTP принял код")
+			}
+		})
+	}
+
+	// Секрет в нижнем регистре и с пробелами по краям — как его могли сохранить
+	if _, ok := ValidateTOTP("  "+toLowerASCII(rfc6238Secret)+" ", "050471", now); !ok {
+		t.Error("секрет в нижнем регистре не принят")
+	}
+}
+
+func toLowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("код %q не в формате xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("код %q повторяется", code)
+		}
+		seen[code] = true
+		if got := NormalizeRecoveryCode(code); got != code {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, код из NewRecoveryCodes должен остаться как есть", code, got)
+		}
+	}
+	if len(codes) != 10 {
+		t.Errorf("кодов = %d, want 10", len(codes))
+	}
+}
+
+// Как бы юзер ни ввёл код — регистр, пробелы, дефис или его отсутствие — хеш должен совпасть.
+func TestNormalizeRecoveryCode(t *testing.T) {
+	const want = "k7m2q-9xw4d"
+	inputs := []string{
+		"k7m2q-9xw4d",
+		"K7M2Q-9XW4D",
+		"k7m2q9xw4d",
+		"K7M2Q9XW4D",
+		"  k7m2q-9xw4d\n",
+		"k7m2q 9xw4d",
+		"k7m2q - 9xw4d",
+		"k7-m2q9x-w4d",
+		"K7M2Q--9XW4D",
+	}
+	for _, in := range inputs {
+		if got := NormalizeRecoveryCode(in); got != want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	// Лишний или недостающий символ — не код, дефис не вставляем
+	for _, in := range []string{"k7m2q-9xw4", "k7m2q-9xw4dd"} {
+		if got := NormalizeRecoveryCode(in); got == want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q — совпал с чужим кодом", in, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -18,6 +19,47 @@ type Config struct {
 	JwtSecret     string
 	RefreshSecret string
 	BaseUrl       string
+	FrontendURL   string // адрес сайта — для ссылок в письмах (сброс пароля и т.п.)
+
+	// Асимметричная подпись access-токенов (см. auth.KeySet); пусто — HS256 с JwtSecret
+	JwtKeysDir    string // каталог с <kid>.pem
+	JwtSigningKID string // каким ключом подписывать новые токены
+
+	// Как фронт получает токены: "bearer" — в теле ответа, "cookie" — HttpOnly-куками + CSRF
+	AuthMode       string
+	CookieDomain   string // пусто — куки только для хоста API
+	CookieSecure   bool   // false — только для локальной разработки по http
+	CookieSameSite string // Lax | Strict | None (None — фронт и API на разных сайтах)
+	CORSOrigins    string // откуда пускать с куками, через запятую; пусто — FrontendURL
+
+	// Что недоступно юзеру без подтверждённого email: "checkout,payments"; пусто — всё доступно
+	RequireVerifiedEmailFor string
+
+	// 2FA обязательна для админских ролей: без неё админка отвечает 403
+	RequireAdmin2FA bool
+
+	// Вход через Telegram Login Widget: токен бота, к которому привязан домен сайта
+	// (пусто — берём TELEGRAM_BOT_TOKEN; оба пусты — вход через Telegram выключен)
+	TelegramLoginBotToken string
+
+	// Уведомления о заказах (см. пакет notify)
+	NotifyChannels   string // "telegram,email"; пусто — все, для которых есть настройки
+	TelegramBotToken string
+	TelegramChatID   string
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	SMTPFrom         string
+	OrderEmail       string // куда слать письма о заказах, можно несколько через запятую
+
+	// Онлайн-оплата (см. пакет payments)
+	PaymentReturnURL      string // куда провайдер вернёт покупателя после оплаты
+	YooKassaShopID        string
+	YooKassaSecretKey     string
+	YooKassaWebhookSecret string
+	YooKassaAPIURL        string // пусто — боевой API; для локальной заглушки http://localhost:...
+	FakePaymentsSecret    string // не пусто — включает фейкового провайдера (только для разработки!)
 }
 
 // Load читает конфиг из переменных окружения.
@@ -38,9 +80,41 @@ func Load() *Config {
 		DBUser:        LoadEnv("DB_USER", "postgres"),
 		DBPassword:    LoadEnv("DB_PASSWORD", ""),
 		DBName:        LoadEnv("DB_NAME", "postgres"),
-		JwtSecret:     LoadEnv("JWT_SECRET", "not found"),
-		RefreshSecret: LoadEnv("REFRESH_SECRET", "not found"),
+		JwtSecret:     LoadEnv("JWT_SECRET", ""),
+		RefreshSecret: LoadEnv("REFRESH_SECRET", ""),
 		BaseUrl:       LoadEnv("BASE_URL", "http://localhost:3000"),
+		FrontendURL:   LoadEnv("FRONTEND_URL", "http://localhost:3000"),
+
+		JwtKeysDir:    LoadEnv("JWT_KEYS_DIR", ""),
+		JwtSigningKID: LoadEnv("JWT_SIGNING_KID", ""),
+
+		AuthMode:       LoadEnv("AUTH_MODE", AuthModeBearer),
+		CookieDomain:   LoadEnv("COOKIE_DOMAIN", ""),
+		CookieSecure:   LoadEnv("COOKIE_SECURE", "true") == "true",
+		CookieSameSite: LoadEnv("COOKIE_SAMESITE", "Lax"),
+		CORSOrigins:    LoadEnv("CORS_ORIGINS", ""),
+
+		RequireVerifiedEmailFor: LoadEnv("REQUIRE_VERIFIED_EMAIL_FOR", ""),
+		RequireAdmin2FA:         LoadEnv("REQUIRE_ADMIN_2FA", "false") == "true",
+
+		TelegramLoginBotToken: LoadEnv("TELEGRAM_LOGIN_BOT_TOKEN", LoadEnv("TELEGRAM_BOT_TOKEN", "")),
+
+		NotifyChannels:   LoadEnv("NOTIFY_CHANNELS", ""),
+		TelegramBotToken: LoadEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:   LoadEnv("TELEGRAM_CHAT_ID", ""),
+		SMTPHost:         LoadEnv("SMTP_HOST", ""),
+		SMTPPort:         LoadEnv("SMTP_PORT", "587"),
+		SMTPUsername:     LoadEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     LoadEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:         LoadEnv("SMTP_FROM", "shop@socialsh.ru"),
+		OrderEmail:       LoadEnv("ORDER_EMAIL", ""),
+
+		PaymentReturnURL:      LoadEnv("PAYMENT_RETURN_URL", "http://localhost:3000/account"),
+		YooKassaShopID:        LoadEnv("YOOKASSA_SHOP_ID", ""),
+		YooKassaSecretKey:     LoadEnv("YOOKASSA_SECRET_KEY", ""),
+		YooKassaWebhookSecret: LoadEnv("YOOKASSA_WEBHOOK_SECRET", ""),
+		YooKassaAPIURL:        LoadEnv("YOOKASSA_API_URL", ""),
+		FakePaymentsSecret:    LoadEnv("FAKE_PAYMENTS_SECRET", ""),
 	}
 }
 
@@ -53,6 +127,71 @@ func LoadEnv(key string, replacment string) string {
 	return res
 }
 
+// minSecretLen — секреты короче этого подбираются перебором.
+const minSecretLen = 32
+
+// Режимы AUTH_MODE.
+const (
+	AuthModeBearer = "bearer"
+	AuthModeCookie = "cookie"
+)
+
+// Validate — проверка того, без чего сервер запускать нельзя.
+// JWT_SECRET нужен всегда: им подписаны одноразовые токены (подтверждение email, 2FA-челлендж),
+// а без JWT_KEYS_DIR — ещё и access-токены. REFRESH_SECRET — refresh-токены.
+// С пустым или угадываемым секретом токен подделает кто угодно, поэтому без них не стартуем.
+func (c *Config) Validate() error {
+	if len(c.JwtSecret) < minSecretLen {
+		return fmt.Errorf("JWT_SECRET не задан или короче %d символов", minSecretLen)
+	}
+	if len(c.RefreshSecret) < minSecretLen {
+		return fmt.Errorf("REFRESH_SECRET не задан или короче %d символов", minSecretLen)
+	}
+	if c.JwtSecret == c.RefreshSecret {
+		return fmt.Errorf("JWT_SECRET и REFRESH_SECRET должны различаться")
+	}
+	if c.JwtKeysDir != "" && c.JwtSigningKID == "" {
+		return fmt.Errorf("задан JWT_KEYS_DIR, но не задан JWT_SIGNING_KID")
+	}
+	switch c.AuthMode {
+	case AuthModeBearer:
+	case AuthModeCookie:
+		switch strings.ToLower(c.CookieSameSite) {
+		case "lax", "strict":
+		case "none":
+			// Браузеры отбрасывают SameSite=None без Secure
+			if !c.CookieSecure {
+				return fmt.Errorf("COOKIE_SAMESITE=None требует COOKIE_SECURE=true")
+			}
+		default:
+			return fmt.Errorf("COOKIE_SAMESITE: ожидается Lax, Strict или None, получено %q", c.CookieSameSite)
+		}
+	default:
+		return fmt.Errorf("AUTH_MODE: ожидается bearer или cookie, получено %q", c.AuthMode)
+	}
+	return nil
+}
+
+// AllowedOrigins — origin'ы фронта для CORS с куками (CORS_ORIGINS или FRONTEND_URL).
+// В режиме cookie "*" нельзя: браузер не отдаст ответ на запрос с credentials.
+func (c *Config) AllowedOrigins() string {
+	if c.CORSOrigins != "" {
+		return c.CORSOrigins
+	}
+	return strings.TrimRight(c.FrontendURL, "/")
+}
+
+// RequiresVerifiedEmail — закрыта ли фича (checkout, payments) для юзеров
+// без подтверждённого email. Список — REQUIRE_VERIFIED_EMAIL_FOR через запятую.
+func (c *Config) RequiresVerifiedEmail(feature string) bool {
+	for _, f := range strings.Split(c.RequireVerifiedEmailFor, ",") {
+		if strings.TrimSpace(f) == feature {
+			return true
+		}
+	}
+	return false
+}
+
 // это у нас метод-хелпер чтобы не размазывать сборку dsn по коду
 func (c *Config) PostgresDSN() string {
 	// Если пароль пустой, не включаем его в DSN (для peer authentication на macOS)
@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
@@ -74,63 +77,169 @@ func GetOrders(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"items": orders})
 }
 
+// reauthMaxAge — насколько давно юзер должен был вводить пароль для чувствительных
+// действий (смена email). Старше — нужен POST /api/auth/reauth.
+const reauthMaxAge = 10 * time.Minute
+
+// recentlyAuthenticated — вводил ли юзер пароль за последние reauthMaxAge
+// (authTime кладёт middleware.Protected из claim auth_time).
+func recentlyAuthenticated(c *fiber.Ctx) bool {
+	authTime, _ := c.Locals("authTime").(time.Time)
+	return time.Since(authTime) <= reauthMaxAge
+}
+
 // UpdateProfile — обновление профиля текущего пользователя.
 // PATCH /api/account/me
 // Body: { "name": "Новое имя", "email": "new@mail.com" } — только изменённые поля.
 // Ответ: { "user": { обновлённый профиль } }
-func UpdateProfile(c *fiber.Ctx) error {
-	userID, _ := c.Locals("userID").(string)
-
-	// Парсим body — указатели в DTO позволяют отличить "не прислали" (nil) от "пустая строка"
-	var req models.UpdateProfileRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "невалидный JSON",
-		})
-	}
+//
+// Смена email — способ угнать аккаунт по украденному access-токену
+// (дальше сброс пароля уйдёт на новый адрес), поэтому:
+//   - нужен недавний ввод пароля (reauthMaxAge), иначе 403 с "reauthRequired": true —
+//     фронт спрашивает пароль, дёргает POST /api/auth/reauth и повторяет запрос;
+//   - новый адрес становится неподтверждённым, на него уходит письмо подтверждения;
+//   - на старый адрес уходит уведомление о смене.
+func UpdateProfile(jwtSecret, frontendURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+
+		// Парсим body — указатели в DTO позволяют отличить "не прислали" (nil) от "пустая строка"
+		var req models.UpdateProfileRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "невалидный JSON",
+			})
+		}
 
-	// Проверяем что хоть что-то прислали
-	if req.Name == nil && req.Email == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "нечего обновлять — передай name и/или email",
-		})
-	}
+		// Проверяем что хоть что-то прислали
+		if req.Name == nil && req.Email == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "нечего обновлять — передай name и/или email",
+			})
+		}
 
-	// Валидация: если обновляется email, проверяем что он не занят другим пользователем
-	if req.Email != nil && *req.Email != "" {
-		existingUser, err := Repo.Account.GetUserByEmail(*req.Email)
-		if err != nil && err != sql.ErrNoRows {
+		current, err := Repo.Account.GetUserByID(userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "пользователь не найден",
+				})
+			}
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при проверке email",
+				"error": "ошибка при получении профиля",
 			})
 		}
-		// Если email занят другим пользователем (не текущим)
-		if existingUser != nil && existingUser.ID != userID {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "email уже используется другим пользователем",
-			})
+
+		emailChanged := req.Email != nil && *req.Email != current.Email
+		if emailChanged {
+			if msg := validateEmail(*req.Email); msg != "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+			}
+			if !recentlyAuthenticated(c) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":          "для смены email подтвердите пароль",
+					"reauthRequired": true,
+				})
+			}
+
+			// Проверяем что email не занят другим пользователем
+			existingUser, err := Repo.Account.GetUserByEmail(*req.Email)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "ошибка при проверке email",
+				})
+			}
+			if existingUser != nil && existingUser.ID != userID {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "email уже используется другим пользователем",
+				})
+			}
 		}
-	}
 
-	// Обновляем юзера в БД и получаем обновлённую версию
-	user, err := Repo.Account.UpdateUser(userID, &req)
-	if err != nil {
-		// Проверяем на duplicate key (email уже занят)
-		if utils.IsDuplicateKeyError(err) {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": utils.FormatDuplicateError(err),
+		// Обновляем юзера в БД и получаем обновлённую версию
+		user, err := Repo.Account.UpdateUser(userID, &req)
+		if err != nil {
+			// Проверяем на duplicate key (email уже занят)
+			if utils.IsDuplicateKeyError(err) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": utils.FormatDuplicateError(err),
+				})
+			}
+			// Если пользователь не найден
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "пользователь не найден",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось обновить профиль",
 			})
 		}
-		// Если пользователь не найден
-		if err == sql.ErrNoRows {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "пользователь не найден",
-			})
+
+		if emailChanged {
+			if err := sendVerificationEmail(user, jwtSecret, frontendURL); err != nil {
+				log.Printf("update profile: письмо подтверждения: %v", err)
+			}
+			sendMailAsync(current.Email, "Email аккаунта изменён",
+				"Здравствуйте!\n\n"+
+					"Email вашего аккаунта в магазине SOCIAL SH изменён на "+user.Email+".\n\n"+
+					"Если это были не вы — срочно восстановите доступ через «Забыли пароль?» "+
+					"и напишите нам на странице контактов.")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "не удалось обновить профиль",
-		})
+
+		return c.JSON(fiber.Map{"user": user})
 	}
+}
 
-	return c.JSON(fiber.Map{"user": user})
+// ChangePassword — смена пароля из личного кабинета.
+// POST /api/account/password
+// Body: { "currentPassword": "...", "newPassword": "..." }
+// Ответ 200: { "message": "ok" }
+//
+// Логика:
+//  1. Проверяем текущий пароль (украденного access-токена мало, чтобы сменить пароль);
+//     неудачи считаются вместе с неудачами входа (см. login_throttle.go)
+//  2. Валидируем и хешируем новый
+//  3. Repo.Account.ChangePassword — новый хеш + выход на всех остальных устройствах;
+//     текущий вход (sessionID) остаётся
+func ChangePassword(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "невалидный JSON"})
+	}
+
+	// 1. Текущий пароль
+	user, err := Repo.Account.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "пользователь не найден"})
+	}
+	attempt, wait := startLoginAttempt(loginThrottleKeys(c, user.Email))
+	if wait > 0 {
+		return tooManyLoginAttempts(c, wait)
+	}
+	if !checkPassword(user.PasswordHash, req.CurrentPassword) {
+		attempt.failed()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "неверный текущий пароль"})
+	}
+	attempt.succeeded()
+
+	// 2. Новый пароль
+	if msg := validatePassword(req.NewPassword); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	hashed, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "не удалось захешировать пароль"})
+	}
+
+	// 3. Сохраняем и отзываем остальные входы
+	if err := Repo.Account.ChangePassword(userID, hashed, sessionID); err != nil {
+		log.Printf("change password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "не удалось сменить пароль"})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
 }
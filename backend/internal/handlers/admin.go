@@ -2,18 +2,20 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
 	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
 	"socialsh/backend/internal/utils"
 )
 
 // ═══════════════════════════════════════════════════════════════
 // Админские хендлеры для CRUD операций.
-// Все роуты защищены: Protected(jwtSecret) → AdminOnly().
-// Если юзер не админ — 403 на уровне middleware, сюда не дойдёт.
+// Все роуты защищены: Protected(jwtSecret) → AdminOnly() → RequirePermission(право раздела).
+// Если у роли нет нужного права — 403 на уровне middleware, сюда не дойдёт.
 // ═══════════════════════════════════════════════════════════════
 
 // ──── Товары ────
@@ -77,6 +79,16 @@ func AdminCreateProduct(c *fiber.Ctx) error {
 			"error": "price должен быть больше 0",
 		})
 	}
+	if product.Stock < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "stock не может быть отрицательным",
+		})
+	}
+	if product.WeightGrams < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "weightGrams не может быть отрицательным",
+		})
+	}
 
 	// TODO: вызвать Repo.Products.Create(&product)
 	// Create должен:
@@ -176,6 +188,12 @@ func AdminUpdateProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	if product.WeightGrams < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "weightGrams не может быть отрицательным",
+		})
+	}
+
 	updated, err := Repo.Products.Update(id, &product)
 	if err != nil {
 		// Если товар не найден (sql.ErrNoRows) — возвращаем 404
@@ -204,6 +222,54 @@ func AdminUpdateProduct(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"item": updated})
 }
 
+// AdminSetProductStock — выставить остаток товара на складе.
+// PATCH /api/admin/products/:id/stock
+// Body: { "stock": 25 }
+// Ответ: { "item": { товар с новым stock/reserved/inStock } }
+//
+// Отдельный роут, а не поле в AdminUpdateProduct: Update перезаписывает все поля,
+// и форма редактирования без stock обнулила бы склад.
+// Остаток меньше уже зарезервированного заказами → 409.
+func AdminSetProductStock(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	var req models.UpdateStockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if req.Stock < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "stock не может быть отрицательным",
+		})
+	}
+
+	updated, err := Repo.Products.SetStock(id, req.Stock)
+	if err != nil {
+		if errors.Is(err, repository.ErrStockBelowReserved) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "остаток не может быть меньше зарезервированного заказами",
+			})
+		}
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "товар не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось обновить остаток",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": updated})
+}
+
 // AdminDeleteProduct — удаление товара по ID.
 // DELETE /api/admin/products/:id
 // Ответ: { "message": "ok" }
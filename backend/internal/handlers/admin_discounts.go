@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Админские хендлеры промокодов.
+// Проверка промокода на чекауте — в checkout.go (applyDiscountCode).
+// ═══════════════════════════════════════════════════════════════
+
+// AdminListDiscounts — все промокоды.
+// GET /api/admin/discounts
+// Ответ: { "items": [ { "id", "code", "kind", "value", "usedCount", ... }, ... ] }
+func AdminListDiscounts(c *fiber.Ctx) error {
+	items, err := Repo.Discounts.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить промокоды",
+		})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// AdminGetDiscount — один промокод по ID.
+// GET /api/admin/discounts/:id
+// Ответ: { "item": { ... } }
+func AdminGetDiscount(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	discount, err := Repo.Discounts.GetByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "промокод не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при получении промокода",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": discount})
+}
+
+// AdminCreateDiscount — создать промокод.
+// POST /api/admin/discounts
+// Body: { "code": "SALE10", "kind": "percent", "value": 10, "minOrderTotal": 300000,
+// "startsAt": "2024-11-01T00:00:00Z", "endsAt": null, "usageLimit": 100,
+// "perCustomerLimit": 1, "productIds": [], "active": true }
+// Ответ 201: { "item": { созданный промокод } }
+// Дубликат кода → 409.
+func AdminCreateDiscount(c *fiber.Ctx) error {
+	var discount models.Discount
+	if err := c.BodyParser(&discount); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if msg := validateDiscount(&discount); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := Repo.Discounts.Create(&discount); err != nil {
+		if utils.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "промокод с таким кодом уже существует",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось создать промокод",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"item": discount})
+}
+
+// AdminUpdateDiscount — изменить промокод (все поля перезаписываются).
+// PATCH /api/admin/discounts/:id
+// Body: как в AdminCreateDiscount
+// Ответ: { "item": { обновлённый промокод } }
+func AdminUpdateDiscount(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	var discount models.Discount
+	if err := c.BodyParser(&discount); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if msg := validateDiscount(&discount); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	updated, err := Repo.Discounts.Update(id, &discount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "промокод не найден",
+			})
+		}
+		if utils.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "промокод с таким кодом уже существует",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось обновить промокод",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": updated})
+}
+
+// AdminDeleteDiscount — удалить промокод.
+// DELETE /api/admin/discounts/:id
+// Ответ: { "message": "ok" }
+// Чтобы просто выключить промокод, лучше PATCH с "active": false — история применений останется.
+func AdminDeleteDiscount(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	if err := Repo.Discounts.Delete(id); err != nil {
+		if strings.Contains(err.Error(), "не найден") || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "промокод не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось удалить промокод",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
+
+// validateDiscount — общая валидация для create/update.
+// Возвращает текст ошибки или "" если всё ок.
+func validateDiscount(d *models.Discount) string {
+	d.Code = strings.ToUpper(strings.TrimSpace(d.Code))
+	if d.Code == "" {
+		return "code обязателен"
+	}
+
+	switch d.Kind {
+	case models.DiscountKindPercent:
+		if d.Value < 1 || d.Value > 100 {
+			return "для процентной скидки value должен быть от 1 до 100"
+		}
+	case models.DiscountKindFixed:
+		if d.Value <= 0 {
+			return "value должен быть больше 0"
+		}
+	default:
+		return "kind должен быть percent или fixed"
+	}
+
+	if d.MinOrderTotal < 0 {
+		return "minOrderTotal не может быть отрицательным"
+	}
+	if d.StartsAt != nil && d.EndsAt != nil && !d.EndsAt.After(*d.StartsAt) {
+		return "endsAt должен быть позже startsAt"
+	}
+	if d.UsageLimit != nil && *d.UsageLimit < 1 {
+		return "usageLimit должен быть не меньше 1"
+	}
+	if d.PerCustomerLimit != nil && *d.PerCustomerLimit < 1 {
+		return "perCustomerLimit должен быть не меньше 1"
+	}
+	return ""
+}
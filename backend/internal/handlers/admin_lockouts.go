@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Админские хендлеры блокировок входа (перебор паролей).
+// Сама защита — в login_throttle.go.
+// ═══════════════════════════════════════════════════════════════
+
+// AdminListLoginLockouts — заблокированные сейчас email/IP и ключи с недавними неудачами.
+// GET /api/admin/login-lockouts
+// Ответ: { "items": [ { "id", "kind", "subject", "failures", "lastFailureAt", "lockedUntil" }, ... ] }
+// Лимит запросов ссылок для входа (magic-link-ip) сюда не попадает — это не перебор паролей.
+func AdminListLoginLockouts(c *fiber.Ctx) error {
+	kinds := []string{models.LoginThrottleEmail, models.LoginThrottleIP}
+	items, err := Repo.LoginThrottle.ListActive(kinds, loginThrottlePolicies[models.LoginThrottleEmail].window)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить блокировки",
+		})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// AdminClearLoginLockout — снять блокировку и обнулить счётчик неудач.
+// DELETE /api/admin/login-lockouts/:id
+// Ответ: { "message": "ok" }
+func AdminClearLoginLockout(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	if err := Repo.LoginThrottle.Delete(id); err != nil {
+		if strings.Contains(err.Error(), "не найден") || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "блокировка не найдена",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось снять блокировку",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Админские хендлеры заказов.
+// Роуты под /api/admin/orders, защищены так же как остальная админка.
+// Статус меняется только по таблице models.OrderTransitions,
+// каждый переход пишется в историю с ID админа.
+// ═══════════════════════════════════════════════════════════════
+
+// AdminListOrders — список заказов с фильтрами.
+// GET /api/admin/orders?status=paid&from=2024-01-01&to=2024-01-31&customer=ivan&page=1&limit=50
+// from/to — дата (YYYY-MM-DD, to включительно) или RFC3339.
+// customer — подстрока email или имени покупателя.
+// Ответ: { "items": [ { заказ с items и history }, ... ] }
+func AdminListOrders(c *fiber.Ctx) error {
+	filter := models.OrderFilter{
+		Status:   c.Query("status"),
+		Customer: c.Query("customer"),
+	}
+
+	if filter.Status != "" && !isKnownOrderStatus(filter.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неизвестный статус заказа",
+		})
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseDateParam(from)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "неверный формат from, ожидается YYYY-MM-DD",
+			})
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseDateParam(to)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "неверный формат to, ожидается YYYY-MM-DD",
+			})
+		}
+		// Дата без времени — включаем весь день целиком
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = &t
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	filter.Page = page
+	filter.Limit = limit
+
+	items, err := Repo.Orders.List(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить список заказов",
+		})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// AdminGetOrder — один заказ с позициями и историей статусов.
+// GET /api/admin/orders/:id
+// Ответ: { "item": { ... } }
+func AdminGetOrder(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	order, err := Repo.Orders.GetByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "заказ не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при получении заказа",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": order})
+}
+
+// AdminUpdateOrderStatus — перевести заказ в другой статус.
+// PATCH /api/admin/orders/:id/status
+// Body: { "status": "shipped", "comment": "трек RA123456789RU" }
+// Ответ: { "item": { обновлённый заказ с history } }
+//
+// Логика:
+//  1. Валидируем что статус вообще существует → 400.
+//  2. Repo.Orders.UpdateStatus проверяет переход и пишет историю с ID админа.
+//  3. Недопустимый переход (например delivered → pending) → 409.
+func AdminUpdateOrderStatus(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	var req models.UpdateOrderStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if !isKnownOrderStatus(req.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неизвестный статус заказа",
+		})
+	}
+
+	adminID, _ := c.Locals("userID").(string)
+
+	order, err := Repo.Orders.UpdateStatus(id, req.Status, adminID, req.Comment)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidStatusTransition) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "недопустимый переход статуса",
+			})
+		}
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "заказ не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось обновить статус заказа",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": order})
+}
+
+// isKnownOrderStatus — есть ли такой статус вообще.
+func isKnownOrderStatus(status string) bool {
+	switch status {
+	case models.OrderStatusPending, models.OrderStatusPaid, models.OrderStatusShipped,
+		models.OrderStatusDelivered, models.OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// parseDateParam — разбирает дату из query: YYYY-MM-DD или RFC3339.
+// dateOnly = true, если пришла дата без времени.
+func parseDateParam(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	return t, false, err
+}
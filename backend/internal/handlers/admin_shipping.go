@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Админские хендлеры способов доставки.
+// Расчёт стоимости — в shipping.go (quoteShipping).
+// ═══════════════════════════════════════════════════════════════
+
+// AdminListShippingMethods — все способы доставки, включая выключенные.
+// GET /api/admin/shipping-methods
+// Ответ: { "items": [ ... ] }
+func AdminListShippingMethods(c *fiber.Ctx) error {
+	items, err := Repo.Shipping.ListAll()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить способы доставки",
+		})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// AdminCreateShippingMethod — создать способ доставки.
+// POST /api/admin/shipping-methods
+// Body: { "code": "courier", "title": "Курьер", "kind": "courier", "freeFrom": 500000,
+// "rules": [ { "zone": "moscow", "maxWeightGrams": 5000, "price": 39000 },
+// { "zone": "*", "maxWeightGrams": 0, "price": 69000 } ], "active": true, "sortOrder": 1 }
+// Ответ 201: { "item": { созданный способ } }
+// Дубликат code → 409.
+func AdminCreateShippingMethod(c *fiber.Ctx) error {
+	var method models.ShippingMethod
+	if err := c.BodyParser(&method); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if msg := validateShippingMethod(&method); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	if err := Repo.Shipping.Create(&method); err != nil {
+		if utils.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "code уже используется",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось создать способ доставки",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"item": method})
+}
+
+// AdminUpdateShippingMethod — изменить способ доставки (все поля перезаписываются).
+// PATCH /api/admin/shipping-methods/:id
+// Body: как в AdminCreateShippingMethod
+// Ответ: { "item": { обновлённый способ } }
+func AdminUpdateShippingMethod(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	var method models.ShippingMethod
+	if err := c.BodyParser(&method); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if msg := validateShippingMethod(&method); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	updated, err := Repo.Shipping.Update(id, &method)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "способ доставки не найден",
+			})
+		}
+		if utils.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "code уже используется",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось обновить способ доставки",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": updated})
+}
+
+// AdminDeleteShippingMethod — удалить способ доставки.
+// DELETE /api/admin/shipping-methods/:id
+// Ответ: { "message": "ok" }
+func AdminDeleteShippingMethod(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	if err := Repo.Shipping.Delete(id); err != nil {
+		if strings.Contains(err.Error(), "не найден") || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "способ доставки не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось удалить способ доставки",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
+
+// validateShippingMethod — общая валидация для create/update.
+// Возвращает текст ошибки или "" если всё ок.
+func validateShippingMethod(m *models.ShippingMethod) string {
+	m.Code = strings.TrimSpace(m.Code)
+	m.Title = strings.TrimSpace(m.Title)
+	if m.Code == "" {
+		return "code обязателен"
+	}
+	if m.Title == "" {
+		return "title обязателен"
+	}
+
+	switch m.Kind {
+	case models.ShippingKindCourier, models.ShippingKindPickup, models.ShippingKindPost:
+	default:
+		return "kind должен быть courier, pickup или post"
+	}
+
+	if len(m.Rules) == 0 {
+		return "нужно хотя бы одно правило цены"
+	}
+	for i := range m.Rules {
+		m.Rules[i].Zone = strings.TrimSpace(m.Rules[i].Zone)
+		if m.Rules[i].Zone == "" {
+			return "у каждого правила должна быть zone (или \"*\" для любой)"
+		}
+		if m.Rules[i].MaxWeightGrams < 0 {
+			return "maxWeightGrams не может быть отрицательным"
+		}
+		if m.Rules[i].Price < 0 {
+			return "price не может быть отрицательным"
+		}
+	}
+
+	if m.FreeFrom != nil && *m.FreeFrom < 0 {
+		return "freeFrom не может быть отрицательным"
+	}
+	return ""
+}
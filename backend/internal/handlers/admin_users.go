@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Админские хендлеры пользователей: поиск, роли, блокировка, выход со всех устройств.
+// Все роуты — под правом users:manage (см. пакет auth).
+// ═══════════════════════════════════════════════════════════════
+
+// AdminListUsers — поиск пользователей.
+// GET /api/admin/users?q=ivan&role=owner&page=1&limit=50
+// Ответ: { "items": [ { "id", "email", "name", "role", "blockedAt", "createdAt", "orderCount", ... }, ... ] }
+func AdminListUsers(c *fiber.Ctx) error {
+	filter := models.UserFilter{
+		Query: c.Query("q"),
+		Role:  c.Query("role"),
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+	filter.Page = page
+	filter.Limit = limit
+
+	items, err := Repo.Users.List(filter)
+	if err != nil {
+		log.Printf("admin users: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить список пользователей",
+		})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// AdminGetUser — профиль пользователя с числом заказов.
+// GET /api/admin/users/:id
+// Ответ: { "item": { ... } }
+func AdminGetUser(c *fiber.Ctx) error {
+	user, err := Repo.Users.GetByID(c.Params("id"))
+	if err != nil {
+		return adminUserError(c, err, "ошибка при получении пользователя")
+	}
+
+	return c.JSON(fiber.Map{"item": user})
+}
+
+// AdminUpdateUserRole — сменить роль.
+// PATCH /api/admin/users/:id/role
+// Body: { "role": "catalog-manager" }
+// Ответ: { "item": { ... } }
+// Новая роль попадёт в access-токен при следующем рефреше (до 15 минут).
+// Последнего owner понизить нельзя — 409.
+func AdminUpdateUserRole(c *fiber.Ctx) error {
+	var req models.UpdateUserRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if !auth.ValidRole(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неизвестная роль",
+		})
+	}
+
+	user, err := Repo.Users.SetRole(c.Params("id"), req.Role)
+	if err != nil {
+		return adminUserError(c, err, "не удалось сменить роль")
+	}
+
+	return c.JSON(fiber.Map{"item": user})
+}
+
+// AdminBlockUser — заблокировать: вход и рефреш запрещены, все сессии завершаются сразу.
+// POST /api/admin/users/:id/block
+// Ответ: { "item": { ... } }
+func AdminBlockUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if currentUserID, _ := c.Locals("userID").(string); id == currentUserID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "нельзя заблокировать самого себя",
+		})
+	}
+
+	user, err := Repo.Users.SetBlocked(id, true)
+	if err != nil {
+		return adminUserError(c, err, "не удалось заблокировать пользователя")
+	}
+
+	return c.JSON(fiber.Map{"item": user})
+}
+
+// AdminUnblockUser — снять блокировку. Войти юзер должен заново.
+// POST /api/admin/users/:id/unblock
+// Ответ: { "item": { ... } }
+func AdminUnblockUser(c *fiber.Ctx) error {
+	user, err := Repo.Users.SetBlocked(c.Params("id"), false)
+	if err != nil {
+		return adminUserError(c, err, "не удалось разблокировать пользователя")
+	}
+
+	return c.JSON(fiber.Map{"item": user})
+}
+
+// AdminLogoutUser — завершить все сессии пользователя (например, после утечки пароля).
+// POST /api/admin/users/:id/logout
+// Ответ: { "message": "ok" }
+func AdminLogoutUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := Repo.Users.GetByID(id); err != nil {
+		return adminUserError(c, err, "не удалось завершить сессии")
+	}
+
+	if err := Repo.RefreshTokens.RevokeAllForUser(id); err != nil {
+		log.Printf("admin users: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось завершить сессии",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
+
+// adminUserError — общий разбор ошибок репозитория пользователей:
+// нет юзера → 404, последний owner → 409, остальное → 500 с текстом fallback.
+func adminUserError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	case errors.Is(err, repository.ErrLastOwner):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": repository.ErrLastOwner.Error(),
+		})
+	}
+	log.Printf("admin users: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": fallback,
+	})
+}
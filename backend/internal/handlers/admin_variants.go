@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Админские хендлеры вариантов товара (размер/цвет).
+// Список и создание — вложенные в товар: /api/admin/products/:id/variants.
+// Изменение и удаление — по ID варианта: /api/admin/variants/:id.
+// ═══════════════════════════════════════════════════════════════
+
+// AdminListVariants — все варианты товара.
+// GET /api/admin/products/:id/variants
+// Ответ: { "items": [ { "id", "sku", "options", "price", "stock", ... }, ... ] }
+func AdminListVariants(c *fiber.Ctx) error {
+	productID := c.Params("id")
+	if productID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	items, err := Repo.Variants.ListByProduct(productID)
+	if err != nil {
+		if utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "товар не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить варианты товара",
+		})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// AdminCreateVariant — добавить вариант товару.
+// POST /api/admin/products/:id/variants
+// Body: { "sku": "HOODIE-BLK-M", "options": { "size": "M" }, "price": null, "stock": 10 }
+// Ответ 201: { "item": { созданный вариант } }
+//
+// price = null — вариант продаётся по цене товара.
+// Дубликат SKU → 409.
+func AdminCreateVariant(c *fiber.Ctx) error {
+	productID := c.Params("id")
+	if productID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	var variant models.ProductVariant
+	if err := c.BodyParser(&variant); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if msg := validateVariant(&variant); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+	if variant.Stock < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "stock не может быть отрицательным",
+		})
+	}
+
+	// Проверяем что товар существует — иначе FK упадёт с невнятной 500
+	if _, err := Repo.Products.GetByID(productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "товар не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при получении товара",
+		})
+	}
+
+	variant.ProductID = productID
+	if err := Repo.Variants.Create(&variant); err != nil {
+		if utils.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "sku уже используется",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось создать вариант",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"item": variant})
+}
+
+// AdminUpdateVariant — изменить SKU, опции и цену варианта.
+// PATCH /api/admin/variants/:id
+// Body: { "sku": "...", "options": { ... }, "price": 5490 }
+// Ответ: { "item": { обновлённый вариант } }
+// Остаток меняется отдельно — PATCH /api/admin/variants/:id/stock.
+func AdminUpdateVariant(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	var variant models.ProductVariant
+	if err := c.BodyParser(&variant); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if msg := validateVariant(&variant); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	updated, err := Repo.Variants.Update(id, &variant)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "вариант не найден",
+			})
+		}
+		if utils.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "sku уже используется",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось обновить вариант",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": updated})
+}
+
+// AdminSetVariantStock — выставить остаток варианта.
+// PATCH /api/admin/variants/:id/stock
+// Body: { "stock": 10 }
+// Ответ: { "item": { вариант } }. Меньше резерва → 409.
+func AdminSetVariantStock(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	var req models.UpdateStockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if req.Stock < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "stock не может быть отрицательным",
+		})
+	}
+
+	updated, err := Repo.Variants.SetStock(id, req.Stock)
+	if err != nil {
+		if errors.Is(err, repository.ErrStockBelowReserved) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "остаток не может быть меньше зарезервированного заказами",
+			})
+		}
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "вариант не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось обновить остаток",
+		})
+	}
+
+	return c.JSON(fiber.Map{"item": updated})
+}
+
+// AdminDeleteVariant — удалить вариант.
+// DELETE /api/admin/variants/:id
+// Ответ: { "message": "ok" }
+func AdminDeleteVariant(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	if err := Repo.Variants.Delete(id); err != nil {
+		if strings.Contains(err.Error(), "не найден") || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "вариант не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось удалить вариант",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
+
+// validateVariant — общая валидация для create/update.
+// Возвращает текст ошибки или "" если всё ок.
+func validateVariant(v *models.ProductVariant) string {
+	v.SKU = strings.TrimSpace(v.SKU)
+	if v.SKU == "" {
+		return "sku обязателен"
+	}
+	if v.Price != nil && *v.Price <= 0 {
+		return "price должен быть больше 0"
+	}
+	return ""
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
+	"net/mail"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
+	"socialsh/backend/internal/auth"
 	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
 	"socialsh/backend/internal/utils"
 )
 
@@ -28,26 +34,103 @@ func checkPassword(hash, password string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
 }
 
-// generateTokens — генерирует пару access + refresh JWT.
-// access: живёт 15 минут, claims: sub (userID), role.
-// refresh: живёт 7 дней, claims: sub (userID).
-func generateTokens(userID, role, jwtSecret, refreshSecret string) (*models.TokenResponse, error) {
+// validatePassword — требования к паролю. Пустая строка — пароль подходит,
+// иначе текст ошибки для ответа 400.
+// 72 байта — предел bcrypt, всё что длиннее он молча обрезает.
+func validatePassword(password string) string {
+	if len(password) < 8 {
+		return "пароль должен содержать минимум 8 символов"
+	}
+	if len(password) > 72 {
+		return "пароль не должен превышать 72 символа"
+	}
+	return ""
+}
+
+// validateEmail — проверка формата email через net/mail.
+// Принимаем только голый адрес: "Имя <a@b.ru>" и адреса с пробелами по краям — нет.
+func validateEmail(email string) string {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "некорректный email"
+	}
+	return ""
+}
+
+// signPurposeToken — JWT для ссылок из писем (подтверждение email и т.п.).
+// Подписывается ключом, производным от secret и purpose, поэтому такой токен
+// не пройдёт ни как access-токен, ни как токен другого назначения.
+func signPurposeToken(secret, purpose string, claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(purposeKey(secret, purpose))
+	if err != nil {
+		return "", fmt.Errorf("не удалось подписать токен %s: %w", purpose, err)
+	}
+	return signed, nil
+}
+
+// parsePurposeToken — проверить подпись и срок токена из signPurposeToken.
+func parsePurposeToken(secret, purpose, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("неверный метод подписи")
+		}
+		return purposeKey(secret, purpose), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("невалидный или просроченный токен %s", purpose)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("не удалось прочитать claims токена %s", purpose)
+	}
+	return claims, nil
+}
+
+// purposeKey — HMAC(secret, purpose): отдельный ключ на каждое назначение токена.
+func purposeKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Время жизни токенов.
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// generateTokens — подписывает пару access + refresh JWT и записывает sha256
+// refresh-токена в rt.TokenHash (сохраняет запись вызывающий).
+// access: живёт 15 минут, claims: sub (userID), role, sid (семья refresh-токенов —
+// по ней logout находит, что отзывать), auth_time (когда юзер последний раз вводил пароль),
+// mfa (вход подтверждён вторым фактором).
+// refresh: живёт 7 дней, claims: sub (userID), sid, jti (ID строки в refresh_tokens), auth_time, mfa —
+// при рефреше auth_time и mfa переносятся в новую пару как есть.
+func generateTokens(user *models.User, rt *models.RefreshToken, refreshSecret string) (*models.TokenResponse, error) {
 	// Access-токен (15 мин)
 	accessClaims := jwt.MapClaims{
-		"sub":  userID,
-		"role": role,
-		"exp":  time.Now().Add(15 * time.Minute).Unix(),
+		"sub":       user.ID,
+		"role":      user.Role,
+		"sid":       rt.FamilyID,
+		"auth_time": rt.AuthTime.Unix(),
+		"mfa":       rt.MFA,
+		"exp":       time.Now().Add(accessTokenTTL).Unix(),
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	access, err := accessToken.SignedString([]byte(jwtSecret))
+	access, err := Keys.Sign(accessClaims)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось подписать access-токен: %w", err)
 	}
 
 	// Refresh-токен (7 дней)
 	refreshClaims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(7 * 24 * time.Hour).Unix(),
+		"sub":       user.ID,
+		"sid":       rt.FamilyID,
+		"jti":       rt.ID,
+		"auth_time": rt.AuthTime.Unix(),
+		"mfa":       rt.MFA,
+		"exp":       rt.ExpiresAt.Unix(),
 	}
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
 	refresh, err := refreshToken.SignedString([]byte(refreshSecret))
@@ -55,9 +138,58 @@ func generateTokens(userID, role, jwtSecret, refreshSecret string) (*models.Toke
 		return nil, fmt.Errorf("не удалось подписать refresh-токен: %w", err)
 	}
 
+	rt.TokenHash = utils.HashToken(refresh)
 	return &models.TokenResponse{Access: access, Refresh: refresh}, nil
 }
 
+// newRefreshToken — заготовка записи refresh-токена: новый jti, срок жизни,
+// User-Agent и IP запроса. familyID пустой — начинается новая семья (вход).
+func newRefreshToken(c *fiber.Ctx, userID, familyID string) (*models.RefreshToken, error) {
+	jti, err := utils.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	if familyID == "" {
+		familyID = jti
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	return &models.RefreshToken{
+		ID:        jti,
+		FamilyID:  familyID,
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        c.IP(),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}, nil
+}
+
+// issueTokens — выдать токены при входе: новая семья refresh-токенов,
+// подписываем пару и сохраняем refresh (его хеш) в БД. auth_time — сейчас,
+// mfa — был ли на этом входе введён второй фактор.
+func issueTokens(c *fiber.Ctx, user *models.User, refreshSecret string, mfa bool) (*models.TokenResponse, error) {
+	rt, err := newRefreshToken(c, user.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	rt.AuthTime = time.Now()
+	rt.MFA = mfa
+
+	tokens, err := generateTokens(user, rt, refreshSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Repo.RefreshTokens.Create(rt); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
 // ──── Хендлеры ────
 
 // SignUp — регистрация нового пользователя.
@@ -70,10 +202,14 @@ func generateTokens(userID, role, jwtSecret, refreshSecret string) (*models.Toke
 //  2. Валидируем поля (email, password длина)
 //  3. Проверяем что email свободен → Repo.Account.GetUserByEmail
 //  4. Хешируем пароль → bcrypt
-//  5. Создаём юзера → Repo.Account.CreateUser
-//  6. Генерируем access + refresh JWT
-//  7. Возвращаем токены
-func SignUp(jwtSecret, refreshSecret string) fiber.Handler {
+//  5. Создаём юзера → Repo.Account.CreateUser (email ещё не подтверждён);
+//     его ID — в c.Locals для Idempotency-Key (повтор выдаст новую сессию, см. SignUpReplay)
+//  6. Отправляем письмо со ссылкой подтверждения. Гостевые заказы на этот email
+//     привяжутся только после подтверждения (ConfirmEmail) — иначе любой мог бы
+//     зарегистрироваться на чужой адрес и увидеть чужие заказы
+//  7. Генерируем access + refresh JWT
+//  8. Возвращаем токены
+func SignUp(jwtSecret, refreshSecret, frontendURL string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req models.SignUpRequest
 
@@ -86,11 +222,11 @@ func SignUp(jwtSecret, refreshSecret string) fiber.Handler {
 		if req.Email == "" {
 			return c.Status(400).JSON(fiber.Map{"error": "email обязателен"})
 		}
-		if len(req.Password) < 8 {
-			return c.Status(400).JSON(fiber.Map{"error": "пароль должен содержать минимум 8 символов"})
+		if msg := validateEmail(req.Email); msg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": msg})
 		}
-		if len(req.Password) > 72 {
-			return c.Status(400).JSON(fiber.Map{"error": "пароль не должен превышать 72 символа"})
+		if msg := validatePassword(req.Password); msg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": msg})
 		}
 
 		// 3. Проверяем что email не занят
@@ -114,7 +250,7 @@ func SignUp(jwtSecret, refreshSecret string) fiber.Handler {
 			Email:        req.Email,
 			Name:         req.Name,
 			PasswordHash: hashed,
-			Role:         "user", // по умолчанию — обычный юзер
+			Role:         auth.RoleUser, // по умолчанию — обычный юзер
 		}
 
 		if err := Repo.Account.CreateUser(user); err != nil {
@@ -126,15 +262,65 @@ func SignUp(jwtSecret, refreshSecret string) fiber.Handler {
 			}
 			return c.Status(500).JSON(fiber.Map{"error": "не удалось создать пользователя"})
 		}
+		c.Locals("idempotencyResource", user.ID)
 
-		// 6. Генерируем JWT-токены
-		tokens, err := generateTokens(user.ID, user.Role, jwtSecret, refreshSecret)
+		// 6. Письмо подтверждения. Ошибка тут не должна ломать регистрацию —
+		// юзер уже создан и может запросить письмо ещё раз.
+		if err := sendVerificationEmail(user, jwtSecret, frontendURL); err != nil {
+			log.Printf("sign-up: %v", err)
+		}
+
+		// 7. Генерируем JWT-токены (refresh сохраняется в БД)
+		tokens, err := issueTokens(c, user, refreshSecret, false)
 		if err != nil {
+			log.Printf("sign-up: %v", err)
 			return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
 		}
 
-		// 7. Возвращаем токены
-		return c.Status(201).JSON(tokens)
+		// 8. Возвращаем токены (в теле или куками — см. respondTokens)
+		return respondTokens(c, 201, tokens, nil)
+	}
+}
+
+// SignUpReplay — повтор POST /api/auth/sign-up с тем же Idempotency-Key и телом
+// (см. middleware.IdempotencyReplay). Токены первого ответа не хранятся — выдаём
+// новую сессию юзеру, созданному первым запросом. Ответ 201 как у SignUp.
+//
+// Логика:
+//  1. Юзер по сохранённому ID; пароль из тела сверяем с текущим хешем —
+//     пароль сменили (или аккаунт удалён) → 409, как у повторной регистрации:
+//     старый запрос не должен работать как вход в обход нового пароля
+//  2. Заблокирован → 403; включил 2FA → challenge, как у SignIn
+//  3. Иначе — новая пара токенов
+func SignUpReplay(jwtSecret, refreshSecret string) func(c *fiber.Ctx, userID string) error {
+	return func(c *fiber.Ctx, userID string) error {
+		var req models.SignUpRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "невалидный JSON"})
+		}
+
+		// 1. Тот же юзер с тем же паролем
+		user, err := Repo.Account.GetUserByID(userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return c.Status(500).JSON(fiber.Map{"error": "ошибка при проверке email"})
+		}
+		if user == nil || !checkPassword(user.PasswordHash, req.Password) {
+			return c.Status(409).JSON(fiber.Map{"error": "пользователь с таким email уже существует"})
+		}
+
+		// 2-3. Дальше как вход, но со статусом регистрации
+		if user.BlockedAt != nil {
+			return c.Status(403).JSON(fiber.Map{"error": "аккаунт заблокирован"})
+		}
+		if user.TOTPEnabled {
+			return completeSignIn(c, user, jwtSecret, refreshSecret)
+		}
+		tokens, err := issueTokens(c, user, refreshSecret, false)
+		if err != nil {
+			log.Printf("sign-up replay: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
+		}
+		return respondTokens(c, 201, tokens, nil)
 	}
 }
 
@@ -142,13 +328,22 @@ func SignUp(jwtSecret, refreshSecret string) fiber.Handler {
 // POST /api/auth/sign-in
 // Body: { "email": "...", "password": "..." }
 // Ответ 200: { "access": "<jwt>", "refresh": "<jwt>" }
+// В режиме AUTH_MODE=cookie вместо токенов в теле — куки и { "csrfToken" } (см. respondTokens)
+// Ответ 200 при включённой 2FA: { "mfaRequired": true, "challenge": "<jwt на 5 минут>" } —
+// токены выдаст POST /api/auth/2fa/verify (см. two_factor.go)
+// Ответ 429: { "error", "retryAfter": <сек> } + Retry-After — слишком много неудачных попыток
 //
 // Логика:
 //  1. Парсим body → SignInRequest
-//  2. Ищем юзера по email
-//  3. Сравниваем пароль через bcrypt
-//  4. Генерируем access + refresh JWT
-//  5. Возвращаем токены
+//  2. Засчитываем попытку по email и IP (см. login_throttle.go);
+//     ключ заблокирован или попыток больше лимита → 429
+//  3. Ищем юзера по email, сравниваем пароль через bcrypt.
+//     Неудача → попытка остаётся в счётчиках; успех → счётчик email обнуляется,
+//     из счётчика IP попытка вычитается
+//     Аккаунт заблокирован админом → 403
+//  4. Включена 2FA → вместо токенов challenge для второго шага
+//  5. Генерируем access + refresh JWT (новая семья, refresh сохраняется в БД)
+//  6. Возвращаем токены
 func SignIn(jwtSecret, refreshSecret string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req models.SignInRequest
@@ -161,45 +356,95 @@ func SignIn(jwtSecret, refreshSecret string) fiber.Handler {
 			return c.Status(400).JSON(fiber.Map{"error": "email и пароль обязательны"})
 		}
 
+		// Попытка засчитывается до проверки пароля; email или IP заблокированы
+		// после серии неудач — даже не проверяем пароль
+		attempt, wait := startLoginAttempt(loginThrottleKeys(c, req.Email))
+		if wait > 0 {
+			return tooManyLoginAttempts(c, wait)
+		}
+
 		// Ищем юзера по email
 		user, err := Repo.Account.GetUserByEmail(req.Email)
 		if err != nil || user == nil {
-			// Не говорим конкретно "email не найден" — чтобы не палить существование аккаунтов
+			// Не говорим конкретно "email не найден" — чтобы не палить существование аккаунтов.
+			// bcrypt всё равно считаем, чтобы время ответа было как у неверного пароля
+			checkPassword(dummyPasswordHash(), req.Password)
+			attempt.failed()
 			return c.Status(401).JSON(fiber.Map{"error": "неверный email или пароль"})
 		}
 
 		// Сравниваем пароль с хешем
 		if !checkPassword(user.PasswordHash, req.Password) {
+			attempt.failed()
 			return c.Status(401).JSON(fiber.Map{"error": "неверный email или пароль"})
 		}
+		attempt.succeeded()
 
-		// Генерируем токены
-		tokens, err := generateTokens(user.ID, user.Role, jwtSecret, refreshSecret)
+		// Заблокирован админом — говорим об этом только тому, кто знает пароль
+		if user.BlockedAt != nil {
+			return c.Status(403).JSON(fiber.Map{"error": "аккаунт заблокирован"})
+		}
+
+		return completeSignIn(c, user, jwtSecret, refreshSecret)
+	}
+}
+
+// completeSignIn — первый фактор пройден (пароль, Telegram): шаги 4-6 SignIn.
+// Включена 2FA → challenge, токены выдаст только POST /auth/2fa/verify с кодом из приложения;
+// иначе — новая семья refresh-токенов и пара токенов.
+func completeSignIn(c *fiber.Ctx, user *models.User, jwtSecret, refreshSecret string) error {
+	if user.TOTPEnabled {
+		challenge, err := signPurposeToken(jwtSecret, purposeMFAChallenge, jwt.MapClaims{"sub": user.ID}, mfaChallengeTTL)
 		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
+			log.Printf("sign-in: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось начать вход"})
 		}
+		return c.JSON(fiber.Map{"mfaRequired": true, "challenge": challenge})
+	}
 
-		return c.JSON(tokens)
+	tokens, err := issueTokens(c, user, refreshSecret, false)
+	if err != nil {
+		log.Printf("sign-in: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
 	}
+	return respondTokens(c, 200, tokens, nil)
 }
 
-// RefreshToken — обмен refresh-токена на новый access.
+// RefreshToken — обмен refresh-токена на новую пару (ротация).
 // POST /api/auth/refresh
-// Body: { "refresh": "<jwt>" }
-// Ответ 200: { "access": "<новый jwt>", "refresh": "<тот же или новый>" }
-func RefreshToken(jwtSecret, refreshSecret string) fiber.Handler {
+// Body: { "refresh": "<jwt>" }; в режиме cookie тело пустое — токен берётся из куки refresh_token
+// (такой запрос проходит через middleware.CSRF)
+// Ответ 200: { "access": "<новый jwt>", "refresh": "<новый jwt>" } или новые куки + { "csrfToken" }
+//
+// Логика:
+//  1. Парсим и валидируем refresh JWT (подпись, срок), достаём sub, sid, jti
+//  2. Проверяем что юзер ещё существует и не заблокирован (роль для access берём свежую из БД)
+//  3. Подписываем новую пару той же семьи (sid)
+//  4. Repo.RefreshTokens.Rotate — старый токен помечается обменянным, новый сохраняется.
+//     Старый токен уже обменивали → это копия (украли или фронт отправил дважды):
+//     вся семья отозвана, 401 — нужно войти заново
+func RefreshToken(refreshSecret string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req models.RefreshRequest
 
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "невалидный JSON"})
+		// В режиме cookie фронт шлёт пустое тело — токен берём из куки
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "невалидный JSON"})
+			}
+		}
+		if req.Refresh == "" && CookieAuth != nil {
+			if req.Refresh = c.Cookies(auth.RefreshCookie); req.Refresh != "" {
+				// Новую пару — туда же, откуда взяли старую
+				c.Locals("authVia", auth.ViaCookie)
+			}
 		}
 
 		if req.Refresh == "" {
 			return c.Status(400).JSON(fiber.Map{"error": "refresh-токен обязателен"})
 		}
 
-		// Парсим и валидируем refresh-токен
+		// 1. Парсим и валидируем refresh-токен
 		token, err := jwt.Parse(req.Refresh, func(t *jwt.Token) (interface{}, error) {
 			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("неверный метод подписи")
@@ -217,37 +462,141 @@ func RefreshToken(jwtSecret, refreshSecret string) fiber.Handler {
 		}
 
 		userID, _ := claims["sub"].(string)
+		familyID, _ := claims["sid"].(string)
+		jti, _ := claims["jti"].(string)
+		if jti == "" || familyID == "" {
+			// Токен старого формата — его нет в БД, обменять нельзя
+			return c.Status(401).JSON(fiber.Map{"error": "невалидный или просроченный refresh-токен"})
+		}
 
-		// Проверяем что юзер ещё существует
+		// 2. Проверяем что юзер ещё существует
 		user, err := Repo.Account.GetUserByID(userID)
 		if err != nil || user == nil {
 			return c.Status(401).JSON(fiber.Map{"error": "пользователь не найден"})
 		}
+		if user.BlockedAt != nil {
+			// Сессии отзываются при блокировке, но токен мог быть выдан параллельно с ней
+			if err := Repo.RefreshTokens.RevokeFamily(familyID); err != nil {
+				log.Printf("refresh: %v", err)
+			}
+			return c.Status(403).JSON(fiber.Map{"error": "аккаунт заблокирован"})
+		}
 
-		// Генерируем новую пару токенов
-		tokens, err := generateTokens(user.ID, user.Role, jwtSecret, refreshSecret)
+		// 3. Новая пара той же семьи, время входа переносим из старого токена
+		next, err := newRefreshToken(c, user.ID, familyID)
 		if err != nil {
+			log.Printf("refresh: %v", err)
 			return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
 		}
+		if authTime, ok := claims["auth_time"].(float64); ok {
+			next.AuthTime = time.Unix(int64(authTime), 0)
+		}
+		next.MFA, _ = claims["mfa"].(bool)
+		tokens, err := generateTokens(user, next, refreshSecret)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
+		}
+
+		// 4. Ротация
+		if err := Repo.RefreshTokens.Rotate(jti, utils.HashToken(req.Refresh), next); err != nil {
+			switch {
+			case errors.Is(err, repository.ErrRefreshTokenReused):
+				log.Printf("refresh: повторное использование токена семьи %s (user %s), семья отозвана", familyID, user.ID)
+				return c.Status(401).JSON(fiber.Map{"error": "refresh-токен уже использован, войдите заново"})
+			case errors.Is(err, repository.ErrRefreshTokenInvalid):
+				return c.Status(401).JSON(fiber.Map{"error": "невалидный или просроченный refresh-токен"})
+			}
+			log.Printf("refresh: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось обновить токены"})
+		}
 
-		return c.JSON(tokens)
+		return respondTokens(c, 200, tokens, nil)
 	}
 }
 
-// Logout — инвалидация сессии.
-// POST /api/auth/logout (через Protected middleware — userID в c.Locals)
+// Logout — завершение текущего входа.
+// POST /api/auth/logout (через Protected middleware — sessionID в c.Locals)
 // Ответ 200: { "message": "ok" }
+//
+// Отзывает всю семью refresh-токенов, к которой относится access-токен (claim sid):
+// после этого /auth/refresh с любым токеном этого входа вернёт 401, а Protected
+// перестанет пускать и сам access-токен. В режиме cookie заодно стираем куки сессии.
 func Logout(c *fiber.Ctx) error {
-	// TODO: если хранишь refresh-токены в БД (таблица refresh_tokens) —
-	// удали строку по userID := c.Locals("userID").(string)
-	// Пока просто возвращаем ok (клиент удалит токены на своей стороне)
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	if err := Repo.RefreshTokens.RevokeFamily(sessionID); err != nil {
+		log.Printf("logout: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "не удалось завершить сессию"})
+	}
+	clearAuthCookies(c)
 	return c.JSON(fiber.Map{"message": "ok"})
 }
 
+// Reauthenticate — повторный ввод пароля перед чувствительным действием (смена email и т.п.).
+// POST /api/auth/reauth (через Protected middleware — userID и sessionID в c.Locals)
+// Body: { "password": "..." }
+// Ответ 200: { "access": "<jwt>", "refresh": "<jwt>" } — новая пара со свежим auth_time.
+//
+// Логика:
+//  1. Проверяем пароль текущего юзера — с теми же счётчиками неудач, что и SignIn:
+//     иначе украденным access-токеном можно было бы подбирать пароль без ограничений
+//  2. Выдаём токены новой семьи (auth_time = сейчас)
+//  3. Отзываем старую семью — вход продолжается уже по новым токенам
+func Reauthenticate(refreshSecret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+		sessionID, _ := c.Locals("sessionID").(string)
+
+		var req models.ReauthRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "невалидный JSON"})
+		}
+
+		// 1. Пароль
+		user, err := Repo.Account.GetUserByID(userID)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "пользователь не найден"})
+		}
+		attempt, wait := startLoginAttempt(loginThrottleKeys(c, user.Email))
+		if wait > 0 {
+			return tooManyLoginAttempts(c, wait)
+		}
+		if !checkPassword(user.PasswordHash, req.Password) {
+			attempt.failed()
+			// 400, а не 401: токен входа в порядке, неверен только введённый пароль
+			return c.Status(400).JSON(fiber.Map{"error": "неверный пароль"})
+		}
+		attempt.succeeded()
+
+		// 2. Новая семья
+		// второй фактор не спрашиваем — переносим его из текущего входа
+		mfa, _ := c.Locals("mfa").(bool)
+		tokens, err := issueTokens(c, user, refreshSecret, mfa)
+		if err != nil {
+			log.Printf("reauth: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
+		}
+
+		// 3. Старая семья больше не нужна
+		if sessionID != "" {
+			if err := Repo.RefreshTokens.RevokeFamily(sessionID); err != nil {
+				log.Printf("reauth: %v", err)
+			}
+		}
+
+		return respondTokens(c, 200, tokens, nil)
+	}
+}
+
 // IsAdmin — проверяет роль текущего юзера.
 // GET /api/auth/is-admin (через Protected middleware — role в c.Locals)
-// Ответ: { "isAdmin": true/false }
+// Ответ: { "isAdmin": true/false, "role": "...", "permissions": ["products:write", ...] }
+// isAdmin — есть ли вообще доступ в админку; какие разделы показывать — по permissions.
 func IsAdmin(c *fiber.Ctx) error {
 	role, _ := c.Locals("role").(string)
-	return c.JSON(fiber.Map{"isAdmin": role == "admin"})
+	return c.JSON(fiber.Map{
+		"isAdmin":     auth.IsStaff(role),
+		"role":        role,
+		"permissions": auth.Permissions(role),
+	})
 }
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Доставка токенов клиенту: в теле ответа (bearer) или куками (cookie).
+// Режим выбирает AUTH_MODE; что за куки и зачем CSRF — см. auth/cookies.go.
+// Все хендлеры, выдающие пару токенов, отвечают через respondTokens.
+// ═══════════════════════════════════════════════════════════════
+
+// CookieAuth — настройки кук в режиме AUTH_MODE=cookie, инициализируется из main.
+// nil — режим bearer: токены только в теле ответа.
+var CookieAuth *CookieSettings
+
+// CookieSettings — атрибуты кук с токенами.
+type CookieSettings struct {
+	Domain   string // пусто — кука только для хоста API
+	Secure   bool   // false — только для локальной разработки по http
+	SameSite string // Lax | Strict | None
+}
+
+// AuthTransportHeader — в режиме cookie API-клиент (мобилка, скрипт) просит
+// токены в теле ответа заголовком "X-Auth-Transport: bearer".
+const AuthTransportHeader = "X-Auth-Transport"
+
+// respondTokens — ответить парой токенов.
+// extra — остальные поля ответа (коды восстановления и т.п.), может быть nil.
+//
+// Режим bearer (или клиент попросил bearer): { ...extra, "access", "refresh" }.
+// Режим cookie: access/refresh ставятся HttpOnly-куками, в теле их нет;
+// вместо них { ...extra, "csrfToken" } — фронт шлёт его в X-CSRF-Token.
+func respondTokens(c *fiber.Ctx, status int, tokens *models.TokenResponse, extra fiber.Map) error {
+	if !tokensInCookies(c) {
+		if extra == nil {
+			return c.Status(status).JSON(tokens)
+		}
+		body := fiber.Map{"access": tokens.Access, "refresh": tokens.Refresh}
+		for k, v := range extra {
+			body[k] = v
+		}
+		return c.Status(status).JSON(body)
+	}
+
+	csrfToken, err := utils.RandomToken(32)
+	if err != nil {
+		log.Printf("auth cookies: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось сгенерировать токены",
+		})
+	}
+	setAuthCookie(c, auth.AccessCookie, tokens.Access, "/", accessTokenTTL, true)
+	setAuthCookie(c, auth.RefreshCookie, tokens.Refresh, auth.RefreshCookiePath, refreshTokenTTL, true)
+	setAuthCookie(c, auth.CSRFCookie, csrfToken, "/", refreshTokenTTL, false)
+
+	body := fiber.Map{"csrfToken": csrfToken}
+	for k, v := range extra {
+		body[k] = v
+	}
+	return c.Status(status).JSON(body)
+}
+
+// tokensInCookies — отдавать ли токены куками.
+// Запрос уже аутентифицирован (reauth, включение 2FA, refresh по куке) — тем же способом,
+// что и пришёл: Bearer-клиенту куки ни к чему, браузеру — токены в теле.
+// Вход с нуля — куками, если клиент не попросил bearer заголовком X-Auth-Transport.
+func tokensInCookies(c *fiber.Ctx) bool {
+	if CookieAuth == nil {
+		return false
+	}
+	if via, ok := c.Locals("authVia").(string); ok && via != "" {
+		return via == auth.ViaCookie
+	}
+	return !strings.EqualFold(c.Get(AuthTransportHeader), auth.ViaBearer)
+}
+
+// clearAuthCookies — удалить куки сессии (logout). В режиме bearer ничего не делает.
+func clearAuthCookies(c *fiber.Ctx) {
+	if CookieAuth == nil {
+		return
+	}
+	setAuthCookie(c, auth.AccessCookie, "", "/", -1, true)
+	setAuthCookie(c, auth.RefreshCookie, "", auth.RefreshCookiePath, -1, true)
+	setAuthCookie(c, auth.CSRFCookie, "", "/", -1, false)
+}
+
+// setAuthCookie — кука с атрибутами из CookieAuth; ttl < 0 — удалить куку.
+func setAuthCookie(c *fiber.Ctx, name, value, path string, ttl time.Duration, httpOnly bool) {
+	cookie := &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   CookieAuth.Domain,
+		Secure:   CookieAuth.Secure,
+		HTTPOnly: httpOnly,
+		SameSite: CookieAuth.SameSite,
+	}
+	if ttl < 0 {
+		cookie.Expires = time.Unix(0, 0)
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = time.Now().Add(ttl)
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+	c.Cookie(cookie)
+}
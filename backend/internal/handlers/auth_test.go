@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/middleware"
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+)
+
+// memIdempotency — idempotency_keys в памяти.
+type memIdempotency struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+func (m *memIdempotency) Begin(rec *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if existing, ok := m.records[rec.Scope+"|"+rec.Key]; ok {
+		cp := *existing
+		return &cp, nil
+	}
+	cp := *rec
+	cp.Status = models.IdempotencyInProgress
+	m.records[rec.Scope+"|"+rec.Key] = &cp
+	return nil, nil
+}
+
+func (m *memIdempotency) Complete(scope, key string, status int, contentType string, body []byte) error {
+	r := m.records[scope+"|"+key]
+	r.Status, r.ResponseStatus, r.ContentType, r.ResponseBody = models.IdempotencyCompleted, status, contentType, body
+	return nil
+}
+
+func (m *memIdempotency) CompleteResource(scope, key string, status int, resourceID string) error {
+	r := m.records[scope+"|"+key]
+	r.Status, r.ResponseStatus, r.ResourceID = models.IdempotencyCompleted, status, resourceID
+	return nil
+}
+
+func (m *memIdempotency) Delete(scope, key string) error {
+	delete(m.records, scope+"|"+key)
+	return nil
+}
+
+// memSignUpAccount — юзеры в памяти для регистрации.
+type memSignUpAccount struct {
+	repository.AccountRepository
+	users map[string]*models.User
+}
+
+func (m *memSignUpAccount) GetUserByEmail(email string) (*models.User, error) {
+	for _, u := range m.users {
+		if strings.EqualFold(u.Email, email) {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memSignUpAccount) GetUserByID(id string) (*models.User, error) {
+	if u, ok := m.users[id]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memSignUpAccount) CreateUser(user *models.User) error {
+	user.ID = fmt.Sprintf("u%d", len(m.users)+1)
+	cp := *user
+	m.users[user.ID] = &cp
+	return nil
+}
+
+func (m *memSignUpAccount) TouchVerificationSent(id string, interval time.Duration) (bool, error) {
+	return true, nil
+}
+
+func signUpFixture(t *testing.T) (*fiber.App, *memSignUpAccount, *memIdempotency) {
+	t.Helper()
+	accounts := &memSignUpAccount{users: map[string]*models.User{}}
+	idem := &memIdempotency{records: map[string]*models.IdempotencyRecord{}}
+
+	prevRepo, prevKeys := Repo, Keys
+	Repo = &repository.Store{Account: accounts, RefreshTokens: &memRefreshTokens{}, Idempotency: idem}
+	Keys = auth.NewHMACKeySet("secret")
+	t.Cleanup(func() { Repo, Keys = prevRepo, prevKeys })
+
+	app := fiber.New()
+	app.Post("/api/auth/sign-up",
+		middleware.IdempotencyReplay(idem, SignUpReplay("secret", "refresh-secret")),
+		SignUp("secret", "refresh-secret", "http://shop.test"))
+	return app, accounts, idem
+}
+
+func signUp(t *testing.T, app *fiber.App, key, password string) (*http.Response, models.TokenResponse) {
+	t.Helper()
+	body := `{"email":"buyer@example.com","password":"` + password + `","name":"Иван"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/sign-up", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyHeader, key)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	var tokens models.TokenResponse
+	json.NewDecoder(resp.Body).Decode(&tokens)
+	return resp, tokens
+}
+
+// Повтор регистрации с тем же Idempotency-Key — тот же юзер и новая сессия, а не 409;
+// токены первого ответа при этом нигде не сохранены.
+func TestSignUpIdempotencyReplay(t *testing.T) {
+	app, accounts, idem := signUpFixture(t)
+
+	first, firstTokens := signUp(t, app, "key-1", "password-1")
+	if first.StatusCode != fiber.StatusCreated || firstTokens.Access == "" {
+		t.Fatalf("первый запрос: status = %d, tokens = %+v", first.StatusCode, firstTokens)
+	}
+	rec := idem.records["|key-1"]
+	if rec == nil || rec.ResourceID != "u1" || len(rec.ResponseBody) != 0 {
+		t.Fatalf("запись ключа = %+v, want только ID юзера без тела ответа", rec)
+	}
+
+	second, secondTokens := signUp(t, app, "key-1", "password-1")
+	if second.StatusCode != fiber.StatusCreated || second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("повтор: status = %d, replayed = %q", second.StatusCode, second.Header.Get("Idempotent-Replayed"))
+	}
+	if secondTokens.Refresh == "" || secondTokens.Refresh == firstTokens.Refresh {
+		t.Errorf("повтор должен выдать новую сессию, refresh = %q", secondTokens.Refresh)
+	}
+	if len(accounts.users) != 1 {
+		t.Errorf("юзеров = %d, want 1", len(accounts.users))
+	}
+
+	// Без ключа повтор — обычная повторная регистрация
+	if resp, _ := signUp(t, app, "", "password-1"); resp.StatusCode != fiber.StatusConflict {
+		t.Errorf("без ключа: status = %d, want 409", resp.StatusCode)
+	}
+	// Тот же ключ с другим телом — 422
+	if resp, _ := signUp(t, app, "key-1", "password-2"); resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Errorf("другое тело: status = %d, want 422", resp.StatusCode)
+	}
+}
+
+// Пароль сменили после регистрации — старый запрос с тем же ключом не даёт войти.
+func TestSignUpIdempotencyReplayAfterPasswordChange(t *testing.T) {
+	app, accounts, _ := signUpFixture(t)
+
+	if resp, _ := signUp(t, app, "key-1", "password-1"); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("status = %d, want 201", resp.StatusCode)
+	}
+	hash, err := hashPassword("new-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts.users["u1"].PasswordHash = hash
+
+	resp, tokens := signUp(t, app, "key-1", "password-1")
+	if resp.StatusCode != fiber.StatusConflict || tokens.Access != "" {
+		t.Fatalf("повтор после смены пароля: status = %d, tokens = %+v; want 409 без токенов", resp.StatusCode, tokens)
+	}
+}
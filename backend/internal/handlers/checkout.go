@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Расчёт заказа на стороне сервера.
+// Клиенту не доверяем: цены и итог пересчитываются по каталогу,
+// всё что прислал фронт (price, total) игнорируется.
+// ═══════════════════════════════════════════════════════════════
+
+// OrderItemError — ошибка по конкретной позиции корзины.
+// Index — позиция в items из запроса, чтобы фронт подсветил нужную строку.
+type OrderItemError struct {
+	Index     int    `json:"index"`
+	ProductID string `json:"productId"`
+	VariantID string `json:"variantId,omitempty"`
+	Error     string `json:"error"`
+}
+
+// priceOrderItems — собирает позиции заказа по данным из каталога.
+//
+// Как читается:
+//  1. Для каждой позиции проверяем quantity >= 1.
+//  2. Грузим товар через Repo.Products.GetByID.
+//     Не нашёлся (удалён или id битый) → ошибка по позиции.
+//  3. Если у товара есть варианты — variantId обязателен и должен быть вариантом
+//     этого товара. Цена варианта (если задана) перекрывает цену товара,
+//     склад берётся с варианта.
+//  4. Проверяем свободный остаток (stock - reserved), суммируя повторы одной позиции.
+//     Это только предварительная проверка ради понятной ошибки по позиции —
+//     атомарный резерв делает Repo.Orders.Create.
+//  5. Снимаем снапшот title, price, SKU и опций из каталога в OrderItem.
+//  6. Считаем итог как сумму price * quantity.
+//
+// Ошибки по позициям копятся в слайс, чтобы вернуть их все разом.
+// Последний return error — только для ошибок БД (это 500, а не 400).
+func priceOrderItems(reqItems []CreateOrderItem) ([]models.OrderItem, int64, []OrderItemError, error) {
+	var items []models.OrderItem
+	var itemErrors []OrderItemError
+	total := int64(0)
+	requested := map[string]int{} // productID/variantID → сколько уже набрали в этой корзине
+
+	for i, reqItem := range reqItems {
+		if reqItem.Quantity < 1 {
+			itemErrors = append(itemErrors, OrderItemError{
+				Index: i, ProductID: reqItem.ProductID, Error: "количество должно быть не меньше 1",
+			})
+			continue
+		}
+
+		product, err := Repo.Products.GetByID(reqItem.ProductID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+				itemErrors = append(itemErrors, OrderItemError{
+					Index: i, ProductID: reqItem.ProductID, Error: "товар не найден",
+				})
+				continue
+			}
+			return nil, 0, nil, err
+		}
+
+		item := models.OrderItem{
+			ProductID:   product.ID,
+			Title:       product.Title,
+			Price:       product.Price,
+			Quantity:    reqItem.Quantity,
+			WeightGrams: product.WeightGrams,
+		}
+		stockKey, available := product.ID, product.Stock-product.Reserved
+
+		if len(product.Variants) > 0 {
+			variant := findVariant(product, reqItem.VariantID)
+			if variant == nil {
+				msg := "вариант не найден"
+				if reqItem.VariantID == "" {
+					msg = "выберите вариант товара"
+				}
+				itemErrors = append(itemErrors, OrderItemError{
+					Index: i, ProductID: reqItem.ProductID, VariantID: reqItem.VariantID, Error: msg,
+				})
+				continue
+			}
+
+			item.VariantID = variant.ID
+			item.SKU = variant.SKU
+			item.Options = variant.Options
+			if variant.Price != nil {
+				item.Price = *variant.Price
+			}
+			stockKey, available = variant.ID, variant.Stock-variant.Reserved
+		}
+
+		requested[stockKey] += reqItem.Quantity
+		if requested[stockKey] > available {
+			itemErrors = append(itemErrors, OrderItemError{
+				Index: i, ProductID: reqItem.ProductID, VariantID: item.VariantID,
+				Error: fmt.Sprintf("недостаточно товара на складе (доступно: %d)", max(available, 0)),
+			})
+			continue
+		}
+
+		items = append(items, item)
+		total += item.Price * int64(item.Quantity)
+	}
+
+	return items, total, itemErrors, nil
+}
+
+// findVariant — вариант товара по ID (nil, если у товара такого нет).
+// Варианты уже подгружены в product.Variants, лишний запрос не нужен.
+func findVariant(product *models.Product, variantID string) *models.ProductVariant {
+	if variantID == "" {
+		return nil
+	}
+	for i := range product.Variants {
+		if product.Variants[i].ID == variantID {
+			return &product.Variants[i]
+		}
+	}
+	return nil
+}
+
+// applyDiscountCode — проверяет промокод и считает скидку для уже посчитанной корзины.
+//
+// Как читается:
+//  1. Ищем промокод по коду (регистр не важен). Нет такого → reason.
+//  2. Проверяем active и окно действия starts_at..ends_at.
+//  3. Проверяем лимиты: общий (used_count) и на покупателя (по email).
+//     Это предварительная проверка — окончательно лимиты держит Repo.Orders.Create.
+//  4. Считаем подходящую сумму: вся корзина или только позиции из ProductIDs.
+//  5. Минимальная сумма сравнивается с суммой всей корзины.
+//  6. percent → процент от подходящей суммы, fixed → Value, но не больше подходящей суммы.
+//
+// reason != "" — промокод не подходит (это 400, текст уходит покупателю).
+// error — только ошибки БД (это 500).
+func applyDiscountCode(code, email string, items []models.OrderItem, subtotal int64) (*models.Discount, int64, string, error) {
+	discount, err := Repo.Discounts.GetByCode(strings.TrimSpace(code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, "промокод не найден", nil
+		}
+		return nil, 0, "", err
+	}
+
+	now := time.Now()
+	if !discount.Active {
+		return nil, 0, "промокод не активен", nil
+	}
+	if discount.StartsAt != nil && now.Before(*discount.StartsAt) {
+		return nil, 0, "промокод ещё не действует", nil
+	}
+	if discount.EndsAt != nil && now.After(*discount.EndsAt) {
+		return nil, 0, "срок действия промокода истёк", nil
+	}
+
+	if discount.UsageLimit != nil && discount.UsedCount >= *discount.UsageLimit {
+		return nil, 0, "промокод больше недоступен", nil
+	}
+	if discount.PerCustomerLimit != nil && email != "" {
+		used, err := Repo.Discounts.CountRedemptions(discount.ID, email)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if used >= *discount.PerCustomerLimit {
+			return nil, 0, "вы уже использовали этот промокод", nil
+		}
+	}
+
+	eligible := subtotal
+	if len(discount.ProductIDs) > 0 {
+		allowed := make(map[string]bool, len(discount.ProductIDs))
+		for _, id := range discount.ProductIDs {
+			allowed[id] = true
+		}
+		eligible = 0
+		for _, item := range items {
+			if allowed[item.ProductID] {
+				eligible += item.Price * int64(item.Quantity)
+			}
+		}
+		if eligible == 0 {
+			return nil, 0, "промокод не распространяется на товары в корзине", nil
+		}
+	}
+
+	if subtotal < discount.MinOrderTotal {
+		return nil, 0, fmt.Sprintf("минимальная сумма заказа для промокода: %d руб.", discount.MinOrderTotal/100), nil
+	}
+
+	var amount int64
+	switch discount.Kind {
+	case models.DiscountKindPercent:
+		amount = eligible * discount.Value / 100
+	case models.DiscountKindFixed:
+		amount = min(discount.Value, eligible)
+	}
+
+	return discount, amount, "", nil
+}
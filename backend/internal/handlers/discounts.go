@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PreviewDiscountRequest — корзина + промокод для предпросмотра скидки.
+// Email необязателен: без него не проверяется лимит на покупателя
+// (при оформлении заказа он проверится всё равно).
+type PreviewDiscountRequest struct {
+	PromoCode string            `json:"promoCode"`
+	Email     string            `json:"email,omitempty"`
+	Items     []CreateOrderItem `json:"items"`
+}
+
+// PreviewDiscount — посчитать скидку по промокоду, ничего не сохраняя.
+// POST /api/discounts/preview
+// Body: PreviewDiscountRequest
+// Ответ: { "code": "SALE10", "subtotal": 99800, "discountAmount": 9980, "total": 89820 }
+//
+// Считает ровно так же, как CreateOrder (priceOrderItems + applyDiscountCode),
+// поэтому цифры на чекауте совпадут с итоговым заказом.
+// Промокод не подходит → 400 с причиной.
+func PreviewDiscount(c *fiber.Ctx) error {
+	var req PreviewDiscountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if strings.TrimSpace(req.PromoCode) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "промокод обязателен",
+		})
+	}
+	if len(req.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "корзина пуста",
+		})
+	}
+
+	items, subtotal, itemErrors, err := priceOrderItems(req.Items)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при проверке товаров",
+		})
+	}
+	if len(itemErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "некоторые позиции заказа невалидны",
+			"items": itemErrors,
+		})
+	}
+
+	discount, amount, reason, err := applyDiscountCode(req.PromoCode, req.Email, items, subtotal)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при проверке промокода",
+		})
+	}
+	if reason != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": reason,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"code":           discount.Code,
+		"subtotal":       subtotal,
+		"discountAmount": amount,
+		"total":          subtotal - amount,
+	})
+}
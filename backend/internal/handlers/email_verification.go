@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"socialsh/backend/internal/models"
+)
+
+// Подтверждение email: назначение токена, срок жизни ссылки и как часто можно слать письмо.
+const (
+	purposeVerifyEmail         = "verify_email"
+	emailVerificationTTL       = 48 * time.Hour
+	emailVerificationResendGap = time.Minute
+)
+
+// ConfirmEmail — подтвердить email по ссылке из письма.
+// POST /api/auth/email/confirm
+// Body: { "token": "..." }
+// Ответ 200: { "message": "ok" }
+//
+// Логика:
+//  1. Проверяем подпись и срок токена (claims: sub — userID, email — адрес из письма)
+//  2. Repo.Account.MarkEmailVerified — только если email юзера всё ещё тот же
+//  3. Привязываем гостевые заказы на этот email — теперь точно известно,
+//     что адрес принадлежит юзеру
+//
+// Повторный переход по той же ссылке — тоже ok, ничего не ломает.
+func ConfirmEmail(jwtSecret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.ConfirmEmailRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "невалидный JSON"})
+		}
+		if req.Token == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "токен обязателен"})
+		}
+
+		// 1. Токен
+		claims, err := parsePurposeToken(jwtSecret, purposeVerifyEmail, req.Token)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "ссылка подтверждения недействительна или устарела",
+			})
+		}
+		userID, _ := claims["sub"].(string)
+		email, _ := claims["email"].(string)
+
+		// 2. Подтверждаем
+		ok, err := Repo.Account.MarkEmailVerified(userID, email)
+		if err != nil {
+			log.Printf("email confirm: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "не удалось подтвердить email"})
+		}
+		if !ok {
+			// Юзер сменил email после отправки письма (или удалён)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "ссылка подтверждения недействительна или устарела",
+			})
+		}
+
+		// 3. Гостевые заказы. Ошибка не должна ломать подтверждение.
+		if _, err := Repo.Orders.AttachGuestOrders(userID, email); err != nil {
+			log.Printf("email confirm: не удалось привязать гостевые заказы: %v", err)
+		}
+
+		return c.JSON(fiber.Map{"message": "ok"})
+	}
+}
+
+// ResendVerification — отправить письмо подтверждения ещё раз.
+// POST /api/auth/email/resend (через Protected middleware — userID в c.Locals)
+// Ответ 200: { "message": "ok" }
+// 400 — email уже подтверждён или его нет; 429 — письмо уже отправляли меньше минуты назад.
+func ResendVerification(jwtSecret, frontendURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+
+		user, err := Repo.Account.GetUserByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "пользователь не найден"})
+		}
+		if user.Email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "у аккаунта нет email — укажите его в профиле"})
+		}
+		if user.EmailVerified {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email уже подтверждён"})
+		}
+
+		if err := sendVerificationEmail(user, jwtSecret, frontendURL); err != nil {
+			if errors.Is(err, errVerificationThrottled) {
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+					"error": "письмо уже отправлено, повторить можно через минуту",
+				})
+			}
+			log.Printf("email resend: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "не удалось отправить письмо"})
+		}
+
+		return c.JSON(fiber.Map{"message": "ok"})
+	}
+}
+
+// errVerificationThrottled — письмо подтверждения уже отправляли недавно.
+var errVerificationThrottled = errors.New("письмо подтверждения уже отправлено")
+
+// sendVerificationEmail — подписать токен и отправить письмо со ссылкой
+// <frontendURL>/verify-email?token=...
+// Не чаще раза в emailVerificationResendGap (отметка в users, см. TouchVerificationSent).
+func sendVerificationEmail(user *models.User, jwtSecret, frontendURL string) error {
+	allowed, err := Repo.Account.TouchVerificationSent(user.ID, emailVerificationResendGap)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errVerificationThrottled
+	}
+
+	token, err := signPurposeToken(jwtSecret, purposeVerifyEmail, jwt.MapClaims{
+		"sub":   user.ID,
+		"email": user.Email,
+	}, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(frontendURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+	body := "Здравствуйте!\n\n" +
+		"Подтвердите email для аккаунта в магазине SOCIAL SH — перейдите по ссылке (действует 48 часов):\n\n" +
+		link + "\n\n" +
+		"Если вы не регистрировались — просто проигнорируйте это письмо."
+	sendMailAsync(user.Email, "Подтверждение email", body)
+	return nil
+}
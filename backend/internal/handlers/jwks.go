@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+)
+
+// Keys — ключи подписи access-токенов, инициализируется из main.
+// Подписывает generateTokens, проверяют middleware.Protected/OptionalAuth.
+var Keys *auth.KeySet
+
+// JWKS — открытые ключи, которыми можно проверить наши access-токены.
+// GET /.well-known/jwks.json
+// Ответ 200: { "keys": [ { "kty": "OKP", "kid": "...", "alg": "EdDSA", ... } ] }
+//
+// В режиме HS256 список пустой: общий секрет не публикуется.
+// Кешировать можно недолго — при ротации новый ключ появляется тут раньше,
+// чем им начинают подписывать.
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{"keys": Keys.JWKS()})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+
+	"socialsh/backend/internal/models"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Защита входа от перебора паролей.
+// Неудачи считаем по двум ключам: email (перебор пароля к одному аккаунту)
+// и IP (перебор по многим аккаунтам с одного адреса). Счётчики и блокировки
+// лежат в Postgres (login_throttles) — действуют сразу на всех инстансах.
+// Попытка засчитывается ДО проверки пароля, а успешная потом вычитается:
+// иначе пачка параллельных запросов проскочила бы проверку блокировки разом.
+// ═══════════════════════════════════════════════════════════════
+
+// loginThrottlePolicy — правила для одного вида ключа.
+//
+// Как читается:
+//
+//	Первые free неудач — без последствий.
+//	Дальше каждая неудача закрывает ключ на 1с, 2с, 4с, ... (не больше maxDelay) —
+//	прогрессивная задержка, перебор становится бесполезно медленным.
+//	На lockAfter неудаче — блокировка на lockFor.
+//	Счётчик живёт window с последней неудачи, потом начинается заново.
+//	failClosed — БД недоступна → отказ (для входа наоборот: лучше пустить юзера).
+type loginThrottlePolicy struct {
+	free       int
+	maxDelay   time.Duration
+	lockAfter  int
+	lockFor    time.Duration
+	window     time.Duration
+	failClosed bool
+}
+
+var loginThrottlePolicies = map[string]loginThrottlePolicy{
+	// один аккаунт: 3 попытки бесплатно, на 10-й — 15 минут
+	models.LoginThrottleEmail: {free: 3, maxDelay: time.Minute, lockAfter: 10, lockFor: 15 * time.Minute, window: time.Hour},
+	// один IP (за NAT могут сидеть многие — порог выше)
+	models.LoginThrottleIP: {free: 10, maxDelay: time.Minute, lockAfter: 50, lockFor: 30 * time.Minute, window: time.Hour},
+	// запросы ссылок для входа с одного IP (см. magic_link.go): считается каждый запрос, не только неудачи
+	// без счётчика лимита нет вовсе — тогда писем не шлём
+	models.LoginThrottleMagicLinkIP: {free: 5, maxDelay: time.Minute, lockAfter: 20, lockFor: time.Hour, window: time.Hour, failClosed: true},
+}
+
+// delay — на сколько закрыть ключ после failures неудач. 0 — не закрывать.
+func (p loginThrottlePolicy) delay(failures int) time.Duration {
+	if failures >= p.lockAfter {
+		return p.lockFor
+	}
+	if failures <= p.free {
+		return 0
+	}
+	d := time.Duration(math.Pow(2, float64(failures-p.free-1))) * time.Second
+	if d > p.maxDelay {
+		d = p.maxDelay
+	}
+	return d
+}
+
+// loginThrottleKeys — ключи текущей попытки входа: email в нижнем регистре и IP клиента.
+// У аккаунтов из Telegram email нет — тогда только IP (иначе все они делили бы один счётчик).
+func loginThrottleKeys(c *fiber.Ctx, email string) map[string]string {
+	keys := map[string]string{models.LoginThrottleIP: c.IP()}
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		keys[models.LoginThrottleEmail] = email
+	}
+	return keys
+}
+
+// loginAttempt — попытка, уже засчитанная в счётчики (см. startLoginAttempt).
+// attempts — её номер по каждому ключу.
+// err — счётчик с failClosed недоступен: хендлер отвечает 503.
+type loginAttempt struct {
+	keys     map[string]string
+	attempts map[string]int
+	err      error
+}
+
+// startLoginAttempt — засчитать попытку по всем ключам, пока пароль не проверен.
+// wait > 0 — хендлер отвечает 429 и пароль не проверяет.
+//
+// Логика:
+//  1. CountAttempt: атомарно +1 и номер этой попытки.
+//     Ключ заблокирован — счётчик не растёт, в ответ остаток блокировки.
+//  2. Номер больше lockAfter — отказ, даже если блокировку ещё не успели поставить:
+//     параллельные запросы получают разные номера, так что сотня одновременных
+//     запросов проверит пароль не больше lockAfter раз.
+//     Первая попытка после истёкшей блокировки получает ровно lockAfter — пароль
+//     проверяется; неудача снова закрывает ключ на lockFor (см. delay), успех снимает.
+//
+// Ошибку БД только логируем: лучше пустить юзера, чем уронить вход целиком.
+// Кроме ключей с failClosed — их ошибка попадает в a.err.
+func startLoginAttempt(keys map[string]string) (*loginAttempt, time.Duration) {
+	a := &loginAttempt{keys: keys, attempts: make(map[string]int, len(keys))}
+	var wait time.Duration
+	for kind, subject := range keys {
+		policy := loginThrottlePolicies[kind]
+		n, locked, err := Repo.LoginThrottle.CountAttempt(kind, subject, policy.window, policy.lockAfter)
+		if err != nil {
+			log.Printf("login throttle: %v", err)
+			if policy.failClosed {
+				a.err = err
+			}
+			continue
+		}
+		if locked == 0 {
+			a.attempts[kind] = n
+			if n > policy.lockAfter {
+				locked = policy.lockFor
+				if err := Repo.LoginThrottle.Lock(kind, subject, locked); err != nil {
+					log.Printf("login throttle: %v", err)
+				}
+			}
+		}
+		if locked > wait {
+			wait = locked
+		}
+	}
+	return a, wait
+}
+
+// failed — неудача: попытка остаётся в счётчиках, если пора — задержка/блокировка.
+func (a *loginAttempt) failed() {
+	for kind, n := range a.attempts {
+		if d := loginThrottlePolicies[kind].delay(n); d > 0 {
+			if err := Repo.LoginThrottle.Lock(kind, a.keys[kind], d); err != nil {
+				log.Printf("login throttle: %v", err)
+			}
+		}
+	}
+}
+
+// succeeded — успех обнуляет счётчик аккаунта (только ключ email).
+// Из остальных (IP) лишь вычитаем эту попытку: обнули мы счётчик IP,
+// атакующий сбрасывал бы его входом в свой аккаунт.
+func (a *loginAttempt) succeeded() {
+	for kind := range a.attempts {
+		var err error
+		if kind == models.LoginThrottleEmail {
+			err = Repo.LoginThrottle.Reset(kind, a.keys[kind])
+		} else {
+			err = Repo.LoginThrottle.Refund(kind, a.keys[kind])
+		}
+		if err != nil {
+			log.Printf("login throttle: %v", err)
+		}
+	}
+}
+
+// tooManyLoginAttempts — ответ 429. Текст один и тот же, есть аккаунт или нет:
+// счётчики ведутся и для несуществующих email.
+func tooManyLoginAttempts(c *fiber.Ctx, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":      "слишком много попыток входа, попробуйте позже",
+		"retryAfter": seconds,
+	})
+}
+
+// dummyPasswordHash — хеш-заглушка для email без аккаунта: bcrypt считаем всегда,
+// чтобы по времени ответа нельзя было понять, существует ли аккаунт.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), 10)
+	if err != nil {
+		log.Printf("login throttle: dummy hash: %v", err)
+	}
+	return string(hash)
+})
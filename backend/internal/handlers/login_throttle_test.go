@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+)
+
+// memThrottle — login_throttles в памяти с подменяемыми часами (now).
+// CountAttempt повторяет CASE из LoginThrottleSQLRepo.CountAttempt.
+type memThrottle struct {
+	repository.LoginThrottleRepository
+	now  time.Time
+	rows map[string]*models.LoginThrottle
+}
+
+func newMemThrottle() *memThrottle {
+	return &memThrottle{now: time.Unix(1760000000, 0), rows: map[string]*models.LoginThrottle{}}
+}
+
+func (m *memThrottle) locked(r *models.LoginThrottle) bool {
+	return r.LockedUntil != nil && r.LockedUntil.After(m.now)
+}
+
+func (m *memThrottle) CountAttempt(kind, subject string, window time.Duration, lockAfter int) (int, time.Duration, error) {
+	r, ok := m.rows[kind+"|"+subject]
+	switch {
+	case !ok:
+		r = &models.LoginThrottle{Kind: kind, Subject: subject, Failures: 1, LastFailureAt: m.now}
+		m.rows[kind+"|"+subject] = r
+	case m.locked(r):
+		return r.Failures, r.LockedUntil.Sub(m.now), nil
+	case r.LastFailureAt.Before(m.now.Add(-window)):
+		r.Failures = 1
+	case r.LockedUntil != nil && r.Failures >= lockAfter:
+		r.Failures = lockAfter
+	default:
+		r.Failures++
+	}
+	r.LastFailureAt, r.LockedUntil = m.now, nil
+	return r.Failures, 0, nil
+}
+
+func (m *memThrottle) Refund(kind, subject string) error {
+	if r, ok := m.rows[kind+"|"+subject]; ok && r.Failures > 0 {
+		r.Failures--
+	}
+	return nil
+}
+
+func (m *memThrottle) Lock(kind, subject string, d time.Duration) error {
+	if r, ok := m.rows[kind+"|"+subject]; ok {
+		until := m.now.Add(d)
+		if !m.locked(r) || until.After(*r.LockedUntil) {
+			r.LockedUntil = &until
+		}
+	}
+	return nil
+}
+
+func (m *memThrottle) Reset(kind, subject string) error {
+	delete(m.rows, kind+"|"+subject)
+	return nil
+}
+
+// memSignInAccount — один юзер с паролем "right-password".
+type memSignInAccount struct {
+	repository.AccountRepository
+	user *models.User
+}
+
+func (m *memSignInAccount) GetUserByEmail(email string) (*models.User, error) {
+	if strings.EqualFold(email, m.user.Email) {
+		cp := *m.user
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+type memRefreshTokens struct {
+	repository.RefreshTokenRepository
+	created int
+}
+
+func (m *memRefreshTokens) Create(rt *models.RefreshToken) error {
+	m.created++
+	return nil
+}
+
+func signInFixture(t *testing.T) (*fiber.App, *memThrottle) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("right-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	throttle := newMemThrottle()
+
+	prevRepo, prevKeys := Repo, Keys
+	Repo = &repository.Store{
+		Account:       &memSignInAccount{user: &models.User{ID: "u1", Email: "buyer@example.com", PasswordHash: string(hash), Role: "user"}},
+		RefreshTokens: &memRefreshTokens{},
+		LoginThrottle: throttle,
+	}
+	Keys = auth.NewHMACKeySet("secret")
+	t.Cleanup(func() { Repo, Keys = prevRepo, prevKeys })
+
+	app := fiber.New()
+	app.Post("/api/auth/sign-in", SignIn("secret", "refresh-secret"))
+	return app, throttle
+}
+
+func signIn(t *testing.T, app *fiber.App, password string) int {
+	t.Helper()
+	body := `{"email":"buyer@example.com","password":"` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/sign-in", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	return resp.StatusCode
+}
+
+// Блокировка истекла — следующая попытка проверяет пароль, а не продлевает блокировку:
+// иначе любой держал бы чужой аккаунт закрытым, просто отправляя по запросу раз в 15 минут.
+func TestSignInAfterLockExpires(t *testing.T) {
+	app, throttle := signInFixture(t)
+	policy := loginThrottlePolicies[models.LoginThrottleEmail]
+
+	// Подбор: на каждую неудачу ждём ровно столько, сколько велит задержка
+	for i := 1; i <= policy.lockAfter; i++ {
+		if code := signIn(t, app, "wrong"); code != fiber.StatusUnauthorized {
+			t.Fatalf("попытка %d: status = %d, want 401", i, code)
+		}
+		if i < policy.lockAfter {
+			throttle.now = throttle.now.Add(policy.delay(i))
+		}
+	}
+	// Блокировка: даже верный пароль не проверяется
+	if code := signIn(t, app, "right-password"); code != fiber.StatusTooManyRequests {
+		t.Fatalf("под блокировкой: status = %d, want 429", code)
+	}
+
+	// Блокировка истекла, снова неверный пароль — ещё одна проверка, потом снова lockFor
+	throttle.now = throttle.now.Add(policy.lockFor)
+	if code := signIn(t, app, "wrong"); code != fiber.StatusUnauthorized {
+		t.Fatalf("после блокировки, неверный: status = %d, want 401", code)
+	}
+	if code := signIn(t, app, "right-password"); code != fiber.StatusTooManyRequests {
+		t.Fatalf("после новой неудачи: status = %d, want 429", code)
+	}
+
+	// Истекла и эта — верный пароль пускает, счётчик email обнулён
+	throttle.now = throttle.now.Add(policy.lockFor)
+	if code := signIn(t, app, "right-password"); code != fiber.StatusOK {
+		t.Fatalf("после блокировки, верный: status = %d, want 200", code)
+	}
+	if _, ok := throttle.rows[models.LoginThrottleEmail+"|buyer@example.com"]; ok {
+		t.Error("счётчик email не сброшен после успешного входа")
+	}
+}
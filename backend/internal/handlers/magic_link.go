@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Вход по ссылке из письма (без пароля) — рядом с SignIn.
+//
+// Как читается:
+//  1. Браузер генерирует случайный nonce, сохраняет его у себя и просит ссылку:
+//     POST /api/auth/magic-link { email, nonce }.
+//  2. Мы подписываем токен на 15 минут (purpose magic-link, claims sub, jti, email),
+//     в magic_links кладём jti и sha256(nonce), токен уходит письмом.
+//  3. По ссылке фронт шлёт токен и nonce из того же браузера:
+//     POST /api/auth/magic-link/verify { token, nonce }.
+//     Ссылка одноразовая, а без nonce не работает — перехваченное письмо
+//     (или ссылка, открытая на другом устройстве) войти не даст.
+//  4. Дальше как после пароля: 2FA → challenge, иначе пара токенов.
+// ═══════════════════════════════════════════════════════════════
+
+// Ссылка для входа: назначение токена, срок жизни и сколько писем в час на аккаунт.
+const (
+	purposeMagicLink    = "magic-link"
+	magicLinkTTL        = 15 * time.Minute
+	magicLinkHourlyMax  = 3
+	magicLinkNonceMin   = 32
+	magicLinkNonceMax   = 128
+	magicLinkInvalidMsg = "ссылка для входа недействительна или устарела — запросите новую в этом браузере"
+)
+
+// RequestMagicLink — запросить письмо со ссылкой для входа.
+// POST /api/auth/magic-link
+// Body: { "email": "...", "nonce": "<случайная строка 32-128 символов, хранится в браузере>" }
+// Ответ 200: { "message": "ok" } — ВСЕГДА, есть такой аккаунт или нет.
+// Ответ 429: { "error", "retryAfter" } — слишком много запросов с этого IP.
+// Ответ 503: { "error" } — счётчик запросов недоступен (без лимита писем не шлём).
+//
+// Логика:
+//  1. IP запрашивал ссылки слишком часто → 429 (счётчик общий для всех email, наличие аккаунта не палит)
+//  2. Нет аккаунта, он заблокирован или уже magicLinkHourlyMax писем за час — молча ok
+//  3. Иначе — подписанный токен, запись в magic_links и письмо
+//
+// Шаги 2-3 идут в фоне, после ответа — как в ForgotPassword: иначе по времени
+// ответа было бы видно, есть ли аккаунт с таким email.
+func RequestMagicLink(jwtSecret, frontendURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.MagicLinkRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "невалидный JSON"})
+		}
+		req.Email = strings.TrimSpace(req.Email)
+		if req.Email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email обязателен"})
+		}
+		if len(req.Nonce) < magicLinkNonceMin || len(req.Nonce) > magicLinkNonceMax {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "nonce должен быть от 32 до 128 символов"})
+		}
+
+		// 1. Лимит по IP
+		// Считается каждый запрос, поэтому попытка сразу "неудачная"
+		attempt, wait := startLoginAttempt(map[string]string{models.LoginThrottleMagicLinkIP: c.IP()})
+		if attempt.err != nil {
+			// Без лимита не шлём: иначе с одного IP можно засыпать письмами кого угодно
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "сервис временно недоступен, попробуйте позже"})
+		}
+		if wait > 0 {
+			return tooManyLoginAttempts(c, wait)
+		}
+		attempt.failed()
+
+		// 2-3. Строки из запроса копируем: fiber переиспользует его буферы после ответа
+		go startMagicLink(strings.Clone(req.Email), strings.Clone(req.Nonce), strings.Clone(c.IP()), jwtSecret, frontendURL)
+		return c.JSON(fiber.Map{"message": "ok"})
+	}
+}
+
+// startMagicLink — шаги 2-3 RequestMagicLink, запускается в горутине
+// (поэтому без *fiber.Ctx — ip передаётся строкой). Ошибки только в лог:
+// клиент уже получил ответ.
+func startMagicLink(email, nonce, ip, jwtSecret, frontendURL string) {
+	user, err := Repo.Account.GetUserByEmail(email)
+	if err != nil || user == nil || user.BlockedAt != nil {
+		return
+	}
+
+	sent, err := Repo.MagicLinks.CountRecent(user.ID, time.Hour)
+	if err != nil {
+		log.Printf("magic link: %v", err)
+		return
+	}
+	if sent >= magicLinkHourlyMax {
+		return
+	}
+
+	jti, err := utils.NewUUID()
+	if err != nil {
+		log.Printf("magic link: %v", err)
+		return
+	}
+	token, err := signPurposeToken(jwtSecret, purposeMagicLink, jwt.MapClaims{
+		"sub":   user.ID,
+		"jti":   jti,
+		"email": user.Email,
+	}, magicLinkTTL)
+	if err != nil {
+		log.Printf("magic link: %v", err)
+		return
+	}
+
+	link := &models.MagicLink{
+		ID:        jti,
+		UserID:    user.ID,
+		NonceHash: utils.HashToken(nonce),
+		IP:        ip,
+	}
+	if err := Repo.MagicLinks.Create(link, magicLinkTTL); err != nil {
+		log.Printf("magic link: %v", err)
+		return
+	}
+
+	href := strings.TrimRight(frontendURL, "/") + "/magic-link?token=" + url.QueryEscape(token)
+	body := "Здравствуйте!\n\n" +
+		"Чтобы войти в магазин SOCIAL SH без пароля, перейдите по ссылке (действует 15 минут).\n" +
+		"Откройте её в том же браузере, где запрашивали вход:\n\n" +
+		href + "\n\n" +
+		"Если вы не запрашивали вход — просто проигнорируйте это письмо."
+	sendMailAsync(user.Email, "Вход в SOCIAL SH", body)
+}
+
+// VerifyMagicLink — обменять токен из письма на пару токенов.
+// POST /api/auth/magic-link/verify
+// Body: { "token": "<из ссылки>", "nonce": "<тот же, что при запросе>" }
+// Ответ 200: { "access", "refresh" } или { "mfaRequired": true, "challenge" } — как у SignIn
+// 400 — ссылка недействительна, устарела, уже использована или открыта в другом браузере;
+// 403 — аккаунт заблокирован.
+//
+// Логика:
+//  1. Проверяем подпись и срок токена
+//  2. Гасим ссылку по jti + sha256(nonce) — одноразовая и только для своего браузера
+//  3. Email аккаунта с тех пор не менялся (иначе ссылка от старого адреса)
+//  4. Переход по ссылке доказывает владение email — отмечаем его подтверждённым
+//     и привязываем гостевые заказы (как ConfirmEmail)
+//  5. Дальше как в SignIn
+func VerifyMagicLink(jwtSecret, refreshSecret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.MagicLinkVerifyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "невалидный JSON"})
+		}
+		if req.Token == "" || req.Nonce == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "token и nonce обязательны"})
+		}
+
+		// 1. Подпись и срок
+		claims, err := parsePurposeToken(jwtSecret, purposeMagicLink, req.Token)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": magicLinkInvalidMsg})
+		}
+		userID, _ := claims["sub"].(string)
+		jti, _ := claims["jti"].(string)
+		email, _ := claims["email"].(string)
+
+		// 2. Одноразовость и браузер
+		linkUserID, err := Repo.MagicLinks.Consume(jti, utils.HashToken(req.Nonce))
+		if err != nil {
+			if errors.Is(err, repository.ErrMagicLinkInvalid) || utils.IsInvalidInputError(err) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": magicLinkInvalidMsg})
+			}
+			log.Printf("magic link verify: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "не удалось войти"})
+		}
+		if linkUserID != userID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": magicLinkInvalidMsg})
+		}
+
+		// 3. Юзер и его email
+		user, err := Repo.Account.GetUserByID(userID)
+		if err != nil || user.Email == "" || !strings.EqualFold(user.Email, email) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": magicLinkInvalidMsg})
+		}
+		if user.BlockedAt != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "аккаунт заблокирован"})
+		}
+
+		// 4. Email подтверждён — как в ConfirmEmail, заодно привязываем гостевые заказы
+		if !user.EmailVerified {
+			if _, err := Repo.Account.MarkEmailVerified(user.ID, user.Email); err != nil {
+				log.Printf("magic link verify: %v", err)
+			} else if _, err := Repo.Orders.AttachGuestOrders(user.ID, user.Email); err != nil {
+				log.Printf("magic link verify: не удалось привязать гостевые заказы: %v", err)
+			}
+		}
+
+		// 5. Токены (или второй фактор)
+		return completeSignIn(c, user, jwtSecret, refreshSecret)
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/repository"
+)
+
+// brokenThrottle — счётчики попыток, у которых БД недоступна.
+type brokenThrottle struct {
+	repository.LoginThrottleRepository
+	calls int
+}
+
+func (b *brokenThrottle) CountAttempt(kind, subject string, window time.Duration, lockAfter int) (int, time.Duration, error) {
+	b.calls++
+	return 0, 0, errors.New("pq: connection refused")
+}
+
+// Без счётчика лимита по IP нет — ссылку не шлём, отвечаем 503.
+// Account не задан: дойди хендлер до поиска аккаунта, тест упал бы на nil.
+func TestRequestMagicLinkThrottleUnavailable(t *testing.T) {
+	throttle := &brokenThrottle{}
+	prevRepo := Repo
+	Repo = &repository.Store{LoginThrottle: throttle}
+	t.Cleanup(func() { Repo = prevRepo })
+
+	app := fiber.New()
+	app.Post("/api/auth/magic-link", RequestMagicLink("secret", "http://shop.test"))
+
+	body := `{"email":"buyer@example.com","nonce":"` + strings.Repeat("n", magicLinkNonceMin) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/magic-link", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+	if throttle.calls != 1 {
+		t.Errorf("CountAttempt вызван %d раз, want 1", throttle.calls)
+	}
+}
@@ -1,49 +1,66 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"sort"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/notify"
+	"socialsh/backend/internal/repository"
 )
 
-// CreateOrderRequest — структура запроса на создание заказа
+// Notify — очередь уведомлений магазину, инициализируется из main.
+// nil — уведомления выключены.
+var Notify *notify.Outbox
+
+// CreateOrderRequest — структура запроса на создание заказа.
+// Price и Total остались для совместимости со старым фронтом,
+// но сервер их игнорирует — цены берутся из каталога (см. priceOrderItems).
 type CreateOrderRequest struct {
-	Items []struct {
-		ProductID string `json:"productId"`
-		Quantity  int    `json:"quantity"`
-		Price     int64  `json:"price"`
-	} `json:"items"`
-	Customer struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Phone    string `json:"phone,omitempty"`
-		Telegram string `json:"telegram,omitempty"`
-		Address  string `json:"address,omitempty"`
-	} `json:"customer"`
-	Comment string `json:"comment,omitempty"`
-	Total   int64  `json:"total"`
+	Items     []CreateOrderItem    `json:"items"`
+	Customer  models.OrderCustomer `json:"customer"`
+	Comment   string               `json:"comment,omitempty"`
+	PromoCode string               `json:"promoCode,omitempty"` // необязательный промокод
+	Shipping  *ShippingChoice      `json:"shipping,omitempty"`  // обязателен, если есть включённые способы доставки
+	Total     int64                `json:"total"`               // игнорируется
+}
+
+// CreateOrderItem — позиция корзины из запроса.
+type CreateOrderItem struct {
+	ProductID string `json:"productId"`
+	VariantID string `json:"variantId,omitempty"` // обязателен, если у товара есть варианты
+	Quantity  int    `json:"quantity"`
+	Price     int64  `json:"price"` // игнорируется
 }
 
 // CreateOrder — создание заказа с отправкой уведомления.
-// POST /api/orders
+// POST /api/orders (через OptionalAuth — userID в c.Locals, если юзер залогинен)
+// Гости тоже могут оформить заказ: он сохранится без user_id, с контактами из Customer,
+// и привяжется к аккаунту, когда покупатель зарегистрируется на этот email.
 // Body: CreateOrderRequest
 // Ответ: { "message": "Заказ создан", "orderId": "..." }
 //
 // Логика:
 //  1. Парсим body → CreateOrderRequest
 //  2. Валидируем обязательные поля (name, email, items)
-//  3. Формируем сообщение для отправки
-//  4. Отправляем в Telegram (если настроен) или на email
-//  5. Возвращаем успешный ответ
+//  3. Пересчитываем цены и итог по каталогу, проверяем остатки → priceOrderItems
+//     Если передан promoCode — проверяем его и считаем скидку → applyDiscountCode
+//     Считаем доставку по выбранному способу и зоне → resolveShipping
+//  4. Сохраняем заказ + позиции + резерв склада + применение промокода
+//     + уведомление в outbox (Notify) одной транзакцией → Repo.Orders.Create.
+//     Сообщение формируется уже по сохранённому заказу (нужен его ID),
+//     отправкой в Telegram/email занимается фоновый воркер
+//  5. Возвращаем успешный ответ с ID заказа
 func CreateOrder(c *fiber.Ctx) error {
 	var req CreateOrderRequest
 
+	// Пустой userID = гостевой заказ
+	userID, _ := c.Locals("userID").(string)
+
 	// 1. Парсим JSON-body
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -67,46 +84,134 @@ func CreateOrder(c *fiber.Ctx) error {
 			"error": "корзина пуста",
 		})
 	}
-	if req.Total <= 0 {
+
+	// 3. Цены и итог — только из каталога
+	items, subtotal, itemErrors, err := priceOrderItems(req.Items)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при проверке товаров",
+		})
+	}
+	if len(itemErrors) > 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "неверная сумма заказа",
+			"error": "некоторые позиции заказа невалидны",
+			"items": itemErrors,
 		})
 	}
 
-	// 3. Формируем сообщение
-	message := formatOrderMessage(req)
+	// Промокод: пустой — без скидки
+	var discount *models.Discount
+	discountAmount := int64(0)
+	if req.PromoCode != "" {
+		var reason string
+		discount, discountAmount, reason, err = applyDiscountCode(req.PromoCode, req.Customer.Email, items, subtotal)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "ошибка при проверке промокода",
+			})
+		}
+		if reason != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": reason,
+			})
+		}
+	}
+
+	// Доставка
+	shippingMethod, shippingCost, reason, err := resolveShipping(req.Shipping, items, subtotal)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при расчёте доставки",
+		})
+	}
+	if reason != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": reason,
+		})
+	}
+
+	// 4. Сохраняем заказ
+	order := &models.Order{
+		UserID:         userID,
+		Status:         models.OrderStatusPending,
+		Subtotal:       subtotal,
+		DiscountAmount: discountAmount,
+		ShippingCost:   shippingCost,
+		Total:          subtotal - discountAmount + shippingCost,
+		Customer:       req.Customer,
+		Comment:        req.Comment,
+		Items:          items,
+	}
+	if discount != nil {
+		order.DiscountID = discount.ID
+		order.DiscountCode = discount.Code
+	}
+	if shippingMethod != nil {
+		order.ShippingMethodID = shippingMethod.ID
+		order.ShippingMethod = shippingMethod.Title
+		order.ShippingZone = req.Shipping.Zone
+	}
 
-	// 4. Отправляем уведомление
-	// Сначала пробуем Telegram, потом email
-	sent := false
-	if telegramBotToken := os.Getenv("TELEGRAM_BOT_TOKEN"); telegramBotToken != "" {
-		if chatID := os.Getenv("TELEGRAM_CHAT_ID"); chatID != "" {
-			if err := sendTelegramMessage(telegramBotToken, chatID, message); err == nil {
-				sent = true
-			}
+	// Уведомление пишется в outbox той же транзакцией, что и заказ
+	var outbox func(*models.Order) []models.Notification
+	if Notify != nil {
+		outbox = func(saved *models.Order) []models.Notification {
+			return Notify.Notifications(notify.Message{
+				Subject: "Новый заказ " + saved.ID,
+				Text:    formatOrderMessage(saved, req),
+			})
 		}
 	}
 
-	// Если Telegram не сработал, пробуем email
-	if !sent {
-		if emailTo := os.Getenv("ORDER_EMAIL"); emailTo != "" {
-			// Здесь можно добавить отправку через SMTP или сервис типа SendGrid
-			// Пока просто логируем
-			fmt.Printf("Order email (not implemented): %s\n", emailTo)
+	if err := Repo.Orders.Create(order, outbox); err != nil {
+		// Лимит промокода выбрали параллельные заказы
+		if errors.Is(err, repository.ErrDiscountLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "промокод больше недоступен",
+			})
+		}
+		// Остаток успели забрать между проверкой и резервом
+		var stockErr *repository.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "недостаточно товара на складе",
+				"items": []OrderItemError{{
+					Index:     orderItemIndex(&req, stockErr.ProductID, stockErr.VariantID),
+					ProductID: stockErr.ProductID,
+					VariantID: stockErr.VariantID,
+					Error:     "недостаточно товара на складе",
+				}},
+			})
 		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось сохранить заказ",
+		})
 	}
 
 	// 5. Возвращаем успешный ответ
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Заказ успешно оформлен. Мы свяжемся с вами в ближайшее время.",
+		"orderId": order.ID,
 	})
 }
 
-// formatOrderMessage форматирует сообщение о заказе
-func formatOrderMessage(req CreateOrderRequest) string {
+// orderItemIndex — индекс первой позиции запроса с этим товаром/вариантом (-1 если нет).
+func orderItemIndex(req *CreateOrderRequest, productID, variantID string) int {
+	for i, item := range req.Items {
+		if item.ProductID == productID && (variantID == "" || item.VariantID == variantID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// formatOrderMessage форматирует сообщение о заказе.
+// Позиции и суммы берём из сохранённого заказа (цены из каталога), контакты — из запроса.
+func formatOrderMessage(order *models.Order, req CreateOrderRequest) string {
 	var b strings.Builder
 
 	b.WriteString("🛒 *НОВЫЙ ЗАКАЗ*\n\n")
+	b.WriteString(fmt.Sprintf("🧾 *Номер:* %s\n", order.ID))
 	b.WriteString(fmt.Sprintf("👤 *Клиент:* %s\n", req.Customer.Name))
 	b.WriteString(fmt.Sprintf("📧 *Email:* %s\n", req.Customer.Email))
 
@@ -121,17 +226,25 @@ func formatOrderMessage(req CreateOrderRequest) string {
 	}
 
 	b.WriteString("\n📦 *Товары:*\n")
-	total := int64(0)
-	for i, item := range req.Items {
+	for i, item := range order.Items {
 		itemTotal := item.Price * int64(item.Quantity)
-		total += itemTotal
-		b.WriteString(fmt.Sprintf("%d. Товар ID: %s\n", i+1, item.ProductID))
+		b.WriteString(fmt.Sprintf("%d. %s (ID: %s)\n", i+1, item.Title, item.ProductID))
+		if item.SKU != "" {
+			b.WriteString(fmt.Sprintf("   Вариант: %s %s\n", item.SKU, formatVariantOptions(item.Options)))
+		}
 		b.WriteString(fmt.Sprintf("   Количество: %d\n", item.Quantity))
 		b.WriteString(fmt.Sprintf("   Цена: %d руб.\n", item.Price/100))
 		b.WriteString(fmt.Sprintf("   Сумма: %d руб.\n\n", itemTotal/100))
 	}
 
-	b.WriteString(fmt.Sprintf("💰 *Итого:* %d руб.\n", req.Total/100))
+	if order.DiscountAmount > 0 {
+		b.WriteString(fmt.Sprintf("🧮 *Сумма товаров:* %d руб.\n", order.Subtotal/100))
+		b.WriteString(fmt.Sprintf("🏷 *Промокод %s:* −%d руб.\n", order.DiscountCode, order.DiscountAmount/100))
+	}
+	if order.ShippingMethod != "" {
+		b.WriteString(fmt.Sprintf("🚚 *Доставка:* %s (%s) — %d руб.\n", order.ShippingMethod, order.ShippingZone, order.ShippingCost/100))
+	}
+	b.WriteString(fmt.Sprintf("💰 *Итого:* %d руб.\n", order.Total/100))
 
 	if req.Comment != "" {
 		b.WriteString(fmt.Sprintf("\n💬 *Комментарий:*\n%s\n", req.Comment))
@@ -140,38 +253,21 @@ func formatOrderMessage(req CreateOrderRequest) string {
 	return b.String()
 }
 
-// sendTelegramMessage отправляет сообщение в Telegram через Bot API
-func sendTelegramMessage(botToken, chatID, message string) error {
-	// Экранируем специальные символы для Markdown
-	message = strings.ReplaceAll(message, "_", "\\_")
-	message = strings.ReplaceAll(message, "*", "\\*")
-	message = strings.ReplaceAll(message, "[", "\\[")
-	message = strings.ReplaceAll(message, "]", "\\]")
-
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
-
-	payload := map[string]interface{}{
-		"chat_id":    chatID,
-		"text":        message,
-		"parse_mode":  "Markdown",
-		"disable_web_page_preview": true,
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("telegram marshal: %w", err)
+// formatVariantOptions — {"size": "M", "color": "black"} → "(color: black, size: M)".
+// Ключи сортируем, чтобы сообщение не прыгало от заказа к заказу.
+func formatVariantOptions(options map[string]string) string {
+	if len(options) == 0 {
+		return ""
 	}
-
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("telegram request: %w", err)
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
 	}
-	defer resp.Body.Close()
+	sort.Strings(keys)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error: %s", string(body))
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + ": " + options[k]
 	}
-
-	return nil
+	return "(" + strings.Join(parts, ", ") + ")"
 }
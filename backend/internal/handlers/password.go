@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/notify"
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// Mailer — отправка писем покупателям, инициализируется из main.
+// nil — письма не отправляются (запросы всё равно отвечают как обычно).
+var Mailer notify.Mailer
+
+// Сброс пароля: срок жизни ссылки и сколько писем можно запросить за час.
+const (
+	passwordResetTTL        = time.Hour
+	passwordResetHourlyMax  = 3
+	passwordResetTokenBytes = 32
+)
+
+// ForgotPassword — запросить письмо со ссылкой для сброса пароля.
+// POST /api/auth/password/forgot
+// Body: { "email": "..." }
+// Ответ 200: { "message": "ok" } — ВСЕГДА, есть такой аккаунт или нет.
+//
+// Логика:
+//  1. Ищем юзера по email. Нет — молча отвечаем ok (не палим существование аккаунтов)
+//  2. Больше passwordResetHourlyMax писем за час — тоже молча ok (защита от спама)
+//  3. Генерируем случайный токен, в БД кладём только его sha256 и срок (1 час)
+//  4. Письмо со ссылкой <frontendURL>/reset-password?token=...
+//
+// Шаги 1-4 идут в фоне, после ответа: для существующего аккаунта запросов в БД
+// больше, и по времени ответа можно было бы понять, есть ли такой email.
+func ForgotPassword(frontendURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.ForgotPasswordRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "невалидный JSON"})
+		}
+		req.Email = strings.TrimSpace(req.Email)
+		if req.Email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email обязателен"})
+		}
+
+		// Строки из запроса копируем: fiber переиспользует его буферы после ответа
+		go startPasswordReset(strings.Clone(req.Email), strings.Clone(c.IP()), frontendURL)
+		return c.JSON(fiber.Map{"message": "ok"})
+	}
+}
+
+// startPasswordReset — шаги 1-4 ForgotPassword, запускается в горутине
+// (поэтому без *fiber.Ctx — ip передаётся строкой). Ничего не возвращает:
+// клиент уже получил ответ, ошибки — только в лог.
+func startPasswordReset(email, ip, frontendURL string) {
+	// 1. Юзер
+	user, err := Repo.Account.GetUserByEmail(email)
+	if err != nil || user == nil {
+		return
+	}
+
+	// 2. Лимит писем
+	sent, err := Repo.PasswordResets.CountRecent(user.ID, time.Hour)
+	if err != nil {
+		log.Printf("password forgot: %v", err)
+		return
+	}
+	if sent >= passwordResetHourlyMax {
+		return
+	}
+
+	// 3. Токен
+	token, err := utils.RandomToken(passwordResetTokenBytes)
+	if err != nil {
+		log.Printf("password forgot: %v", err)
+		return
+	}
+	reset := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		IP:        ip,
+	}
+	if err := Repo.PasswordResets.Create(reset, passwordResetTTL); err != nil {
+		log.Printf("password forgot: %v", err)
+		return
+	}
+
+	// 4. Письмо
+	link := strings.TrimRight(frontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	body := "Здравствуйте!\n\n" +
+		"Кто-то (надеемся, вы) запросил сброс пароля в магазине SOCIAL SH.\n" +
+		"Чтобы задать новый пароль, перейдите по ссылке (действует 1 час):\n\n" +
+		link + "\n\n" +
+		"Если вы не запрашивали сброс — просто проигнорируйте это письмо, пароль останется прежним."
+	sendMailAsync(user.Email, "Сброс пароля", body)
+}
+
+// ResetPassword — задать новый пароль по токену из письма.
+// POST /api/auth/password/reset
+// Body: { "token": "...", "password": "..." }
+// Ответ 200: { "message": "ok" }
+//
+// Токен одноразовый: Repo.PasswordResets.Consume гасит его, меняет пароль
+// и отзывает все refresh-токены юзера одной транзакцией — после сброса
+// все устройства (в том числе чужие) разлогинены, входить нужно заново.
+func ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "невалидный JSON"})
+	}
+	if req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "токен обязателен"})
+	}
+	if msg := validatePassword(req.Password); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	hashed, err := hashPassword(req.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "не удалось захешировать пароль"})
+	}
+
+	if _, err := Repo.PasswordResets.Consume(utils.HashToken(req.Token), hashed); err != nil {
+		if errors.Is(err, repository.ErrResetTokenInvalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "ссылка для сброса пароля недействительна или устарела",
+			})
+		}
+		log.Printf("password reset: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "не удалось сменить пароль"})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
+
+// sendMailAsync — отправить письмо в фоне; ошибка только в лог.
+// Для писем, которые не должны задерживать ответ (и выдавать по времени ответа,
+// было письмо или нет).
+// Пустой to (аккаунт из Telegram без email) — письма просто нет.
+func sendMailAsync(to, subject, body string) {
+	if to == "" {
+		return
+	}
+	if Mailer == nil {
+		log.Printf("mail: Mailer не настроен, письмо %q для %s не отправлено", subject, to)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := Mailer.SendMail(ctx, to, subject, body); err != nil {
+			log.Printf("mail: письмо %q для %s: %v", subject, to, err)
+		}
+	}()
+}
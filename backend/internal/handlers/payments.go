@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/payments"
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// Payments — включённые платёжные провайдеры, инициализируется из main.
+// nil — онлайн-оплата выключена.
+var Payments *payments.Registry
+
+// CreatePayment — начать оплату заказа.
+// POST /api/payments (через OptionalAuth, как и создание заказа)
+// Body: { "orderId": "...", "provider": "yookassa" } (provider необязателен)
+// Ответ 201: { "paymentId": "...", "confirmationUrl": "https://..." } — фронт редиректит туда покупателя.
+//
+// Логика:
+//  1. Ищем провайдера в Payments (пустое имя — провайдер по умолчанию).
+//  2. Грузим заказ. Заказ аккаунта может оплатить только его владелец,
+//     гостевой — любой, кто знает ID заказа (его отдали при оформлении).
+//  3. Оплатить можно только pending-заказ → иначе 409.
+//  4. Заводим платёж в БД (pending) на сумму заказа, просим провайдера создать платёж,
+//     сохраняем его ID и ссылку на оплату. У заказа уже есть pending-платёж —
+//     отдаём его ссылку (ответ 200), второй не заводим.
+//
+// Оплату подтверждает только вебхук провайдера (PaymentWebhook), не редирект покупателя.
+func CreatePayment(returnURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if Payments == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "онлайн-оплата недоступна",
+			})
+		}
+
+		var req models.CreatePaymentRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "невалидный JSON",
+			})
+		}
+		if req.OrderID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "orderId обязателен",
+			})
+		}
+
+		// 1. Провайдер
+		provider, ok := Payments.Get(req.Provider)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "неизвестный способ оплаты",
+			})
+		}
+
+		// 2. Заказ
+		order, err := Repo.Orders.GetByID(req.OrderID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "заказ не найден",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "ошибка при получении заказа",
+			})
+		}
+		// Чужой заказ — отвечаем так же, как на несуществующий
+		userID, _ := c.Locals("userID").(string)
+		if order.UserID != "" && order.UserID != userID {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "заказ не найден",
+			})
+		}
+
+		// 3. Статус
+		if order.Status != models.OrderStatusPending {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "заказ уже оплачен или отменён",
+			})
+		}
+
+		// 4. Платёж. Незавершённый уже есть (вторая вкладка, повтор запроса) — продолжаем его:
+		// две живые ссылки на оплату одного заказа можно оплатить обе, и деньги спишутся дважды
+		payment, err := Repo.Payments.GetPendingByOrder(order.ID)
+		switch {
+		case err == nil:
+			if payment.ConfirmationURL != "" {
+				return c.JSON(fiber.Map{
+					"paymentId":       payment.ID,
+					"confirmationUrl": payment.ConfirmationURL,
+				})
+			}
+			// Ссылки нет — прошлое обращение к провайдеру не удалось. Повторяем его с тем же ID
+			// платежа (у провайдера это ключ идемпотентности) и у того же провайдера
+			if provider, ok = Payments.Get(payment.Provider); !ok || payment.Provider == "" {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "по заказу есть незавершённая оплата через отключённый способ",
+				})
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			payment = &models.Payment{
+				OrderID:  order.ID,
+				Provider: provider.Name(),
+				Amount:   order.Total,
+				Currency: "RUB",
+			}
+			if err := Repo.Payments.Create(payment); err != nil {
+				if utils.IsDuplicateKeyError(err) {
+					// Параллельный запрос успел завести платёж (idx_payments_pending_order)
+					return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+						"error": "оплата заказа уже создаётся, повторите запрос",
+					})
+				}
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "не удалось создать платёж",
+				})
+			}
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось создать платёж",
+			})
+		}
+
+		result, err := provider.Create(c.UserContext(), payments.CreateRequest{
+			PaymentID:   payment.ID,
+			OrderID:     order.ID,
+			Amount:      payment.Amount,
+			Currency:    payment.Currency,
+			Description: fmt.Sprintf("Заказ %s", order.ID),
+			ReturnURL:   returnURL,
+		})
+		if err != nil {
+			log.Printf("payment %s: provider %s create: %v", payment.ID, provider.Name(), err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "платёжный сервис недоступен, попробуйте позже",
+			})
+		}
+
+		if err := Repo.Payments.SetConfirmation(payment.ID, result.ProviderPaymentID, result.ConfirmationURL); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось сохранить платёж",
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"paymentId":       payment.ID,
+			"confirmationUrl": result.ConfirmationURL,
+		})
+	}
+}
+
+// PaymentWebhook — уведомление провайдера о результате оплаты.
+// POST /api/payments/webhook/:provider
+// Ответ: { "message": "ok" } — любой 2xx провайдер считает доставленным, остальное ретраит.
+//
+// Логика:
+//  1. Провайдер по имени из URL → нет такого 404.
+//  2. ParseWebhook проверяет подпись → не сошлась 401.
+//  3. Ищем наш платёж по ID у провайдера, сверяем сумму (не сошлась → 400, в лог).
+//  4. succeeded → платёж succeeded и заказ → paid одной транзакцией (Payments.Succeed).
+//     canceled → платёж canceled, заказ не трогаем (покупатель может попробовать снова).
+//
+// Повторный вебхук безопасен: платёж меняется только из pending (Succeed/SetStatus вернул false —
+// ничего больше не делаем, отвечаем 200), поэтому заказ переводится в paid ровно один раз
+// и только по платежу, который ещё не был отменён.
+func PaymentWebhook(c *fiber.Ctx) error {
+	if Payments == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "онлайн-оплата недоступна",
+		})
+	}
+
+	// 1. Провайдер
+	// Пустое имя в Get означает провайдера по умолчанию — здесь так нельзя
+	name := c.Params("provider")
+	provider, ok := Payments.Get(name)
+	if !ok || name == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "неизвестный провайдер",
+		})
+	}
+
+	// 2. Подпись
+	header := func(key string) string { return c.Get(key) }
+	event, err := provider.ParseWebhook(header, c.Body())
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "неверная подпись",
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный вебхук",
+		})
+	}
+
+	// 3. Платёж
+	payment, err := Repo.Payments.GetByProviderID(provider.Name(), event.ProviderPaymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "платёж не найден",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при получении платежа",
+		})
+	}
+	if event.Amount != payment.Amount {
+		log.Printf("payment %s: сумма в вебхуке %d не совпадает с платежом %d", payment.ID, event.Amount, payment.Amount)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "сумма платежа не совпадает",
+		})
+	}
+
+	// 4. Статусы
+	switch event.Status {
+	case payments.StatusSucceeded:
+		// Платёж и заказ — одной транзакцией: при 500 не меняется ничего,
+		// и повтор вебхука от провайдера доведёт дело до конца
+		comment := fmt.Sprintf("оплата %s %s", provider.Name(), event.ProviderPaymentID)
+		updated, err := Repo.Payments.Succeed(payment.ID, comment)
+		if err != nil && !errors.Is(err, repository.ErrInvalidStatusTransition) {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось обновить платёж",
+			})
+		}
+		if !updated {
+			// Повторный вебхук или платёж уже отменён — заказ не трогаем, провайдеру отвечаем 200
+			log.Printf("payment %s: вебхук succeeded для платежа в статусе %s пропущен", payment.ID, payment.Status)
+			break
+		}
+		if err != nil {
+			// Заказ успели отменить — деньги надо вернуть руками
+			log.Printf("payment %s: заказ %s не переведён в paid: %v", payment.ID, payment.OrderID, err)
+		}
+
+	case payments.StatusCanceled:
+		updated, err := Repo.Payments.SetStatus(payment.ID, payments.StatusCanceled)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось обновить платёж",
+			})
+		}
+		if !updated {
+			log.Printf("payment %s: вебхук canceled для платежа в статусе %s пропущен", payment.ID, payment.Status)
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
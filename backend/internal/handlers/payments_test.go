@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/payments"
+	"socialsh/backend/internal/repository"
+)
+
+// memPayments — платежи в памяти. Встроенный интерфейс закрывает методы,
+// которые вебхук не вызывает (вызов такого метода в тесте — паника).
+type memPayments struct {
+	repository.PaymentRepository
+	byID   map[string]*models.Payment
+	orders *memOrders
+}
+
+func (m *memPayments) GetByProviderID(provider, providerPaymentID string) (*models.Payment, error) {
+	for _, p := range m.byID {
+		if p.Provider == provider && p.ProviderPaymentID == providerPaymentID {
+			cp := *p
+			return &cp, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memPayments) SetStatus(id, status string) (bool, error) {
+	p, ok := m.byID[id]
+	if !ok || p.Status != payments.StatusPending {
+		return false, nil
+	}
+	p.Status = status
+	return true, nil
+}
+
+// Succeed — как в PaymentSQLRepo: ошибка заказа (кроме запрещённого перехода)
+// откатывает и платёж.
+func (m *memPayments) Succeed(id, comment string) (bool, error) {
+	p, ok := m.byID[id]
+	if !ok || p.Status != payments.StatusPending {
+		return false, nil
+	}
+	_, err := m.orders.UpdateStatus(p.OrderID, models.OrderStatusPaid, "", comment)
+	if err != nil && !errors.Is(err, repository.ErrInvalidStatusTransition) {
+		return false, err
+	}
+	p.Status = payments.StatusSucceeded
+	return true, err
+}
+
+// memOrders — заказы в памяти, переходы статусов по models.OrderTransitions.
+// failures — сколько следующих UpdateStatus упадут, как при недоступной БД.
+type memOrders struct {
+	repository.OrderRepository
+	byID     map[string]*models.Order
+	history  []string
+	failures int
+}
+
+func (m *memOrders) UpdateStatus(id, status, changedBy, comment string) (*models.Order, error) {
+	if m.failures > 0 {
+		m.failures--
+		return nil, errors.New("pq: connection reset")
+	}
+	o, ok := m.byID[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	allowed := false
+	for _, next := range models.OrderTransitions[o.Status] {
+		allowed = allowed || next == status
+	}
+	if !allowed {
+		return nil, repository.ErrInvalidStatusTransition
+	}
+	o.Status = status
+	m.history = append(m.history, status)
+	return o, nil
+}
+
+// webhookFixture — магазин с одним заказом на 1500 ₽ и его pending-платежом у фейкового провайдера.
+func webhookFixture(t *testing.T) (*fiber.App, *payments.Fake, *memPayments, *memOrders) {
+	t.Helper()
+	fake := payments.NewFake("secret", "http://shop.test")
+	pays := &memPayments{byID: map[string]*models.Payment{
+		"p1": {ID: "p1", OrderID: "o1", Provider: "fake", ProviderPaymentID: "fake_1",
+			Status: payments.StatusPending, Amount: 150000, Currency: "RUB"},
+	}}
+	orders := &memOrders{byID: map[string]*models.Order{
+		"o1": {ID: "o1", Status: models.OrderStatusPending},
+	}}
+
+	prevRepo, prevPayments := Repo, Payments
+	pays.orders = orders
+	Repo = &repository.Store{Payments: pays, Orders: orders}
+	Payments = payments.NewRegistry(fake)
+	t.Cleanup(func() { Repo, Payments = prevRepo, prevPayments })
+
+	app := fiber.New()
+	app.Post("/api/payments/webhook/:provider", PaymentWebhook)
+	return app, fake, pays, orders
+}
+
+func sendWebhook(t *testing.T, app *fiber.App, provider string, body []byte, signature string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/payments/webhook/"+provider, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payments.FakeSignatureHeader, signature)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestPaymentWebhookPendingToPaid(t *testing.T) {
+	app, fake, pays, orders := webhookFixture(t)
+
+	body, signature, err := fake.Webhook("fake_1", payments.StatusSucceeded, 150000)
+	if err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	if code := sendWebhook(t, app, "fake", body, signature); code != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if got := pays.byID["p1"].Status; got != payments.StatusSucceeded {
+		t.Errorf("платёж = %s, want succeeded", got)
+	}
+	if got := orders.byID["o1"].Status; got != models.OrderStatusPaid {
+		t.Errorf("заказ = %s, want paid", got)
+	}
+
+	// Провайдер повторил вебхук — 200, второй записи в историю заказа нет
+	if code := sendWebhook(t, app, "fake", body, signature); code != fiber.StatusOK {
+		t.Fatalf("повтор: status = %d, want 200", code)
+	}
+	if len(orders.history) != 1 {
+		t.Errorf("история заказа = %v, want один переход", orders.history)
+	}
+}
+
+func TestPaymentWebhookAmountMismatch(t *testing.T) {
+	app, fake, pays, orders := webhookFixture(t)
+
+	body, signature, err := fake.Webhook("fake_1", payments.StatusSucceeded, 100)
+	if err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	if code := sendWebhook(t, app, "fake", body, signature); code != fiber.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", code)
+	}
+	if pays.byID["p1"].Status != payments.StatusPending || orders.byID["o1"].Status != models.OrderStatusPending {
+		t.Error("вебхук с чужой суммой изменил платёж или заказ")
+	}
+}
+
+func TestPaymentWebhookRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		tamper   func(body []byte, signature string) ([]byte, string)
+		want     int
+	}{
+		{"неверная подпись", "fake", func(b []byte, _ string) ([]byte, string) { return b, "deadbeef" }, fiber.StatusUnauthorized},
+		{"без подписи", "fake", func(b []byte, _ string) ([]byte, string) { return b, "" }, fiber.StatusUnauthorized},
+		{"тело изменено после подписи", "fake", func(b []byte, s string) ([]byte, string) {
+			return bytes.Replace(b, []byte("fake_1"), []byte("fake_2"), 1), s
+		}, fiber.StatusUnauthorized},
+		{"неизвестный провайдер", "paypal", func(b []byte, s string) ([]byte, string) { return b, s }, fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, fake, pays, _ := webhookFixture(t)
+			body, signature, err := fake.Webhook("fake_1", payments.StatusSucceeded, 150000)
+			if err != nil {
+				t.Fatalf("Webhook: %v", err)
+			}
+			body, signature = tt.tamper(body, signature)
+			if code := sendWebhook(t, app, tt.provider, body, signature); code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+			if pays.byID["p1"].Status != payments.StatusPending {
+				t.Error("отклонённый вебхук изменил платёж")
+			}
+		})
+	}
+}
+
+func TestPaymentWebhookCanceled(t *testing.T) {
+	app, fake, pays, orders := webhookFixture(t)
+
+	body, signature, err := fake.Webhook("fake_1", payments.StatusCanceled, 150000)
+	if err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	if code := sendWebhook(t, app, "fake", body, signature); code != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if pays.byID["p1"].Status != payments.StatusCanceled {
+		t.Errorf("платёж = %s, want canceled", pays.byID["p1"].Status)
+	}
+	if orders.byID["o1"].Status != models.OrderStatusPending {
+		t.Error("отмена платежа не должна трогать заказ")
+	}
+
+	// Опоздавший succeeded после отмены — платёж и заказ не меняются
+	body, signature, _ = fake.Webhook("fake_1", payments.StatusSucceeded, 150000)
+	if code := sendWebhook(t, app, "fake", body, signature); code != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if pays.byID["p1"].Status != payments.StatusCanceled || orders.byID["o1"].Status != models.OrderStatusPending {
+		t.Error("succeeded после canceled изменил платёж или заказ")
+	}
+}
+
+// Заказ не обновился (500) — платёж тоже остаётся pending, и повтор вебхука доводит оплату до конца.
+func TestPaymentWebhookRetryAfterOrderError(t *testing.T) {
+	app, fake, pays, orders := webhookFixture(t)
+	orders.failures = 1
+
+	body, signature, err := fake.Webhook("fake_1", payments.StatusSucceeded, 150000)
+	if err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	if code := sendWebhook(t, app, "fake", body, signature); code != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", code)
+	}
+	if pays.byID["p1"].Status != payments.StatusPending || orders.byID["o1"].Status != models.OrderStatusPending {
+		t.Fatal("после ошибки платёж или заказ изменились наполовину")
+	}
+
+	// Провайдер повторяет вебхук
+	if code := sendWebhook(t, app, "fake", body, signature); code != fiber.StatusOK {
+		t.Fatalf("повтор: status = %d, want 200", code)
+	}
+	if pays.byID["p1"].Status != payments.StatusSucceeded || orders.byID["o1"].Status != models.OrderStatusPaid {
+		t.Errorf("после повтора платёж = %s, заказ = %s; want succeeded и paid",
+			pays.byID["p1"].Status, orders.byID["o1"].Status)
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Персональные данные по 152-ФЗ / GDPR: выгрузка (право на доступ) и удаление.
+// Юзер делает это сам из личного кабинета, админ (право users:manage) —
+// по запросу, пришедшему на почту.
+// Удаление = анонимизация: аккаунт стирается, заказы остаются для бухгалтерии,
+// но контакты в них заменяются псевдонимом (см. AccountRepository.DeleteAccount).
+// ═══════════════════════════════════════════════════════════════
+
+// ExportAccountData — выгрузка данных текущего юзера.
+// GET /api/account/export?format=json|zip
+// Ответ 200: models.PersonalDataExport (JSON) или ZIP с profile.json, orders.json,
+// addresses.json, sessions.json. В обоих случаях — вложение (Content-Disposition).
+func ExportAccountData(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	return sendPersonalData(c, userID)
+}
+
+// DeleteAccount — удалить свой аккаунт.
+// POST /api/account/delete
+// Ответ 200: { "message": "ok" } — аккаунт удалён, все входы завершены (в режиме cookie стёрты и куки).
+// 403 с "reauthRequired" — нужен недавний ввод пароля (POST /api/auth/reauth);
+// 409 — аккаунт сотрудника: сначала владелец магазина должен снять с него роль.
+func DeleteAccount(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+
+	if !recentlyAuthenticated(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":          "для удаления аккаунта подтвердите пароль",
+			"reauthRequired": true,
+		})
+	}
+
+	if _, errResp := eraseAccount(c, userID); errResp != nil {
+		return errResp
+	}
+
+	clearAuthCookies(c)
+	return c.JSON(fiber.Map{"message": "ok"})
+}
+
+// AdminExportUserData — выгрузка данных юзера по запросу, пришедшему на почту.
+// GET /api/admin/users/:id/export?format=json|zip
+// Ответ: как у GET /api/account/export.
+func AdminExportUserData(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("userID").(string)
+	id := c.Params("id")
+
+	log.Printf("personal data: админ %s выгрузил данные юзера %s", adminID, id)
+	return sendPersonalData(c, id)
+}
+
+// AdminDeleteUser — удалить аккаунт по запросу, пришедшему на почту.
+// POST /api/admin/users/:id/delete
+// Ответ 200: { "message": "ok", "pseudonym": "deleted-..." } — псевдоним, которым
+// подписаны его заказы (для ответа бухгалтерии или самому юзеру).
+// 400 — свой аккаунт; 404 — нет юзера; 409 — аккаунт сотрудника (сначала снять роль).
+func AdminDeleteUser(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("userID").(string)
+	id := c.Params("id")
+	if id == adminID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "нельзя удалить самого себя",
+		})
+	}
+
+	pseudonym, errResp := eraseAccount(c, id)
+	if errResp != nil {
+		return errResp
+	}
+
+	log.Printf("personal data: админ %s удалил аккаунт %s (%s)", adminID, id, pseudonym)
+	return c.JSON(fiber.Map{"message": "ok", "pseudonym": pseudonym})
+}
+
+// eraseAccount — общая часть удаления: проверки и AccountRepository.DeleteAccount.
+// errResp != nil → ответ с ошибкой уже записан, хендлер возвращает errResp.
+//
+// Логика:
+//  1. Юзер есть (нет → 404) и не сотрудник (→ 409): удалённый owner мог бы оставить
+//     магазин без владельца, а роль снимается в админке явно
+//  2. Случайный псевдоним — заказы одного юзера остаются связаны друг с другом,
+//     но вычислить по нему, чьи они, нельзя
+//  3. DeleteAccount
+func eraseAccount(c *fiber.Ctx, userID string) (string, error) {
+	// 1. Юзер
+	user, err := Repo.Account.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return "", c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "пользователь не найден",
+			})
+		}
+		log.Printf("delete account: %v", err)
+		return "", c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось удалить аккаунт",
+		})
+	}
+	if auth.IsStaff(user.Role) {
+		return "", c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "аккаунт сотрудника: сначала снимите с него админскую роль",
+		})
+	}
+
+	// 2. Псевдоним
+	suffix, err := utils.RandomToken(6)
+	if err != nil {
+		log.Printf("delete account: %v", err)
+		return "", c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось удалить аккаунт",
+		})
+	}
+	pseudonym := "deleted-" + suffix
+
+	// 3. Удаление
+	if err := Repo.Account.DeleteAccount(userID, pseudonym); err != nil {
+		log.Printf("delete account: %v", err)
+		return "", c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось удалить аккаунт",
+		})
+	}
+	return pseudonym, nil
+}
+
+// sendPersonalData — собрать выгрузку юзера и отдать её в формате из ?format=.
+func sendPersonalData(c *fiber.Ctx, userID string) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "zip" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format: ожидается json или zip",
+		})
+	}
+
+	export, err := buildPersonalDataExport(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "пользователь не найден",
+			})
+		}
+		log.Printf("export account: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось выгрузить данные",
+		})
+	}
+
+	name := "socialsh-data-" + export.ExportedAt.Format("2006-01-02")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	if format == "json" {
+		c.Attachment(name + ".json")
+		return c.JSON(export)
+	}
+
+	archive, err := personalDataZip(export)
+	if err != nil {
+		log.Printf("export account: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось выгрузить данные",
+		})
+	}
+	c.Attachment(name + ".zip")
+	return c.Send(archive)
+}
+
+// buildPersonalDataExport — профиль, заказы, адреса из заказов и активные входы юзера.
+func buildPersonalDataExport(userID string) (*models.PersonalDataExport, error) {
+	profile, err := Repo.Users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := Repo.Account.ListOrdersByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := Repo.RefreshTokens.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Отдельной таблицы адресов нет — собираем те, что указывались в заказах
+	addresses := []string{}
+	seen := map[string]bool{}
+	for _, o := range orders {
+		address := strings.TrimSpace(o.Customer.Address)
+		if address != "" && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	return &models.PersonalDataExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    *profile,
+		Orders:     orders,
+		Addresses:  addresses,
+		Sessions:   sessions,
+	}, nil
+}
+
+// personalDataZip — ZIP с JSON-файлом на каждый раздел выгрузки.
+func personalDataZip(export *models.PersonalDataExport) ([]byte, error) {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"orders.json", export.Orders},
+		{"addresses.json", export.Addresses},
+		{"sessions.json", export.Sessions},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		data, err := json.MarshalIndent(f.data, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", f.name, err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", f.name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("export %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("export zip: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Активные сессии в личном кабинете ("где выполнен вход").
+// Сессия = семья refresh-токенов одного логина, её id — claim sid в access-токене.
+// Отозванная сессия сразу перестаёт работать: Protected сверяет sid с БД.
+// ═══════════════════════════════════════════════════════════════
+
+// GetSessions — активные сессии текущего юзера.
+// GET /api/account/sessions
+// Ответ: { "items": [ { "id", "userAgent", "ip", "signedInAt", "lastUsedAt", "expiresAt", "current" }, ... ] }
+// current = true у сессии, из которой пришёл запрос.
+func GetSessions(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	sessions, err := Repo.RefreshTokens.ListSessions(userID)
+	if err != nil {
+		log.Printf("sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить список сессий",
+		})
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == sessionID
+	}
+	return c.JSON(fiber.Map{"items": sessions})
+}
+
+// RevokeSession — завершить одну сессию (например, забытый чужой компьютер).
+// DELETE /api/account/sessions/:id
+// Ответ: { "message": "ok" }
+// Завершить можно и текущую — это то же самое, что logout.
+func RevokeSession(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id обязателен",
+		})
+	}
+
+	if err := Repo.RefreshTokens.RevokeSession(userID, id); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) || utils.IsInvalidInputError(err) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "сессия не найдена",
+			})
+		}
+		log.Printf("sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось завершить сессию",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
+
+// RevokeOtherSessions — выйти на всех устройствах, кроме текущего.
+// DELETE /api/account/sessions
+// Ответ: { "message": "ok" }
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	sessionID, _ := c.Locals("sessionID").(string)
+
+	if err := Repo.RefreshTokens.RevokeOtherSessions(userID, sessionID); err != nil {
+		log.Printf("sessions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось завершить сессии",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "ok"})
+}
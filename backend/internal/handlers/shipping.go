@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Доставка: публичный список способов и расчёт стоимости.
+// Та же функция quoteShipping считает доставку и в CreateOrder,
+// поэтому цифра на чекауте совпадает с итоговым заказом.
+// ═══════════════════════════════════════════════════════════════
+
+// ShippingChoice — выбранный покупателем способ доставки и зона.
+type ShippingChoice struct {
+	MethodID string `json:"methodId"`
+	Zone     string `json:"zone"`
+}
+
+// QuoteShippingRequest — корзина + зона для расчёта доставки.
+type QuoteShippingRequest struct {
+	Zone  string            `json:"zone"`
+	Items []CreateOrderItem `json:"items"`
+}
+
+// ShippingQuote — стоимость доставки одним способом.
+// Available = false — способ не возит в эту зону или посылка слишком тяжёлая.
+type ShippingQuote struct {
+	MethodID  string `json:"methodId"`
+	Code      string `json:"code"`
+	Title     string `json:"title"`
+	Kind      string `json:"kind"`
+	Cost      int64  `json:"cost"`
+	Available bool   `json:"available"`
+}
+
+// GetShippingMethods — включённые способы доставки (с правилами — фронту нужны зоны).
+// GET /api/shipping/methods
+// Ответ: { "items": [ { "id", "code", "title", "kind", "rules", "freeFrom" }, ... ] }
+func GetShippingMethods(c *fiber.Ctx) error {
+	items, err := Repo.Shipping.ListActive()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить способы доставки",
+		})
+	}
+
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// QuoteShipping — стоимость доставки корзины всеми включёнными способами.
+// POST /api/shipping/quote
+// Body: { "zone": "moscow", "items": [ { "productId", "variantId", "quantity" } ] }
+// Ответ: { "subtotal": 99800, "weightGrams": 1200, "items": [ ShippingQuote, ... ] }
+func QuoteShipping(c *fiber.Ctx) error {
+	var req QuoteShippingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+	if req.Zone == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "zone обязательна",
+		})
+	}
+	if len(req.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "корзина пуста",
+		})
+	}
+
+	items, subtotal, itemErrors, err := priceOrderItems(req.Items)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка при проверке товаров",
+		})
+	}
+	if len(itemErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "некоторые позиции заказа невалидны",
+			"items": itemErrors,
+		})
+	}
+
+	methods, err := Repo.Shipping.ListActive()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось получить способы доставки",
+		})
+	}
+
+	weight := orderWeight(items)
+	quotes := make([]ShippingQuote, 0, len(methods))
+	for i := range methods {
+		cost, ok := quoteShipping(&methods[i], req.Zone, weight, subtotal)
+		quotes = append(quotes, ShippingQuote{
+			MethodID:  methods[i].ID,
+			Code:      methods[i].Code,
+			Title:     methods[i].Title,
+			Kind:      methods[i].Kind,
+			Cost:      cost,
+			Available: ok,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"subtotal":    subtotal,
+		"weightGrams": weight,
+		"items":       quotes,
+	})
+}
+
+// resolveShipping — проверить выбор покупателя и посчитать доставку для заказа.
+//
+// choice == nil допустим, только если в магазине нет ни одного включённого способа
+// (доставку тогда согласует менеджер). reason != "" — ошибка для покупателя (400),
+// error — ошибка БД (500).
+func resolveShipping(choice *ShippingChoice, items []models.OrderItem, subtotal int64) (*models.ShippingMethod, int64, string, error) {
+	if choice == nil || choice.MethodID == "" {
+		methods, err := Repo.Shipping.ListActive()
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if len(methods) > 0 {
+			return nil, 0, "выберите способ доставки", nil
+		}
+		return nil, 0, "", nil
+	}
+
+	method, err := Repo.Shipping.GetByID(choice.MethodID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || utils.IsInvalidInputError(err) {
+			return nil, 0, "способ доставки не найден", nil
+		}
+		return nil, 0, "", err
+	}
+	if !method.Active {
+		return nil, 0, "способ доставки недоступен", nil
+	}
+	if choice.Zone == "" {
+		return nil, 0, "укажите зону доставки", nil
+	}
+
+	cost, ok := quoteShipping(method, choice.Zone, orderWeight(items), subtotal)
+	if !ok {
+		return nil, 0, "этот способ не доставляет в выбранную зону или заказ слишком тяжёлый", nil
+	}
+	return method, cost, "", nil
+}
+
+// quoteShipping — стоимость доставки способом method.
+//
+// Как читается:
+//  1. Подходящие правила: зона совпадает (или "*") и вес влезает в MaxWeightGrams (0 — без лимита).
+//  2. Из подходящих — правило с конкретной зоной важнее "*",
+//     среди равных по зоне — с меньшим лимитом веса (точнее по весу).
+//  3. Ни одно не подошло → ok = false.
+//  4. Сумма товаров >= FreeFrom → доставка бесплатна.
+func quoteShipping(method *models.ShippingMethod, zone string, weightGrams int, subtotal int64) (cost int64, ok bool) {
+	var best *models.ShippingRule
+	for i := range method.Rules {
+		rule := &method.Rules[i]
+		if rule.Zone != zone && rule.Zone != "*" {
+			continue
+		}
+		if rule.MaxWeightGrams > 0 && weightGrams > rule.MaxWeightGrams {
+			continue
+		}
+		if best == nil || betterShippingRule(rule, best) {
+			best = rule
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+
+	if method.FreeFrom != nil && subtotal >= *method.FreeFrom {
+		return 0, true
+	}
+	return best.Price, true
+}
+
+// betterShippingRule — a точнее b: конкретная зона важнее "*", потом меньший лимит веса.
+func betterShippingRule(a, b *models.ShippingRule) bool {
+	if (a.Zone == "*") != (b.Zone == "*") {
+		return b.Zone == "*"
+	}
+	if a.MaxWeightGrams == 0 {
+		return false
+	}
+	return b.MaxWeightGrams == 0 || a.MaxWeightGrams < b.MaxWeightGrams
+}
+
+// orderWeight — общий вес позиций в граммах.
+func orderWeight(items []models.OrderItem) int {
+	total := 0
+	for _, item := range items {
+		total += item.WeightGrams * item.Quantity
+	}
+	return total
+}
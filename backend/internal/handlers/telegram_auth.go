@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Вход через Telegram Login Widget.
+// Фронт показывает кнопку виджета, Telegram отдаёт ему подписанный объект юзера,
+// фронт пересылает его сюда. Подпись и свежесть проверяет auth.VerifyTelegramLogin.
+// Аккаунт ищется по telegram_id; нет такого — создаётся новый, без email и пароля.
+// К существующему аккаунту Telegram привязывается из личного кабинета.
+// ═══════════════════════════════════════════════════════════════
+
+// telegramAuthMaxAge — сколько живут данные виджета (auth_date) до отправки к нам.
+const telegramAuthMaxAge = time.Hour
+
+// TelegramSignIn — вход (и регистрация) через Telegram.
+// POST /api/auth/telegram
+// Body: объект юзера от виджета как есть: { "id", "first_name", "last_name", "username", "photo_url", "auth_date", "hash" }
+// Ответ 200: { "access": "<jwt>", "refresh": "<jwt>" } или { "mfaRequired": true, "challenge" } — как у SignIn
+// 401 — подпись не сошлась или данные устарели; 403 — аккаунт заблокирован;
+// 503 — не задан токен бота (TELEGRAM_LOGIN_BOT_TOKEN).
+//
+// Логика:
+//  1. Проверяем подпись и auth_date
+//  2. Ищем юзера по telegram_id; нет — создаём (имя из Telegram, роль user)
+//  3. Заблокирован → 403
+//  4. Дальше как в SignIn: 2FA → challenge, иначе пара токенов
+func TelegramSignIn(jwtSecret, refreshSecret, botToken string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		identity, errResp := verifyTelegramRequest(c, botToken)
+		if identity == nil {
+			return errResp
+		}
+
+		user, err := findOrCreateTelegramUser(identity)
+		if err != nil {
+			log.Printf("telegram sign-in: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось войти через Telegram"})
+		}
+
+		if user.BlockedAt != nil {
+			return c.Status(403).JSON(fiber.Map{"error": "аккаунт заблокирован"})
+		}
+
+		return completeSignIn(c, user, jwtSecret, refreshSecret)
+	}
+}
+
+// LinkTelegram — привязать Telegram к текущему аккаунту.
+// POST /api/account/telegram
+// Body: объект юзера от виджета (как в POST /api/auth/telegram)
+// Ответ 200: { "user": { профиль с telegramId } }
+// 403 с "reauthRequired" — нужен недавний ввод пароля (новый способ входа в аккаунт);
+// 409 — этот Telegram уже привязан к другому аккаунту.
+func LinkTelegram(botToken string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+
+		if !recentlyAuthenticated(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":          "для привязки Telegram подтвердите пароль",
+				"reauthRequired": true,
+			})
+		}
+
+		identity, errResp := verifyTelegramRequest(c, botToken)
+		if identity == nil {
+			return errResp
+		}
+
+		user, err := Repo.Account.LinkTelegram(userID, identity.ID, identity.Username)
+		if err != nil {
+			if utils.IsDuplicateKeyError(err) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "этот Telegram уже привязан к другому аккаунту",
+				})
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "пользователь не найден",
+				})
+			}
+			log.Printf("telegram link: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось привязать Telegram",
+			})
+		}
+
+		return c.JSON(fiber.Map{"user": user})
+	}
+}
+
+// UnlinkTelegram — отвязать Telegram от текущего аккаунта.
+// DELETE /api/account/telegram
+// Ответ 200: { "user": { профиль без telegramId } }
+// 409 — у аккаунта нет email и пароля, Telegram — единственный способ войти.
+// Такому юзеру сначала нужно указать email и задать пароль через «Забыли пароль?».
+func UnlinkTelegram(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+
+	user, err := Repo.Account.UnlinkTelegram(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrLastLoginMethod) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Telegram — единственный способ входа: сначала укажите email и задайте пароль",
+			})
+		}
+		log.Printf("telegram unlink: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось отвязать Telegram",
+		})
+	}
+
+	return c.JSON(fiber.Map{"user": user})
+}
+
+// verifyTelegramRequest — достать из body данные виджета и проверить их.
+// identity == nil → ответ с ошибкой уже записан, хендлер возвращает errResp.
+func verifyTelegramRequest(c *fiber.Ctx, botToken string) (*auth.TelegramIdentity, error) {
+	if botToken == "" {
+		return nil, c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "вход через Telegram не настроен",
+		})
+	}
+
+	fields, err := telegramFields(c.Body())
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	identity, err := auth.VerifyTelegramLogin(botToken, fields, telegramAuthMaxAge, time.Now())
+	if err != nil {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	return identity, nil
+}
+
+// telegramFields — объект виджета → поля строками для data-check-string.
+// Числа (id, auth_date) читаем как json.Number, чтобы взять их ровно в том виде,
+// в каком их подписал Telegram (float64 испортил бы большие id).
+func telegramFields(body []byte) (map[string]string, error) {
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, errors.New("невалидный JSON")
+	}
+
+	fields := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case string:
+			fields[k] = v
+		case json.Number:
+			fields[k] = v.String()
+		default:
+			return nil, fmt.Errorf("поле %s: неожиданный тип", k)
+		}
+	}
+	return fields, nil
+}
+
+// findOrCreateTelegramUser — юзер с этим telegram_id; нет — новый аккаунт без email и пароля.
+// Username в Telegram меняется — при каждом входе обновляем сохранённый.
+func findOrCreateTelegramUser(identity *auth.TelegramIdentity) (*models.User, error) {
+	user, err := Repo.Account.GetUserByTelegramID(identity.ID)
+	if err == nil {
+		if user.TelegramUsername != identity.Username {
+			if updated, err := Repo.Account.LinkTelegram(user.ID, identity.ID, identity.Username); err == nil {
+				user = updated
+			} else {
+				log.Printf("telegram sign-in: обновление username: %v", err)
+			}
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	telegramID := identity.ID
+	user = &models.User{
+		Name:             identity.DisplayName(),
+		Role:             auth.RoleUser,
+		TelegramID:       &telegramID,
+		TelegramUsername: identity.Username,
+	}
+	if err := Repo.Account.CreateUser(user); err != nil {
+		// Два входа одновременно — аккаунт уже создал соседний запрос
+		if utils.IsDuplicateKeyError(err) {
+			return Repo.Account.GetUserByTelegramID(identity.ID)
+		}
+		return nil, err
+	}
+	return Repo.Account.GetUserByID(user.ID)
+}
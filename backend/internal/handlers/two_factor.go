@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/utils"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Двухфакторная аутентификация (TOTP) и коды восстановления.
+//
+// Включение (личный кабинет, нужен недавний ввод пароля):
+//   POST /account/2fa/setup  → секрет + otpauth-ссылка для QR
+//   POST /account/2fa/enable → код из приложения; в ответ коды восстановления
+//                              и новая пара токенов с mfa = true
+// Вход при включённой 2FA — в два шага:
+//   POST /auth/sign-in    → { mfaRequired, challenge }
+//   POST /auth/2fa/verify → challenge + код из приложения или код восстановления → токены
+// ═══════════════════════════════════════════════════════════════
+
+const (
+	purposeMFAChallenge = "mfa-challenge"
+	mfaChallengeTTL     = 5 * time.Minute
+	recoveryCodeCount   = 10
+	totpIssuer          = "SocialSh" // название в приложении-аутентификаторе
+)
+
+// VerifyMFA — второй шаг входа.
+// POST /api/auth/2fa/verify
+// Body: { "challenge": "<из ответа sign-in>", "code": "123456" | "abcde-fghij" }
+// Ответ 200: { "access": "<jwt>", "refresh": "<jwt>" }
+//
+// Логика:
+//  1. Проверяем challenge (подпись, срок 5 минут), из него — userID
+//  2. Засчитываем попытку; email или IP заблокированы за перебор → 429 (те же счётчики, что у sign-in)
+//  3. Проверяем код: TOTP или код восстановления. Неверный → попытка остаётся в счётчиках, 401
+//  4. Выдаём токены новой семьи с mfa = true
+func VerifyMFA(jwtSecret, refreshSecret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.MFAVerifyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "невалидный JSON"})
+		}
+		if req.Challenge == "" || req.Code == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "challenge и код обязательны"})
+		}
+
+		// 1. Challenge
+		claims, err := parsePurposeToken(jwtSecret, purposeMFAChallenge, req.Challenge)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "вход устарел, введите пароль заново"})
+		}
+		userID, _ := claims["sub"].(string)
+		user, err := Repo.Account.GetUserByID(userID)
+		if err != nil || user == nil || !user.TOTPEnabled {
+			return c.Status(401).JSON(fiber.Map{"error": "вход устарел, введите пароль заново"})
+		}
+		if user.BlockedAt != nil {
+			return c.Status(403).JSON(fiber.Map{"error": "аккаунт заблокирован"})
+		}
+
+		// 2. Перебор кодов
+		attempt, wait := startLoginAttempt(loginThrottleKeys(c, user.Email))
+		if wait > 0 {
+			return tooManyLoginAttempts(c, wait)
+		}
+
+		// 3. Код
+		ok, err := verifySecondFactor(user.ID, req.Code, true)
+		if err != nil {
+			log.Printf("2fa verify: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось проверить код"})
+		}
+		if !ok {
+			attempt.failed()
+			return c.Status(401).JSON(fiber.Map{"error": "неверный код"})
+		}
+		attempt.succeeded()
+
+		// 4. Токены
+		tokens, err := issueTokens(c, user, refreshSecret, true)
+		if err != nil {
+			log.Printf("2fa verify: %v", err)
+			return c.Status(500).JSON(fiber.Map{"error": "не удалось сгенерировать токены"})
+		}
+		return respondTokens(c, 200, tokens, nil)
+	}
+}
+
+// TwoFactorSetup — начать включение 2FA: новый секрет, пока не подтверждённый кодом.
+// POST /api/account/2fa/setup
+// Ответ: { "secret": "BASE32...", "uri": "otpauth://totp/..." } — uri рисуется QR-кодом,
+// secret — для ручного ввода.
+// Нужен недавний ввод пароля — иначе 403 с "reauthRequired": true (как у смены email).
+func TwoFactorSetup(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	if !recentlyAuthenticated(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":          "подтвердите пароль",
+			"reauthRequired": true,
+		})
+	}
+
+	user, err := Repo.Account.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "двухфакторная аутентификация уже включена",
+		})
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err == nil {
+		err = Repo.TwoFactor.SetPendingSecret(userID, secret)
+	}
+	if err != nil {
+		log.Printf("2fa setup: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось начать настройку 2FA",
+		})
+	}
+
+	// Подпись записи в приложении; у аккаунта из Telegram email нет — тогда имя
+	account := user.Email
+	if account == "" {
+		account = user.Name
+	}
+	return c.JSON(fiber.Map{
+		"secret": secret,
+		"uri":    auth.TOTPURI(totpIssuer, account, secret),
+	})
+}
+
+// TwoFactorEnable — подтвердить настройку кодом из приложения.
+// POST /api/account/2fa/enable
+// Body: { "code": "123456" }
+// Ответ: { "recoveryCodes": [...], "access": "<jwt>", "refresh": "<jwt>" }
+// (при входе по кукам токены — новыми куками, в теле recoveryCodes и csrfToken)
+// Коды восстановления показываются один раз. Новая пара токенов — с mfa = true,
+// чтобы сразу работала админка при REQUIRE_ADMIN_2FA; старый вход завершается.
+// Попытки ввода кода считаются вместе с попытками входа (см. login_throttle.go) → 429.
+func TwoFactorEnable(refreshSecret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+		sessionID, _ := c.Locals("sessionID").(string)
+
+		var req models.TwoFactorCodeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "невалидный JSON",
+			})
+		}
+
+		state, err := Repo.TwoFactor.Get(userID)
+		if err != nil {
+			log.Printf("2fa enable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось включить 2FA",
+			})
+		}
+		if state.Enabled {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "двухфакторная аутентификация уже включена",
+			})
+		}
+		if state.Secret == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "сначала начните настройку: POST /api/account/2fa/setup",
+			})
+		}
+
+		user, err := Repo.Account.GetUserByID(userID)
+		if err != nil {
+			log.Printf("2fa enable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось включить 2FA",
+			})
+		}
+		attempt, wait := startLoginAttempt(loginThrottleKeys(c, user.Email))
+		if wait > 0 {
+			return tooManyLoginAttempts(c, wait)
+		}
+
+		ok, err := verifySecondFactor(userID, req.Code, false)
+		if err != nil {
+			log.Printf("2fa enable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось проверить код",
+			})
+		}
+		if !ok {
+			attempt.failed()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "неверный код",
+			})
+		}
+		attempt.succeeded()
+
+		codes, hashes, err := newRecoveryCodes()
+		if err == nil {
+			err = Repo.TwoFactor.Enable(userID, hashes)
+		}
+		if err != nil {
+			log.Printf("2fa enable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось включить 2FA",
+			})
+		}
+
+		// Новый вход с mfa = true вместо текущего
+		tokens, err := issueTokens(c, user, refreshSecret, true)
+		if err != nil {
+			log.Printf("2fa enable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось сгенерировать токены",
+			})
+		}
+		if sessionID != "" {
+			if err := Repo.RefreshTokens.RevokeFamily(sessionID); err != nil {
+				log.Printf("2fa enable: %v", err)
+			}
+		}
+
+		return respondTokens(c, 200, tokens, fiber.Map{"recoveryCodes": codes})
+	}
+}
+
+// TwoFactorRecoveryCodes — выпустить новые коды восстановления (старые перестают работать).
+// POST /api/account/2fa/recovery-codes
+// Body: { "code": "123456" } — только код из приложения
+// Ответ: { "recoveryCodes": [...] }
+// Как и выключение, нужен недавний ввод пароля (403 с "reauthRequired"): коды — тот же
+// второй фактор, одного украденного access-токена и телефона мало.
+// Попытки ввода кода считаются вместе с попытками входа (см. login_throttle.go) → 429.
+func TwoFactorRecoveryCodes(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+
+	if !recentlyAuthenticated(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":          "подтвердите пароль",
+			"reauthRequired": true,
+		})
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "невалидный JSON",
+		})
+	}
+
+	user, err := Repo.Account.GetUserByID(userID)
+	if err != nil {
+		log.Printf("2fa recovery codes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось выпустить коды",
+		})
+	}
+	attempt, wait := startLoginAttempt(loginThrottleKeys(c, user.Email))
+	if wait > 0 {
+		return tooManyLoginAttempts(c, wait)
+	}
+
+	ok, err := verifySecondFactor(userID, req.Code, false)
+	if err != nil {
+		log.Printf("2fa recovery codes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось проверить код",
+		})
+	}
+	if !ok {
+		attempt.failed()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный код",
+		})
+	}
+	attempt.succeeded()
+
+	codes, hashes, err := newRecoveryCodes()
+	if err == nil {
+		err = Repo.TwoFactor.ReplaceRecoveryCodes(userID, hashes)
+	}
+	if err != nil {
+		log.Printf("2fa recovery codes: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "не удалось выпустить коды",
+		})
+	}
+
+	return c.JSON(fiber.Map{"recoveryCodes": codes})
+}
+
+// TwoFactorDisable — выключить 2FA.
+// POST /api/account/2fa/disable
+// Body: { "code": "123456" | "abcde-fghij" }
+// Ответ: { "message": "ok" }
+// Нужен недавний ввод пароля и код. При REQUIRE_ADMIN_2FA сотрудникам выключать нельзя — 403.
+// Попытки ввода кода считаются вместе с попытками входа (см. login_throttle.go) → 429.
+func TwoFactorDisable(requireForStaff bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+		role, _ := c.Locals("role").(string)
+
+		if requireForStaff && auth.IsStaff(role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "для сотрудников двухфакторная аутентификация обязательна",
+			})
+		}
+		if !recentlyAuthenticated(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":          "подтвердите пароль",
+				"reauthRequired": true,
+			})
+		}
+
+		var req models.TwoFactorCodeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "невалидный JSON",
+			})
+		}
+
+		user, err := Repo.Account.GetUserByID(userID)
+		if err != nil {
+			log.Printf("2fa disable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось выключить 2FA",
+			})
+		}
+		attempt, wait := startLoginAttempt(loginThrottleKeys(c, user.Email))
+		if wait > 0 {
+			return tooManyLoginAttempts(c, wait)
+		}
+
+		ok, err := verifySecondFactor(userID, req.Code, true)
+		if err != nil {
+			log.Printf("2fa disable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось проверить код",
+			})
+		}
+		if !ok {
+			attempt.failed()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "неверный код",
+			})
+		}
+		attempt.succeeded()
+
+		if err := Repo.TwoFactor.Disable(userID); err != nil {
+			log.Printf("2fa disable: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось выключить 2FA",
+			})
+		}
+
+		return c.JSON(fiber.Map{"message": "ok"})
+	}
+}
+
+// verifySecondFactor — проверить код: 6 цифр — TOTP (каждый шаг принимается один раз),
+// иначе, если allowRecovery, — код восстановления (гасится).
+// Секрет берётся и у ещё не включённой 2FA — так enable проверяет первый код.
+func verifySecondFactor(userID, code string, allowRecovery bool) (bool, error) {
+	state, err := Repo.TwoFactor.Get(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if state.Secret == "" {
+		return false, nil
+	}
+
+	if step, ok := auth.ValidateTOTP(state.Secret, code, time.Now()); ok {
+		return Repo.TwoFactor.UseStep(userID, step)
+	}
+	if allowRecovery && state.Enabled {
+		return Repo.TwoFactor.ConsumeRecoveryCode(userID, utils.HashToken(auth.NormalizeRecoveryCode(code)))
+	}
+	return false, nil
+}
+
+// newRecoveryCodes — коды для показа юзеру и их хеши для БД.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	codes, err = auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes = make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+	return codes, hashes, nil
+}
@@ -2,84 +2,166 @@ package middleware
 
 import (
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/repository"
 )
 
 // Protected — middleware для проверки JWT-токена.
-// Достаёт токен из заголовка Authorization: Bearer <token>,
-// валидирует его, и кладёт userID + role в c.Locals.
+// Достаёт токен из заголовка Authorization: Bearer <token>, а без заголовка —
+// из куки access_token (режим AUTH_MODE=cookie), валидирует его,
+// и кладёт userID + role в c.Locals.
 // Если токен невалидный или отсутствует — возвращает 401.
+// Сессию (claim sid) сверяем с БД: после logout или "выйти на других устройствах"
+// access-токен перестаёт работать сразу, а не через 15 минут.
+// Токен из куки на изменяющем запросе требует X-CSRF-Token (см. CSRF), иначе 403.
 //
 // Использование в routes:
 //
-//	api.Group("/account", middleware.Protected(cfg.JwtSecret))
-func Protected(jwtSecret string) fiber.Handler {
+//	api.Group("/account", middleware.Protected(handlers.Keys, handlers.Repo.RefreshTokens))
+func Protected(keys *auth.KeySet, sessions repository.RefreshTokenRepository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Достаём заголовок Authorization
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
+		// Достаём токен: заголовок Authorization или кука
+		token, via, errMsg := accessToken(c)
+		if errMsg != "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{ //401
-				"error": "отсутствует токен авторизации",
+				"error": errMsg,
 			})
 		}
 
-		// Ожидаем формат "Bearer <token>"
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{ //401
-				"error": "неверный формат токена, ожидается Bearer <token>",
-			})
+		// Куку браузер прикладывает и к запросам с чужих сайтов
+		if via == auth.ViaCookie && !csrfValid(c) {
+			return csrfRejected(c)
 		}
 
-		tokenString := parts[1]
-
-		// Парсим и валидируем JWT
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			// Проверяем метод подписи — только HMAC
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.NewError(fiber.StatusUnauthorized, "неверный метод подписи токена")
-			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		// Парсим и валидируем JWT (ключ — по kid из заголовка токена)
+		claims, err := keys.Parse(token)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "невалидный или просроченный токен",
 			})
 		}
 
-		// Достаём claims (userID и role)
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		// Сессия отозвана (logout, смена пароля, выход с другого устройства)
+		sessionID, _ := claims["sid"].(string)
+		active, err := sessionActive(sessions, sessionID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось проверить сессию",
+			})
+		}
+		if !active {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "не удалось прочитать claims из токена",
+				"error": "сессия завершена, войдите заново",
 			})
 		}
 
 		// Кладём данные в контекст — хендлеры достанут через c.Locals("userID")
-		userID, _ := claims["sub"].(string)
-		role, _ := claims["role"].(string)
-
-		c.Locals("userID", userID)
-		c.Locals("role", role)
+		setClaimsLocals(c, claims)
+		c.Locals("authVia", via)
 
 		// Передаём управление следующему хендлеру
 		return c.Next()
 	}
 }
 
-// AdminOnly — middleware, проверяющий что у юзера роль admin.
+// OptionalAuth — мягкий вариант Protected для публичных роутов.
+// Если пришёл валидный токен активной сессии (Bearer или кука) — кладёт userID + role в c.Locals,
+// если токена нет, он битый или сессия отозвана — просто пропускает запрос дальше как гостевой.
+// Исключение — токен из куки без X-CSRF-Token на изменяющем запросе: это 403, а не гость,
+// иначе чужой сайт оформлял бы заказы от имени юзера "как гость".
+//
+// Использование:
+//
+//	api.Post("/orders", middleware.OptionalAuth(handlers.Keys, handlers.Repo.RefreshTokens), handlers.CreateOrder)
+func OptionalAuth(keys *auth.KeySet, sessions repository.RefreshTokenRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, via, errMsg := accessToken(c)
+		if errMsg != "" {
+			return c.Next()
+		}
+		if via == auth.ViaCookie && !csrfValid(c) {
+			return csrfRejected(c)
+		}
+		if claims, err := keys.Parse(token); err == nil {
+			sessionID, _ := claims["sid"].(string)
+			if active, err := sessionActive(sessions, sessionID); err == nil && active {
+				setClaimsLocals(c, claims)
+				c.Locals("authVia", via)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// accessToken — access-токен запроса и откуда он взят (auth.ViaBearer / auth.ViaCookie).
+// Заголовок Authorization важнее куки: API-клиент с Bearer работает в любом режиме.
+// errMsg не пустой — токена нет или заголовок кривой.
+func accessToken(c *fiber.Ctx) (token, via, errMsg string) {
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		if cookie := c.Cookies(auth.AccessCookie); cookie != "" {
+			return cookie, auth.ViaCookie, ""
+		}
+		return "", "", "отсутствует токен авторизации"
+	}
+
+	// Ожидаем формат "Bearer <token>"
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", "", "неверный формат токена, ожидается Bearer <token>"
+	}
+	return parts[1], auth.ViaBearer, ""
+}
+
+// sessionActive — жива ли сессия sid. Токен без sid (выдан до появления сессий) — нет.
+func sessionActive(sessions repository.RefreshTokenRepository, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	return sessions.IsSessionActive(sessionID)
+}
+
+// setClaimsLocals — раскладывает claims токена (userID, role, sessionID, authTime, mfa) по c.Locals.
+// sessionID — семья refresh-токенов этого входа (claim sid), нужна для logout.
+// authTime — когда юзер последний раз вводил пароль (claim auth_time, time.Time;
+// нулевое время у токенов без claim — такие считаются "давно").
+// mfa — вход подтверждён вторым фактором (claim mfa).
+func setClaimsLocals(c *fiber.Ctx, claims jwt.MapClaims) {
+	userID, _ := claims["sub"].(string)
+	role, _ := claims["role"].(string)
+	sessionID, _ := claims["sid"].(string)
+
+	mfa, _ := claims["mfa"].(bool)
+
+	var authTime time.Time
+	if v, ok := claims["auth_time"].(float64); ok {
+		authTime = time.Unix(int64(v), 0)
+	}
+
+	c.Locals("userID", userID)
+	c.Locals("role", role)
+	c.Locals("sessionID", sessionID)
+	c.Locals("authTime", authTime)
+	c.Locals("mfa", mfa)
+}
+
+// AdminOnly — middleware, пускающий в админку только сотрудников:
+// роли, у которых есть хоть одно право (см. auth.Permissions).
 // Вешается ПОСЛЕ Protected(), чтобы role уже лежал в c.Locals.
+// Что именно можно делать, проверяет RequirePermission на каждом роуте.
 //
 // Использование:
 //
-//	api.Group("/admin", middleware.Protected(secret), middleware.AdminOnly())
+//	api.Group("/admin", middleware.Protected(keys, sessions), middleware.AdminOnly())
 func AdminOnly() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		role, _ := c.Locals("role").(string)
-		if role != "admin" {
+		if !auth.IsStaff(role) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "доступ только для администраторов",
 			})
@@ -87,3 +169,66 @@ func AdminOnly() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// RequirePermission — пускает дальше, только если у роли юзера есть право perm.
+// Вешается ПОСЛЕ Protected(), на конкретный админский роут.
+//
+// Использование:
+//
+//	adm.Delete("/products/:id", middleware.RequirePermission(auth.ProductsWrite), handlers.AdminDeleteProduct)
+func RequirePermission(perm auth.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if !auth.Has(role, perm) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":      "недостаточно прав",
+				"permission": perm,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireMFA — пускает дальше только с токеном входа, подтверждённого вторым фактором.
+// Вешается ПОСЛЕ Protected() на админку, если REQUIRE_ADMIN_2FA=true.
+// Без 2FA — 403 с "mfaSetupRequired": true: фронт отправляет юзера в кабинет
+// включить 2FA (включение сразу выдаёт токены с mfa).
+func RequireMFA() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if mfa, _ := c.Locals("mfa").(bool); !mfa {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":            "для админки нужна двухфакторная аутентификация",
+				"mfaSetupRequired": true,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireVerifiedEmail — пускает дальше только юзеров с подтверждённым email.
+// Вешается ПОСЛЕ Protected/OptionalAuth. Гостей (userID пустой) не трогает —
+// для них проверка не имеет смысла, email гостя и так есть только в заказе.
+// Статус берём из БД, а не из токена: после подтверждения он должен работать сразу.
+//
+// Какие фичи закрыты, задаёт REQUIRE_VERIFIED_EMAIL_FOR (см. routes.Register).
+func RequireVerifiedEmail(users repository.AccountRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userID").(string)
+		if userID == "" {
+			return c.Next()
+		}
+
+		user, err := users.GetUserByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "пользователь не найден",
+			})
+		}
+		if !user.EmailVerified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "подтвердите email — ссылка в письме после регистрации",
+			})
+		}
+		return c.Next()
+	}
+}
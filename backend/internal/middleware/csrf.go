@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/auth"
+)
+
+// CSRF — double-submit проверка для запросов, аутентифицированных куками (AUTH_MODE=cookie).
+// Изменяющий запрос (POST, PUT, PATCH, DELETE), к которому браузер приложил куки сессии,
+// должен нести заголовок X-CSRF-Token, равный куке csrf_token. Иначе — 403.
+// Запросы с Authorization: Bearer и запросы без кук сессии пропускает как есть.
+//
+// Protected и OptionalAuth делают ту же проверку сами, когда берут токен из куки.
+// Отдельно middleware нужен там, где кука читается без них — на /auth/refresh.
+//
+// Использование:
+//
+//	a.Post("/refresh", middleware.CSRF(), handlers.RefreshToken(cfg.RefreshSecret))
+func CSRF() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") != "" {
+			return c.Next()
+		}
+		if c.Cookies(auth.AccessCookie) == "" && c.Cookies(auth.RefreshCookie) == "" {
+			return c.Next()
+		}
+		if !csrfValid(c) {
+			return csrfRejected(c)
+		}
+		return c.Next()
+	}
+}
+
+// csrfValid — безопасный метод или заголовок X-CSRF-Token совпал с кукой csrf_token.
+func csrfValid(c *fiber.Ctx) bool {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	cookie := c.Cookies(auth.CSRFCookie)
+	header := c.Get(auth.CSRFHeader)
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
+
+// csrfRejected — 403 с "csrfInvalid": фронт по нему понимает, что сессия жива,
+// а потерян CSRF-токен (например, почистили localStorage), — нужно войти заново.
+func csrfRejected(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":       "CSRF-токен отсутствует или не совпадает",
+		"csrfInvalid": true,
+	})
+}
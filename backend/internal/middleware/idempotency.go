@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+)
+
+// IdempotencyHeader — заголовок с ключом идемпотентности от клиента.
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyResourceLocal — ключ c.Locals, куда хендлер за IdempotencyReplay
+// кладёт ID созданного объекта (строкой).
+const IdempotencyResourceLocal = "idempotencyResource"
+
+// Idempotency — middleware для POST-запросов с заголовком Idempotency-Key.
+// Без заголовка запрос проходит как обычно.
+//
+// Как читается:
+//  1. Ключ берём из заголовка, скоуп — userID из c.Locals (пусто для анонимов),
+//     поэтому ставить middleware нужно ПОСЛЕ Protected/OptionalAuth.
+//  2. Хешируем метод + путь + тело и пытаемся занять ключ (store.Begin).
+//  3. Ключ уже был:
+//     → хеш другой — тот же ключ с другим запросом, 422;
+//     → запрос ещё выполняется — 409, клиенту стоит повторить позже;
+//     → выполнен — отдаём сохранённый ответ как есть (+ заголовок Idempotent-Replayed).
+//  4. Ключ наш — выполняем хендлер. 5xx или ошибка — освобождаем ключ,
+//     чтобы повтор выполнился заново; иначе сохраняем ответ (включая 4xx —
+//     тот же запрос получит ту же ошибку валидации).
+//     Ответ с Set-Cookie тоже не сохраняем: куки при повторе не воспроизвести,
+//     а в них (как и в ответах входа) бывают токены, которым не место в БД.
+//     Поэтому на роуты, выдающие токены (sign-up и т.п.), ставим IdempotencyReplay.
+//
+// Использование в routes:
+//
+//	api.Post("/orders", middleware.OptionalAuth(handlers.Keys, handlers.Repo.RefreshTokens), idem, handlers.CreateOrder)
+func Idempotency(store repository.IdempotencyRepository) fiber.Handler {
+	return idempotency(store, nil)
+}
+
+// IdempotencyReplay — Idempotency для роутов, чей ответ хранить нельзя (sign-up выдаёт токены).
+//
+// Как читается:
+//   - Хендлер положил в c.Locals(IdempotencyResourceLocal) ID созданного объекта →
+//     сохраняем только его (CompleteResource), при любом статусе ответа:
+//     объект уже создан, и повтор не должен создавать его заново.
+//   - Повтор с тем же ключом и телом → replay(c, resourceID) собирает свежий ответ
+//     (для sign-up — новая сессия того же юзера) + заголовок Idempotent-Replayed.
+//   - Объекта нет (ошибка валидации и т.п.) — всё как в Idempotency.
+//
+// Использование в routes:
+//
+//	a.Post("/sign-up", middleware.IdempotencyReplay(handlers.Repo.Idempotency, handlers.SignUpReplay(refreshSecret)), handlers.SignUp(...))
+func IdempotencyReplay(store repository.IdempotencyRepository, replay func(c *fiber.Ctx, resourceID string) error) fiber.Handler {
+	return idempotency(store, replay)
+}
+
+// idempotency — общая часть Idempotency и IdempotencyReplay (replay == nil — без повтора по ID).
+func idempotency(store repository.IdempotencyRepository, replay func(c *fiber.Ctx, resourceID string) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > 255 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Idempotency-Key слишком длинный (максимум 255 символов)",
+			})
+		}
+
+		// 1-2. Скоуп и хеш запроса
+		scope, _ := c.Locals("userID").(string)
+		hash := sha256.New()
+		hash.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+		hash.Write(c.Body())
+
+		rec := &models.IdempotencyRecord{
+			Scope:       scope,
+			Key:         key,
+			Method:      c.Method(),
+			Path:        c.Path(),
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+		}
+		existing, err := store.Begin(rec)
+		if err != nil {
+			log.Printf("idempotency: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "не удалось проверить Idempotency-Key",
+			})
+		}
+
+		// 3. Повтор
+		if existing != nil {
+			if existing.RequestHash != rec.RequestHash {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"error": "Idempotency-Key уже использован с другим запросом",
+				})
+			}
+			if existing.Status != models.IdempotencyCompleted {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "запрос с этим Idempotency-Key ещё выполняется",
+				})
+			}
+
+			c.Set("Idempotent-Replayed", "true")
+			if existing.ResourceID != "" && replay != nil {
+				return replay(c, existing.ResourceID)
+			}
+			if existing.ContentType != "" {
+				c.Set(fiber.HeaderContentType, existing.ContentType)
+			}
+			return c.Status(existing.ResponseStatus).Send(existing.ResponseBody)
+		}
+
+		// 4. Первый запрос с этим ключом
+		if err := c.Next(); err != nil {
+			if delErr := store.Delete(scope, key); delErr != nil {
+				log.Printf("idempotency: %v", delErr)
+			}
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if resourceID, _ := c.Locals(IdempotencyResourceLocal).(string); replay != nil && resourceID != "" {
+			if err := store.CompleteResource(scope, key, status, resourceID); err != nil {
+				log.Printf("idempotency: %v", err)
+				if delErr := store.Delete(scope, key); delErr != nil {
+					log.Printf("idempotency: %v", delErr)
+				}
+			}
+			return nil
+		}
+		if status >= fiber.StatusInternalServerError || setsCookies(c) {
+			if err := store.Delete(scope, key); err != nil {
+				log.Printf("idempotency: %v", err)
+			}
+			return nil
+		}
+
+		// Body() — буфер fasthttp, переиспользуется после ответа, поэтому копируем
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		if err := store.Complete(scope, key, status, contentType, body); err != nil {
+			// Ответ уже готов — не ломаем его, повтор просто выполнится заново
+			log.Printf("idempotency: %v", err)
+			if delErr := store.Delete(scope, key); delErr != nil {
+				log.Printf("idempotency: %v", delErr)
+			}
+		}
+		return nil
+	}
+}
+
+// setsCookies — ставит ли ответ хоть одну куку.
+func setsCookies(c *fiber.Ctx) bool {
+	found := false
+	c.Response().Header.VisitAllCookie(func(_, _ []byte) { found = true })
+	return found
+}
@@ -15,6 +15,26 @@ type Product struct {
 	Images      []string `json:"images"      db:"images"` // в Postgres — jsonb
 	IsNew       bool     `json:"isNew"       db:"is_new"`
 	IsOnSale    bool     `json:"isOnSale"    db:"is_on_sale"`
+	Stock       int      `json:"stock"       db:"stock"`        // всего на складе
+	Reserved    int      `json:"reserved"    db:"reserved"`     // зарезервировано неотгруженными заказами
+	InStock     bool     `json:"inStock"     db:"-"`            // stock - reserved > 0, считается при чтении
+	WeightGrams int      `json:"weightGrams" db:"weight_grams"` // вес для расчёта доставки
+
+	// Variants — размеры/цвета. Если у товара есть варианты, склад и цена
+	// ведутся по ним, а Stock/Reserved самого товара не используются.
+	Variants []ProductVariant `json:"variants" db:"-"` // подгружаем из product_variants
+}
+
+// ProductVariant — вариант товара (размер, цвет) со своим SKU, ценой и остатком.
+type ProductVariant struct {
+	ID        string            `json:"id"        db:"id"`
+	ProductID string            `json:"productId" db:"product_id"`
+	SKU       string            `json:"sku"       db:"sku"`
+	Options   map[string]string `json:"options"   db:"options"` // {"size": "M", "color": "black"}, в Postgres — jsonb
+	Price     *int64            `json:"price"     db:"price"`   // nil = цена товара
+	Stock     int               `json:"stock"     db:"stock"`
+	Reserved  int               `json:"reserved"  db:"reserved"`
+	InStock   bool              `json:"inStock"   db:"-"`
 }
 
 // GalleryItem — элемент галереи (фото, кадр и т.п.).
@@ -35,24 +55,166 @@ type Page struct {
 
 // User — аккаунт пользователя.
 type User struct {
-	ID           string `json:"id"            db:"id"`
-	Email        string `json:"email"         db:"email"`
-	Name         string `json:"name"          db:"name"`
-	PasswordHash string `json:"-"             db:"password_hash"` // json:"-" — не отдаём наружу
-	Role         string `json:"role"          db:"role"`          // "user" или "admin"
+	ID               string     `json:"id"                         db:"id"`
+	Email            string     `json:"email"                      db:"email"` // пусто — аккаунт создан входом через Telegram
+	Name             string     `json:"name"                       db:"name"`
+	PasswordHash     string     `json:"-"                          db:"password_hash"`     // json:"-" — не отдаём наружу; пусто — пароля нет
+	Role             string     `json:"role"                       db:"role"`              // "user" или админская роль (см. пакет auth)
+	EmailVerified    bool       `json:"emailVerified"              db:"email_verified"`    // перешёл по ссылке из письма подтверждения
+	BlockedAt        *time.Time `json:"blockedAt,omitempty"        db:"blocked_at"`        // заблокирован админом — вход и рефреш запрещены
+	TOTPEnabled      bool       `json:"totpEnabled"                db:"totp_enabled"`      // включена двухфакторная аутентификация
+	TelegramID       *int64     `json:"telegramId,omitempty"       db:"telegram_id"`       // привязанный Telegram (вход через виджет)
+	TelegramUsername string     `json:"telegramUsername,omitempty" db:"telegram_username"` // @username на момент последнего входа
+}
+
+// AdminUser — юзер в админке: профиль + дата регистрации и число заказов.
+type AdminUser struct {
+	User
+	CreatedAt  time.Time `json:"createdAt"  db:"created_at"`
+	OrderCount int       `json:"orderCount" db:"order_count"`
+}
+
+// UserFilter — поиск пользователей в админке. Нулевые значения = фильтр не применяется.
+type UserFilter struct {
+	Query string // подстрока email/имени
+	Role  string
+	Page  int
+	Limit int
+}
+
+// UpdateUserRoleRequest — смена роли из админки.
+type UpdateUserRoleRequest struct {
+	Role string `json:"role"`
 }
 
 // Order — заказ пользователя.
 // В БД таблица orders хранит мета-инфу (кто, когда, статус, сумма).
 // Позиции заказа лежат в отдельной таблице order_items (связь 1:N через order_id).
+//
+// UserID пустой у гостевых заказов — тогда покупатель известен только по Customer.
 type Order struct {
-	ID        string      `json:"id" db:"id"`
-	UserID    string      `json:"userId" db:"user_id"`
-	Status    string      `json:"status" db:"status"` // pending | paid | shipped | delivered | cancelled
-	Total     int64       `json:"total" db:"total"`   // итого в копейках (4990 = 49.90 ₽)
+	ID       string        `json:"id" db:"id"`
+	UserID   string        `json:"userId" db:"user_id"`
+	Status   string        `json:"status" db:"status"` // pending | paid | shipped | delivered | cancelled
+	Total    int64         `json:"total" db:"total"`   // итого в копейках (4990 = 49.90 ₽)
+	Customer OrderCustomer `json:"customer" db:"-"`    // колонки customer_* в orders
+
+	// Скидка: Total = Subtotal - DiscountAmount
+	Subtotal       int64  `json:"subtotal" db:"subtotal"` // сумма позиций до скидки
+	DiscountCode   string `json:"discountCode,omitempty" db:"discount_code"`
+	DiscountAmount int64  `json:"discountAmount" db:"discount_amount"`
+	DiscountID     string `json:"-" db:"-"` // только для Create — по нему пишется discount_redemptions
+
+	// Доставка: Total = Subtotal - DiscountAmount + ShippingCost
+	ShippingMethodID string `json:"shippingMethodId,omitempty" db:"shipping_method_id"`
+	ShippingMethod   string `json:"shippingMethod,omitempty" db:"shipping_method"` // название НА МОМЕНТ заказа
+	ShippingZone     string `json:"shippingZone,omitempty" db:"shipping_zone"`
+	ShippingCost     int64  `json:"shippingCost" db:"shipping_cost"`
+
+	Comment   string      `json:"comment,omitempty" db:"comment"`
 	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time   `json:"updatedAt" db:"updated_at"`
 	Items     []OrderItem `json:"items" db:"-"` // db:"-" — не колонка, подгружаем отдельным запросом
+
+	History []OrderStatusChange `json:"history" db:"-"` // таймлайн статусов из order_status_history
+}
+
+// OrderCustomer — контакты покупателя, сохранённые вместе с заказом.
+// Лежат в колонках customer_* таблицы orders.
+type OrderCustomer struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone,omitempty"`
+	Telegram string `json:"telegram,omitempty"`
+	Address  string `json:"address,omitempty"`
+}
+
+// Discount — промокод.
+//
+// Kind = "percent": Value — процент (1..100) от суммы подходящих позиций.
+// Kind = "fixed":   Value — фиксированная сумма в копейках (не больше суммы подходящих позиций).
+// ProductIDs пустой — скидка на всю корзину, иначе только на эти товары.
+// nil в лимитах и датах = ограничения нет.
+type Discount struct {
+	ID               string     `json:"id"               db:"id"`
+	Code             string     `json:"code"             db:"code"` // хранится в верхнем регистре
+	Kind             string     `json:"kind"             db:"kind"` // percent | fixed
+	Value            int64      `json:"value"            db:"value"`
+	MinOrderTotal    int64      `json:"minOrderTotal"    db:"min_order_total"` // в копейках, 0 = без минимума
+	StartsAt         *time.Time `json:"startsAt"         db:"starts_at"`
+	EndsAt           *time.Time `json:"endsAt"           db:"ends_at"`
+	UsageLimit       *int       `json:"usageLimit"       db:"usage_limit"`        // всего применений
+	PerCustomerLimit *int       `json:"perCustomerLimit" db:"per_customer_limit"` // применений на один email
+	ProductIDs       []string   `json:"productIds"       db:"product_ids"`        // в Postgres — jsonb
+	Active           bool       `json:"active"           db:"active"`
+	UsedCount        int        `json:"usedCount"        db:"used_count"`
+	CreatedAt        time.Time  `json:"createdAt"        db:"created_at"`
+}
+
+// ShippingMethod — способ доставки (курьер, пункт выдачи, почта).
+//
+// Стоимость считается по Rules: подходящее правило — с зоной заказа
+// (или "*" — любая зона) и весом не больше MaxWeightGrams (0 — без ограничения).
+// Если подошло несколько — берётся правило с конкретной зоной, затем с меньшим весом.
+// FreeFrom — сумма товаров (до скидки), начиная с которой доставка бесплатна.
+type ShippingMethod struct {
+	ID        string         `json:"id"        db:"id"`
+	Code      string         `json:"code"      db:"code"` // courier, pickup, post, ...
+	Title     string         `json:"title"     db:"title"`
+	Kind      string         `json:"kind"      db:"kind"`      // courier | pickup | post
+	Rules     []ShippingRule `json:"rules"     db:"rules"`     // в Postgres — jsonb
+	FreeFrom  *int64         `json:"freeFrom"  db:"free_from"` // в копейках, nil — бесплатной нет
+	Active    bool           `json:"active"    db:"active"`
+	SortOrder int            `json:"sortOrder" db:"sort_order"`
+}
+
+// ShippingRule — цена доставки для зоны и веса.
+type ShippingRule struct {
+	Zone           string `json:"zone"`           // "moscow", "russia", ... или "*"
+	MaxWeightGrams int    `json:"maxWeightGrams"` // 0 — без ограничения
+	Price          int64  `json:"price"`          // в копейках
+}
+
+// Типы способов доставки.
+const (
+	ShippingKindCourier = "courier"
+	ShippingKindPickup  = "pickup"
+	ShippingKindPost    = "post"
+)
+
+// Типы скидок.
+const (
+	DiscountKindPercent = "percent"
+	DiscountKindFixed   = "fixed"
+)
+
+// Статусы заказа.
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+)
+
+// OrderTransitions — разрешённые переходы статуса заказа (откуда → куда).
+// Всё, чего нет в таблице, запрещено. delivered и cancelled — финальные.
+var OrderTransitions = map[string][]string{
+	OrderStatusPending: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:    {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped: {OrderStatusDelivered},
+}
+
+// OrderStatusChange — одна запись истории статусов заказа.
+// ChangedBy — ID админа; пустой, если статус поменяла система (создание заказа и т.п.).
+type OrderStatusChange struct {
+	ID         string    `json:"id" db:"id"`
+	OrderID    string    `json:"orderId" db:"order_id"`
+	FromStatus string    `json:"fromStatus" db:"from_status"` // пустой у первой записи (создание заказа)
+	ToStatus   string    `json:"toStatus" db:"to_status"`
+	ChangedBy  string    `json:"changedBy,omitempty" db:"changed_by"`
+	Comment    string    `json:"comment,omitempty" db:"comment"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
 }
 
 // OrderItem — одна позиция в заказе (какой товар, сколько штук, по какой цене).
@@ -64,8 +226,166 @@ type OrderItem struct {
 	Title     string `json:"title" db:"title"`       // название товара НА МОМЕНТ покупки (не ссылка)
 	Price     int64  `json:"price" db:"price"`       // цена за 1 шт. на момент покупки
 	Quantity  int    `json:"quantity" db:"quantity"` // количество
+
+	// Вариант (если у товара они есть) — тоже снапшот на момент покупки
+	VariantID string            `json:"variantId,omitempty" db:"variant_id"`
+	SKU       string            `json:"sku,omitempty" db:"sku"`
+	Options   map[string]string `json:"options,omitempty" db:"options"`
+
+	WeightGrams int `json:"-" db:"-"` // вес одной штуки из каталога, только для расчёта доставки
+}
+
+// Payment — онлайн-платёж по заказу через провайдера (см. пакет payments).
+// На один заказ может быть несколько платежей (покупатель закрыл страницу оплаты
+// и начал заново) — заказ становится paid по первому успешному.
+type Payment struct {
+	ID                string    `json:"id"                db:"id"`
+	OrderID           string    `json:"orderId"           db:"order_id"`
+	Provider          string    `json:"provider"          db:"provider"`
+	ProviderPaymentID string    `json:"providerPaymentId" db:"provider_payment_id"`
+	Status            string    `json:"status"            db:"status"` // pending | succeeded | canceled
+	Amount            int64     `json:"amount"            db:"amount"` // в копейках
+	Currency          string    `json:"currency"          db:"currency"`
+	ConfirmationURL   string    `json:"confirmationUrl"   db:"confirmation_url"`
+	CreatedAt         time.Time `json:"createdAt"         db:"created_at"`
+	UpdatedAt         time.Time `json:"updatedAt"         db:"updated_at"`
+}
+
+// IdempotencyRecord — сохранённый результат запроса с заголовком Idempotency-Key.
+// Повтор с тем же ключом и телом получает этот ответ, а не выполняется заново.
+type IdempotencyRecord struct {
+	Scope          string    `json:"scope"          db:"scope"` // userID или "" для анонимов
+	Key            string    `json:"key"            db:"key"`
+	Method         string    `json:"method"         db:"method"`
+	Path           string    `json:"path"           db:"path"`
+	RequestHash    string    `json:"requestHash"    db:"request_hash"` // sha256 метода, пути и тела
+	Status         string    `json:"status"         db:"status"`       // in_progress | completed
+	ResponseStatus int       `json:"responseStatus" db:"response_status"`
+	ContentType    string    `json:"contentType"    db:"content_type"`
+	ResponseBody   []byte    `json:"-"              db:"response_body"`
+	ResourceID     string    `json:"resourceId"     db:"resource_id"` // вместо ответа, если его нельзя хранить (см. middleware.IdempotencyReplay)
+	CreatedAt      time.Time `json:"createdAt"      db:"created_at"`
+}
+
+// Статусы IdempotencyRecord.
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+// Notification — запись outbox-таблицы уведомлений.
+// Одна запись = одно сообщение в один канал (telegram, email),
+// чтобы ретраи каналов шли независимо.
+type Notification struct {
+	ID            string    `json:"id"            db:"id"`
+	Channel       string    `json:"channel"       db:"channel"`
+	Subject       string    `json:"subject"       db:"subject"`
+	Body          string    `json:"body"          db:"body"`
+	Status        string    `json:"status"        db:"status"` // pending | sent | failed
+	Attempts      int       `json:"attempts"      db:"attempts"`
+	LastError     string    `json:"lastError"     db:"last_error"`
+	NextAttemptAt time.Time `json:"nextAttemptAt" db:"next_attempt_at"`
+	CreatedAt     time.Time `json:"createdAt"     db:"created_at"`
+}
+
+// Статусы уведомления в outbox.
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed" // ретраи кончились
+)
+
+// RefreshToken — выданный refresh-токен (в БД лежит только sha256 от него).
+// Каждый /auth/refresh выдаёт новый токен той же семьи (FamilyID) и помечает
+// старый как rotated. Семья = один вход: логин порождает её, logout отзывает целиком.
+type RefreshToken struct {
+	ID         string     `json:"id"         db:"id"` // jti из JWT
+	FamilyID   string     `json:"familyId"   db:"family_id"`
+	UserID     string     `json:"userId"     db:"user_id"`
+	TokenHash  string     `json:"-"          db:"token_hash"`
+	UserAgent  string     `json:"userAgent"  db:"user_agent"`
+	IP         string     `json:"ip"         db:"ip"`
+	AuthTime   time.Time  `json:"authTime"   db:"auth_time"` // когда юзер последний раз вводил пароль в этой семье
+	MFA        bool       `json:"mfa"        db:"mfa"`       // вход подтверждён вторым фактором (TOTP/код восстановления)
+	ExpiresAt  time.Time  `json:"expiresAt"  db:"expires_at"`
+	RotatedAt  *time.Time `json:"rotatedAt"  db:"rotated_at"` // nil — токен ещё актуален
+	RevokedAt  *time.Time `json:"revokedAt"  db:"revoked_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"createdAt"  db:"created_at"`
 }
 
+// PasswordResetToken — одноразовый токен сброса пароля из письма.
+// В БД лежит только sha256 от токена; UsedAt != nil — токен уже использован.
+type PasswordResetToken struct {
+	ID        string     `json:"id"        db:"id"`
+	UserID    string     `json:"userId"    db:"user_id"`
+	TokenHash string     `json:"-"         db:"token_hash"`
+	IP        string     `json:"ip"        db:"ip"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	UsedAt    *time.Time `json:"usedAt"    db:"used_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// MagicLink — одноразовая ссылка для входа без пароля. ID — jti подписанного токена из письма.
+type MagicLink struct {
+	ID        string     `json:"id"        db:"id"`
+	UserID    string     `json:"userId"    db:"user_id"`
+	NonceHash string     `json:"-"         db:"nonce_hash"` // sha256 nonce браузера, запросившего ссылку
+	IP        string     `json:"ip"        db:"ip"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	UsedAt    *time.Time `json:"usedAt"    db:"used_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// Session — активный вход пользователя (семья refresh-токенов) для раздела "Устройства".
+// Устройство и IP — из последнего выданного токена семьи.
+type Session struct {
+	ID         string    `json:"id"         db:"family_id"` // = sid в access-токене
+	UserAgent  string    `json:"userAgent"  db:"user_agent"`
+	IP         string    `json:"ip"         db:"ip"`
+	SignedInAt time.Time `json:"signedInAt" db:"signed_in_at"` // первый токен семьи — момент логина
+	LastUsedAt time.Time `json:"lastUsedAt" db:"last_used_at"` // последний рефреш
+	ExpiresAt  time.Time `json:"expiresAt"  db:"expires_at"`
+	Current    bool      `json:"current"    db:"-"` // сессия, из которой пришёл запрос
+}
+
+// PersonalDataExport — всё, что магазин хранит о юзере (152-ФЗ / GDPR, запрос на доступ).
+// Отдаётся JSON-ом или ZIP-архивом с файлом на раздел (см. handlers/personal_data.go).
+type PersonalDataExport struct {
+	ExportedAt time.Time `json:"exportedAt"`
+	Profile    AdminUser `json:"profile"`   // профиль + дата регистрации
+	Orders     []Order   `json:"orders"`    // с контактами, позициями и историей статусов
+	Addresses  []string  `json:"addresses"` // адреса доставки из заказов, без повторов
+	Sessions   []Session `json:"sessions"`  // активные входы: устройство и IP
+}
+
+// TwoFactorState — TOTP юзера. Secret есть, а Enabled = false — настройка начата,
+// но код из приложения ещё не подтверждён.
+type TwoFactorState struct {
+	Secret   string `db:"totp_secret"`
+	Enabled  bool   `db:"totp_enabled"`
+	LastStep int64  `db:"totp_last_step"` // последний принятый шаг TOTP — повтор того же кода не пройдёт
+}
+
+// LoginThrottle — счётчик неудачных попыток входа по email или по IP.
+// Живёт в Postgres, чтобы блокировка действовала на всех инстансах сразу.
+type LoginThrottle struct {
+	ID            string     `json:"id"            db:"id"`
+	Kind          string     `json:"kind"          db:"kind"`    // email | ip | magic-link-ip
+	Subject       string     `json:"subject"       db:"subject"` // email в нижнем регистре или IP
+	Failures      int        `json:"failures"      db:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"lockedUntil"   db:"locked_until"` // nil или в прошлом — не заблокирован
+}
+
+// Виды ключей LoginThrottle.
+const (
+	LoginThrottleEmail = "email"
+	LoginThrottleIP    = "ip"
+	// Запросы ссылок для входа с одного IP (не неудачи, а сами запросы — защита от спама письмами)
+	LoginThrottleMagicLinkIP = "magic-link-ip"
+)
+
 // ──── Request/Response DTO для auth ────
 
 // SignUpRequest — тело запроса на регистрацию.
@@ -86,6 +406,57 @@ type RefreshRequest struct {
 	Refresh string `json:"refresh"`
 }
 
+// ForgotPasswordRequest — запрос письма со ссылкой для сброса пароля.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// MagicLinkRequest — запрос ссылки для входа без пароля.
+// Nonce — случайная строка, которую браузер сохранил у себя: без неё ссылка не сработает.
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+	Nonce string `json:"nonce"`
+}
+
+// MagicLinkVerifyRequest — обмен токена из письма на пару токенов (тем же браузером).
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token"`
+	Nonce string `json:"nonce"`
+}
+
+// TwoFactorCodeRequest — код из приложения (или код восстановления, где он допустим).
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAVerifyRequest — второй шаг входа: challenge из ответа sign-in + код.
+type MFAVerifyRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// ChangePasswordRequest — смена пароля из личного кабинета.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// ReauthRequest — повторный ввод пароля перед чувствительным действием.
+type ReauthRequest struct {
+	Password string `json:"password"`
+}
+
+// ConfirmEmailRequest — токен из ссылки подтверждения email.
+type ConfirmEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ResetPasswordRequest — новый пароль по токену из письма.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
 // TokenResponse — ответ с JWT-токенами.
 type TokenResponse struct {
 	Access  string `json:"access"`
@@ -98,3 +469,33 @@ type UpdateProfileRequest struct {
 	Name  *string `json:"name"`
 	Email *string `json:"email"`
 }
+
+// UpdateStockRequest — тело запроса на изменение остатка товара.
+type UpdateStockRequest struct {
+	Stock int `json:"stock"`
+}
+
+// CreatePaymentRequest — запрос на оплату заказа.
+type CreatePaymentRequest struct {
+	OrderID  string `json:"orderId"`
+	Provider string `json:"provider,omitempty"` // пусто — провайдер по умолчанию
+}
+
+// ──── Request DTO для админки заказов ────
+
+// OrderFilter — фильтры списка заказов в админке.
+// Нулевые значения = фильтр не применяется.
+type OrderFilter struct {
+	Status   string
+	From     *time.Time // created_at >= From
+	To       *time.Time // created_at < To
+	Customer string     // подстрока email/имени покупателя
+	Page     int
+	Limit    int
+}
+
+// UpdateOrderStatusRequest — тело запроса на смену статуса заказа.
+type UpdateOrderStatusRequest struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"log"
+	"strings"
+
+	"socialsh/backend/internal/config"
+)
+
+// FromConfig собирает каналы по конфигу.
+//
+// NOTIFY_CHANNELS пустой — включаем все каналы, для которых заданы настройки
+// (Telegram: токен + чат, email: SMTP_HOST + ORDER_EMAIL).
+// NOTIFY_CHANNELS="telegram" / "email" / "telegram,email" — только перечисленные;
+// если у перечисленного канала нет настроек, пишем в лог и пропускаем.
+func FromConfig(cfg *config.Config) []Notifier {
+	telegramReady := cfg.TelegramBotToken != "" && cfg.TelegramChatID != ""
+	emailReady := cfg.SMTPHost != "" && cfg.OrderEmail != ""
+
+	wanted := map[string]bool{}
+	if cfg.NotifyChannels == "" {
+		wanted["telegram"] = telegramReady
+		wanted["email"] = emailReady
+	} else {
+		for _, name := range strings.Split(cfg.NotifyChannels, ",") {
+			wanted[strings.TrimSpace(name)] = true
+		}
+	}
+
+	var notifiers []Notifier
+	if wanted["telegram"] {
+		if telegramReady {
+			notifiers = append(notifiers, NewTelegram(cfg.TelegramBotToken, cfg.TelegramChatID))
+		} else {
+			log.Printf("notify: канал telegram включён, но TELEGRAM_BOT_TOKEN/TELEGRAM_CHAT_ID не заданы")
+		}
+	}
+	if wanted["email"] {
+		if emailReady {
+			notifiers = append(notifiers, NewSMTP(cfg, splitList(cfg.OrderEmail)))
+		} else {
+			log.Printf("notify: канал email включён, но SMTP_HOST/ORDER_EMAIL не заданы")
+		}
+	}
+	return notifiers
+}
+
+// MailerFromConfig — отправка писем покупателям: SMTP, если задан SMTP_HOST,
+// иначе LogMailer (письма в лог — для локальной разработки).
+func MailerFromConfig(cfg *config.Config) Mailer {
+	if cfg.SMTPHost == "" {
+		log.Printf("notify: SMTP_HOST не задан, письма покупателям будут только в логе")
+		return LogMailer{}
+	}
+	return NewSMTP(cfg, nil)
+}
+
+// NewSMTP — SMTP-канал из конфига. to — адреса для Notifier.Send (для Mailer можно nil).
+func NewSMTP(cfg *config.Config, to []string) *SMTP {
+	return &SMTP{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+		To:       to,
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
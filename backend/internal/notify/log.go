@@ -0,0 +1,16 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer — Mailer для разработки без SMTP: письмо печатается в лог сервера
+// (ссылку сброса пароля и т.п. можно скопировать оттуда).
+// В проде не использовать — в логе окажутся одноразовые ссылки.
+type LogMailer struct{}
+
+func (LogMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	log.Printf("mail (SMTP не настроен) → %s: %s\n%s", to, subject, body)
+	return nil
+}
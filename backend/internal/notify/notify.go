@@ -0,0 +1,30 @@
+// Package notify — уведомления магазина (о новых заказах и т.п.).
+//
+// Каналы (Telegram, SMTP) реализуют интерфейс Notifier.
+// Хендлеры не шлют уведомления напрямую: они кладут их в outbox-таблицу
+// через Outbox.Enqueue, а фоновый воркер (Outbox.Run) отправляет
+// с ретраями и экспоненциальной задержкой. Упал Telegram — уведомление
+// полежит в таблице и уйдёт, когда он поднимется.
+package notify
+
+import "context"
+
+// Message — то, что отправляется в канал.
+// Subject нужен только email, Telegram шлёт один Text.
+type Message struct {
+	Subject string
+	Text    string
+}
+
+// Notifier — канал доставки уведомлений магазину.
+// Name — ключ канала в outbox (колонка channel), должен быть стабильным.
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mailer — отправка письма конкретному адресату
+// (в отличие от Notifier, который шлёт на заранее настроенные адреса магазина).
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
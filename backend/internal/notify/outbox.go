@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"socialsh/backend/internal/models"
+	"socialsh/backend/internal/repository"
+)
+
+// Настройки воркера по умолчанию.
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultBatchSize    = 20
+	DefaultMaxAttempts  = 10
+	DefaultBaseBackoff  = 30 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	sendTimeout         = 30 * time.Second
+)
+
+// Outbox — очередь уведомлений в БД + воркер, который её разгребает.
+//
+// Enqueue пишет по одной записи на каждый включённый канал.
+// Run раз в PollInterval забирает готовые записи (ClaimDue) и отправляет их.
+// Ошибка отправки → следующая попытка через BaseBackoff * 2^(attempts-1),
+// но не реже MaxBackoff. После MaxAttempts попыток запись помечается failed.
+type Outbox struct {
+	store     repository.NotificationRepository
+	notifiers map[string]Notifier
+
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// NewOutbox — outbox с настройками по умолчанию.
+// Без каналов Enqueue ничего не пишет — уведомления просто выключены.
+func NewOutbox(store repository.NotificationRepository, notifiers ...Notifier) *Outbox {
+	o := &Outbox{
+		store:        store,
+		notifiers:    make(map[string]Notifier, len(notifiers)),
+		PollInterval: DefaultPollInterval,
+		BatchSize:    DefaultBatchSize,
+		MaxAttempts:  DefaultMaxAttempts,
+		BaseBackoff:  DefaultBaseBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+	}
+	for _, n := range notifiers {
+		o.notifiers[n.Name()] = n
+	}
+	return o
+}
+
+// Enqueue кладёт сообщение в очередь для каждого канала.
+// Ошибка — только если не удалось записать в БД; отправка будет потом, в воркере.
+func (o *Outbox) Enqueue(msg Message) error {
+	for _, n := range o.Notifications(msg) {
+		if err := o.store.Enqueue(&n); err != nil {
+			return fmt.Errorf("outbox enqueue %s: %w", n.Channel, err)
+		}
+	}
+	return nil
+}
+
+// Notifications — записи outbox для msg, по одной на канал (в порядке имён каналов).
+// Для случаев, когда запись надо сделать в чужой транзакции, — см. OrderRepository.Create.
+func (o *Outbox) Notifications(msg Message) []models.Notification {
+	channels := make([]string, 0, len(o.notifiers))
+	for channel := range o.notifiers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	notifications := make([]models.Notification, len(channels))
+	for i, channel := range channels {
+		notifications[i] = models.Notification{Channel: channel, Subject: msg.Subject, Body: msg.Text}
+	}
+	return notifications
+}
+
+// Run — цикл воркера. Блокирует, пока не отменят ctx; запускать в горутине.
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := o.ProcessDue(ctx); err != nil {
+			log.Printf("notify outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue — один проход: забрать готовые записи и попытаться отправить.
+// Вынесено из Run, чтобы в тестах прогонять очередь синхронно.
+func (o *Outbox) ProcessDue(ctx context.Context) error {
+	// Аренда с запасом: вся пачка должна успеть уйти до её окончания
+	lease := sendTimeout * time.Duration(o.BatchSize+1)
+	due, err := o.store.ClaimDue(o.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range due {
+		if ctx.Err() != nil {
+			return nil // аренда истечёт, запись заберёт следующий запуск
+		}
+		o.deliver(ctx, n)
+	}
+	return nil
+}
+
+// deliver — отправить одну запись и записать результат.
+func (o *Outbox) deliver(ctx context.Context, n models.Notification) {
+	notifier, ok := o.notifiers[n.Channel]
+	if !ok {
+		// Канал выключили в конфиге, а запись осталась — ретраить нечем
+		if err := o.store.MarkFailed(n.ID, "канал "+n.Channel+" не настроен"); err != nil {
+			log.Printf("notify outbox: %v", err)
+		}
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := notifier.Send(sendCtx, Message{Subject: n.Subject, Text: n.Body})
+	cancel()
+
+	if err == nil {
+		err = o.store.MarkSent(n.ID)
+	} else if n.Attempts+1 >= o.MaxAttempts {
+		log.Printf("notify outbox: %s %s: ретраи кончились: %v", n.Channel, n.ID, err)
+		err = o.store.MarkFailed(n.ID, err.Error())
+	} else {
+		err = o.store.MarkRetry(n.ID, o.backoff(n.Attempts+1), err.Error())
+	}
+	if err != nil {
+		log.Printf("notify outbox: %v", err)
+	}
+}
+
+// backoff — задержка перед следующей попыткой после attempts неудачных.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= o.MaxBackoff {
+			return o.MaxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"socialsh/backend/internal/models"
+)
+
+// memStore — NotificationRepository в памяти. Время не моделирует:
+// ClaimDue отдаёт все pending-записи, а задержку MarkRetry запоминает в delays.
+type memStore struct {
+	items  []*models.Notification
+	delays map[string][]time.Duration
+}
+
+func newMemStore() *memStore {
+	return &memStore{delays: map[string][]time.Duration{}}
+}
+
+func (m *memStore) Enqueue(n *models.Notification) error {
+	n.ID = fmt.Sprintf("%s-%d", n.Channel, len(m.items)+1)
+	n.Status = models.NotificationPending
+	cp := *n
+	m.items = append(m.items, &cp)
+	return nil
+}
+
+func (m *memStore) ClaimDue(limit int, lease time.Duration) ([]models.Notification, error) {
+	var due []models.Notification
+	for _, n := range m.items {
+		if n.Status == models.NotificationPending && len(due) < limit {
+			due = append(due, *n)
+		}
+	}
+	return due, nil
+}
+
+func (m *memStore) get(id string) *models.Notification {
+	for _, n := range m.items {
+		if n.ID == id {
+			return n
+		}
+	}
+	panic("нет записи " + id)
+}
+
+func (m *memStore) MarkSent(id string) error {
+	n := m.get(id)
+	n.Status, n.LastError = models.NotificationSent, ""
+	n.Attempts++
+	return nil
+}
+
+func (m *memStore) MarkRetry(id string, delay time.Duration, lastError string) error {
+	n := m.get(id)
+	n.LastError = lastError
+	n.Attempts++
+	m.delays[id] = append(m.delays[id], delay)
+	return nil
+}
+
+func (m *memStore) MarkFailed(id string, lastError string) error {
+	n := m.get(id)
+	n.Status, n.LastError = models.NotificationFailed, lastError
+	n.Attempts++
+	return nil
+}
+
+// flakyNotifier — канал, который падает первые failures отправок.
+type flakyNotifier struct {
+	name     string
+	failures int
+	sent     []Message
+	calls    int
+}
+
+func (f *flakyNotifier) Name() string { return f.name }
+
+func (f *flakyNotifier) Send(ctx context.Context, msg Message) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("канал недоступен")
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestOutboxEnqueuePerChannel(t *testing.T) {
+	store := newMemStore()
+	o := NewOutbox(store, &flakyNotifier{name: "telegram"}, &flakyNotifier{name: "email"})
+
+	if err := o.Enqueue(Message{Subject: "Новый заказ", Text: "текст"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if len(store.items) != 2 || store.items[0].Channel != "email" || store.items[1].Channel != "telegram" {
+		t.Fatalf("записи = %+v, want по одной на канал в порядке имён", store.items)
+	}
+	for _, n := range store.items {
+		if n.Subject != "Новый заказ" || n.Body != "текст" {
+			t.Errorf("запись %s = %+v", n.Channel, *n)
+		}
+	}
+
+	// Без каналов уведомления выключены — ничего не пишем
+	empty := newMemStore()
+	if err := NewOutbox(empty).Enqueue(Message{Text: "x"}); err != nil || len(empty.items) != 0 {
+		t.Fatalf("Enqueue без каналов: %v, записей %d", err, len(empty.items))
+	}
+}
+
+func TestOutboxRetryThenSent(t *testing.T) {
+	store := newMemStore()
+	tg := &flakyNotifier{name: "telegram", failures: 2}
+	o := NewOutbox(store, tg)
+	o.Enqueue(Message{Text: "заказ"})
+	id := store.items[0].ID
+
+	for i := 0; i < 3; i++ {
+		if err := o.ProcessDue(context.Background()); err != nil {
+			t.Fatalf("ProcessDue: %v", err)
+		}
+	}
+
+	n := store.get(id)
+	if n.Status != models.NotificationSent || n.Attempts != 3 || n.LastError != "" {
+		t.Fatalf("запись = %+v, want sent с третьей попытки", *n)
+	}
+	if len(tg.sent) != 1 || tg.sent[0].Text != "заказ" {
+		t.Errorf("отправлено %v", tg.sent)
+	}
+	want := []time.Duration{DefaultBaseBackoff, 2 * DefaultBaseBackoff}
+	if got := store.delays[id]; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("задержки = %v, want %v", got, want)
+	}
+
+	// Отправленное повторно не уходит
+	o.ProcessDue(context.Background())
+	if tg.calls != 3 {
+		t.Errorf("вызовов Send = %d, want 3", tg.calls)
+	}
+}
+
+func TestOutboxFinalFailure(t *testing.T) {
+	store := newMemStore()
+	tg := &flakyNotifier{name: "telegram", failures: 100}
+	o := NewOutbox(store, tg)
+	o.MaxAttempts = 4
+	o.Enqueue(Message{Text: "заказ"})
+	id := store.items[0].ID
+
+	for i := 0; i < 10; i++ {
+		o.ProcessDue(context.Background())
+	}
+
+	n := store.get(id)
+	if n.Status != models.NotificationFailed || n.Attempts != 4 || n.LastError != "канал недоступен" {
+		t.Fatalf("запись = %+v, want failed после 4 попыток", *n)
+	}
+	if tg.calls != 4 {
+		t.Errorf("вызовов Send = %d, want 4", tg.calls)
+	}
+	if got := len(store.delays[id]); got != 3 {
+		t.Errorf("ретраев = %d, want 3 (последняя попытка сразу failed)", got)
+	}
+}
+
+func TestOutboxUnknownChannel(t *testing.T) {
+	store := newMemStore()
+	store.Enqueue(&models.Notification{Channel: "sms", Body: "x"})
+	o := NewOutbox(store, &flakyNotifier{name: "telegram"})
+
+	o.ProcessDue(context.Background())
+	if n := store.items[0]; n.Status != models.NotificationFailed {
+		t.Fatalf("запись выключенного канала = %+v, want failed без ретраев", *n)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	o := NewOutbox(newMemStore())
+	o.BaseBackoff = 30 * time.Second
+	o.MaxBackoff = 5 * time.Minute
+
+	tests := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		4:  4 * time.Minute,
+		5:  5 * time.Minute,
+		50: 5 * time.Minute,
+	}
+	for attempts, want := range tests {
+		if got := o.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+// Через настоящий канал Telegram: Bot API лежит две попытки, на третьей запись уходит.
+func TestOutboxWithTelegram(t *testing.T) {
+	srv := newFakeTelegram(t, 2)
+	store := newMemStore()
+	o := NewOutbox(store, &Telegram{BotToken: "123:abc", ChatID: "1", BaseURL: srv.URL})
+	o.Enqueue(Message{Text: "заказ"})
+	id := store.items[0].ID
+
+	o.ProcessDue(context.Background())
+	if n := store.get(id); n.Status != models.NotificationPending || n.Attempts != 1 || n.LastError == "" {
+		t.Fatalf("после первой попытки = %+v, want pending с ошибкой", *n)
+	}
+
+	o.ProcessDue(context.Background())
+	o.ProcessDue(context.Background())
+	if n := store.get(id); n.Status != models.NotificationSent || n.Attempts != 3 {
+		t.Fatalf("после третьей попытки = %+v, want sent", *n)
+	}
+	if got := srv.calls.Load(); got != 3 {
+		t.Errorf("запросов к Bot API = %d, want 3", got)
+	}
+}
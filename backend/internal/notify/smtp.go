@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP — канал уведомлений по email и заодно Mailer для писем покупателям.
+//
+// Работает с любым SMTP-сервером: в проде — с почтовым сервисом (STARTTLS
+// включается сам, если сервер его поддерживает), локально — с фейковым
+// сервером вроде MailHog на localhost:1025 без авторизации.
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string // пусто → без AUTH
+	Password string
+	From     string
+	To       []string // адреса магазина для Notifier.Send
+}
+
+func (s *SMTP) Name() string { return "email" }
+
+// Send — письмо о событии на адреса магазина (To).
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if len(s.To) == 0 {
+		return errors.New("smtp: не заданы адреса получателей")
+	}
+	return s.send(ctx, s.To, msg.Subject, msg.Text)
+}
+
+// SendMail — письмо одному адресату (сброс пароля, подтверждение email и т.п.).
+func (s *SMTP) SendMail(ctx context.Context, to, subject, body string) error {
+	return s.send(ctx, []string{to}, subject, body)
+}
+
+// send собирает простое text/plain письмо и отправляет через net/smtp.
+// net/smtp не умеет context, поэтому дедлайн ставим на само соединение.
+func (s *SMTP) send(ctx context.Context, to []string, subject, body string) error {
+	addr := net.JoinHostPort(s.Host, s.Port)
+
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp client: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		auth := smtp.PlainAuth("", s.Username, s.Password, s.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.From); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(buildMessage(s.From, to, subject, body)); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data close: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage — заголовки + тело. Тема кодируется в UTF-8 (RFC 2047), иначе кириллица побьётся.
+func buildMessage(from string, to []string, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
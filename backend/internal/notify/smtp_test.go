@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"mime"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP — минимальный SMTP-сервер на localhost: принимает письма и запоминает их.
+// rejectRcpt — адрес, на который сервер отвечает 550 (нет такого ящика).
+type fakeSMTP struct {
+	ln         net.Listener
+	rejectRcpt string
+
+	mu   sync.Mutex
+	auth string // декодированный AUTH PLAIN: "\x00user\x00pass"
+	from string
+	rcpt []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// client — SMTP-канал, настроенный на этот сервер.
+func (s *fakeSMTP) client() *SMTP {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return &SMTP{Host: host, Port: port, From: "shop@example.com"}
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN "):
+			decoded, _ := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+			s.mu.Lock()
+			s.auth = string(decoded)
+			s.mu.Unlock()
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			rcpt := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			if rcpt == s.rejectRcpt {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, rcpt)
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = b.String()
+			s.mu.Unlock()
+			reply("250 OK: queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := newFakeSMTP(t)
+	s := srv.client()
+	s.To = []string{"owner@example.com", "manager@example.com"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Send(ctx, Message{Subject: "Новый заказ 42", Text: "строка 1\nстрока 2"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.from != "shop@example.com" {
+		t.Errorf("MAIL FROM = %q", srv.from)
+	}
+	if strings.Join(srv.rcpt, ",") != "owner@example.com,manager@example.com" {
+		t.Errorf("RCPT TO = %v", srv.rcpt)
+	}
+	if srv.auth != "" {
+		t.Errorf("без Username AUTH не нужен, а пришёл %q", srv.auth)
+	}
+	if !strings.Contains(srv.data, "Subject: "+mime.QEncoding.Encode("utf-8", "Новый заказ 42")+"\r\n") {
+		t.Errorf("тема не закодирована по RFC 2047:\n%s", srv.data)
+	}
+	if !strings.Contains(srv.data, "\r\n\r\nстрока 1\r\nстрока 2") {
+		t.Errorf("тело письма:\n%s", srv.data)
+	}
+}
+
+func TestSMTPSendMailWithAuth(t *testing.T) {
+	srv := newFakeSMTP(t)
+	s := srv.client()
+	s.Username, s.Password = "user", "pass"
+
+	if err := s.SendMail(context.Background(), "buyer@example.com", "Сброс пароля", "ссылка"); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.auth != "\x00user\x00pass" {
+		t.Errorf("AUTH PLAIN = %q", srv.auth)
+	}
+	if len(srv.rcpt) != 1 || srv.rcpt[0] != "buyer@example.com" {
+		t.Errorf("RCPT TO = %v", srv.rcpt)
+	}
+}
+
+func TestSMTPErrors(t *testing.T) {
+	t.Run("нет получателей", func(t *testing.T) {
+		s := newFakeSMTP(t).client()
+		if err := s.Send(context.Background(), Message{Text: "x"}); err == nil {
+			t.Fatal("Send без To = nil, want ошибку")
+		}
+	})
+
+	t.Run("сервер отверг получателя", func(t *testing.T) {
+		srv := newFakeSMTP(t)
+		srv.rejectRcpt = "ghost@example.com"
+		err := srv.client().SendMail(context.Background(), "ghost@example.com", "s", "b")
+		if err == nil || !strings.Contains(err.Error(), "550") {
+			t.Fatalf("SendMail = %v, want ошибку 550", err)
+		}
+	})
+
+	t.Run("сервер недоступен", func(t *testing.T) {
+		srv := newFakeSMTP(t)
+		s := srv.client()
+		srv.ln.Close()
+		if err := s.SendMail(context.Background(), "buyer@example.com", "s", "b"); err == nil {
+			t.Fatal("SendMail на закрытый порт = nil, want ошибку")
+		}
+	})
+}
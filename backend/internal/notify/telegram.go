@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTelegramURL — адрес Bot API. В тестах подменяется на httptest-сервер через BaseURL.
+const DefaultTelegramURL = "https://api.telegram.org"
+
+// Telegram — канал уведомлений в чат через Bot API (sendMessage).
+type Telegram struct {
+	BotToken string
+	ChatID   string
+	BaseURL  string       // пусто → DefaultTelegramURL
+	Client   *http.Client // nil → клиент с таймаутом 10s
+}
+
+// NewTelegram — канал с боевым адресом Bot API и клиентом с таймаутом.
+func NewTelegram(botToken, chatID string) *Telegram {
+	return &Telegram{
+		BotToken: botToken,
+		ChatID:   chatID,
+		BaseURL:  DefaultTelegramURL,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Telegram) Name() string { return "telegram" }
+
+// Send отправляет сообщение в чат. Ответ не 200 → ошибка с телом ответа (воркер повторит).
+func (t *Telegram) Send(ctx context.Context, msg Message) error {
+	// Экранируем специальные символы для Markdown
+	text := msg.Text
+	text = strings.ReplaceAll(text, "_", "\\_")
+	text = strings.ReplaceAll(text, "*", "\\*")
+	text = strings.ReplaceAll(text, "[", "\\[")
+	text = strings.ReplaceAll(text, "]", "\\]")
+
+	baseURL := t.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultTelegramURL
+	}
+	url := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimRight(baseURL, "/"), t.BotToken)
+
+	payload := map[string]interface{}{
+		"chat_id":                  t.ChatID,
+		"text":                     text,
+		"parse_mode":               "Markdown",
+		"disable_web_page_preview": true,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("telegram marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("telegram API error: %d %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeTelegram — заглушка Bot API: первые failures запросов отвечает 500, дальше 200.
+type fakeTelegram struct {
+	*httptest.Server
+	failures int32
+	calls    atomic.Int32
+	path     atomic.Value // string
+	payload  atomic.Value // map[string]interface{}
+}
+
+func newFakeTelegram(t *testing.T, failures int32) *fakeTelegram {
+	t.Helper()
+	f := &fakeTelegram{failures: failures}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := f.calls.Add(1)
+		f.path.Store(r.URL.Path)
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		f.payload.Store(payload)
+
+		if n <= f.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"ok":false,"description":"Internal Server Error"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestTelegramSend(t *testing.T) {
+	srv := newFakeTelegram(t, 0)
+	tg := &Telegram{BotToken: "123:abc", ChatID: "-100500", BaseURL: srv.URL + "/"}
+
+	if err := tg.Send(context.Background(), Message{Text: "*Заказ* [x] user_name"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := srv.path.Load(); got != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %v", got)
+	}
+	payload := srv.payload.Load().(map[string]interface{})
+	if payload["chat_id"] != "-100500" || payload["parse_mode"] != "Markdown" {
+		t.Errorf("payload = %v", payload)
+	}
+	if want := `\*Заказ\* \[x\] user\_name`; payload["text"] != want {
+		t.Errorf("text = %q, want %q", payload["text"], want)
+	}
+}
+
+func TestTelegramSendAPIError(t *testing.T) {
+	srv := newFakeTelegram(t, 1)
+	tg := &Telegram{BotToken: "123:abc", ChatID: "1", BaseURL: srv.URL}
+
+	err := tg.Send(context.Background(), Message{Text: "x"})
+	if err == nil || !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "Internal Server Error") {
+		t.Fatalf("Send = %v, want ошибку с кодом и телом ответа", err)
+	}
+
+	// Сервер поднялся — тот же канал отправляет
+	if err := tg.Send(context.Background(), Message{Text: "x"}); err != nil {
+		t.Fatalf("повторный Send: %v", err)
+	}
+}
+
+func TestTelegramSendUnreachable(t *testing.T) {
+	srv := newFakeTelegram(t, 0)
+	srv.Close()
+	tg := &Telegram{BotToken: "123:abc", ChatID: "1", BaseURL: srv.URL}
+	if err := tg.Send(context.Background(), Message{Text: "x"}); err == nil {
+		t.Fatal("Send на остановленный сервер = nil, want ошибку")
+	}
+}
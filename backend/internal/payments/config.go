@@ -0,0 +1,19 @@
+package payments
+
+import "socialsh/backend/internal/config"
+
+// FromConfig собирает провайдеров по конфигу.
+// YooKassa включается, если заданы ShopID и SecretKey, фейковый — если задан FAKE_PAYMENTS_SECRET.
+// Порядок важен: первый провайдер — провайдер по умолчанию.
+func FromConfig(cfg *config.Config) *Registry {
+	var providers []Provider
+	if cfg.YooKassaShopID != "" && cfg.YooKassaSecretKey != "" {
+		providers = append(providers, NewYooKassa(
+			cfg.YooKassaShopID, cfg.YooKassaSecretKey, cfg.YooKassaWebhookSecret, cfg.YooKassaAPIURL,
+		))
+	}
+	if cfg.FakePaymentsSecret != "" {
+		providers = append(providers, NewFake(cfg.FakePaymentsSecret, cfg.BaseUrl))
+	}
+	return NewRegistry(providers...)
+}
@@ -0,0 +1,70 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FakeSignatureHeader — заголовок с подписью вебхука фейкового провайдера.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// Fake — провайдер для разработки и тестов: ничего не списывает,
+// ссылка на оплату ведёт на BaseURL, а вебхук можно сгенерировать
+// самому через Webhook и отправить в /api/payments/webhook/fake.
+//
+// Вебхук — JSON { "paymentId", "status", "amount" }, подписанный HMAC-SHA256 с Secret.
+type Fake struct {
+	Secret  string
+	BaseURL string
+}
+
+func NewFake(secret, baseURL string) *Fake {
+	return &Fake{Secret: secret, BaseURL: baseURL}
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+// Create — "создаёт" платёж: случайный ID и ссылка вида BaseURL/pay/fake/<id>.
+func (f *Fake) Create(ctx context.Context, req CreateRequest) (*CreateResult, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("fake payment id: %w", err)
+	}
+	id := "fake_" + hex.EncodeToString(buf)
+
+	return &CreateResult{
+		ProviderPaymentID: id,
+		ConfirmationURL:   strings.TrimRight(f.BaseURL, "/") + "/pay/fake/" + id,
+	}, nil
+}
+
+type fakeWebhook struct {
+	PaymentID string `json:"paymentId"`
+	Status    string `json:"status"`
+	Amount    int64  `json:"amount"`
+}
+
+// Webhook — собрать тело вебхука и подпись к нему (для тестов и ручной проверки).
+func (f *Fake) Webhook(providerPaymentID, status string, amount int64) (body []byte, signature string, err error) {
+	body, err = json.Marshal(fakeWebhook{PaymentID: providerPaymentID, Status: status, Amount: amount})
+	if err != nil {
+		return nil, "", err
+	}
+	return body, sign(f.Secret, body), nil
+}
+
+func (f *Fake) ParseWebhook(header func(key string) string, body []byte) (*Event, error) {
+	if err := verify(f.Secret, body, header(FakeSignatureHeader)); err != nil {
+		return nil, err
+	}
+
+	var w fakeWebhook
+	if err := json.Unmarshal(body, &w); err != nil {
+		return nil, fmt.Errorf("fake webhook: %w", err)
+	}
+	return &Event{ProviderPaymentID: w.PaymentID, Status: w.Status, Amount: w.Amount}, nil
+}
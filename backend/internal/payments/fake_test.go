@@ -0,0 +1,84 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFakeCreate(t *testing.T) {
+	f := NewFake("secret", "http://shop.test/")
+
+	res, err := f.Create(context.Background(), CreateRequest{PaymentID: "p1", Amount: 150000, Currency: "RUB"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(res.ProviderPaymentID, "fake_") {
+		t.Errorf("ProviderPaymentID = %q, want префикс fake_", res.ProviderPaymentID)
+	}
+	if want := "http://shop.test/pay/fake/" + res.ProviderPaymentID; res.ConfirmationURL != want {
+		t.Errorf("ConfirmationURL = %q, want %q", res.ConfirmationURL, want)
+	}
+
+	again, err := f.Create(context.Background(), CreateRequest{PaymentID: "p2"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if again.ProviderPaymentID == res.ProviderPaymentID {
+		t.Error("два платежа получили один ID")
+	}
+}
+
+func TestFakeWebhookRoundTrip(t *testing.T) {
+	f := NewFake("secret", "http://shop.test")
+
+	body, signature, err := f.Webhook("fake_1", StatusSucceeded, 150000)
+	if err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	header := func(key string) string {
+		if key == FakeSignatureHeader {
+			return signature
+		}
+		return ""
+	}
+
+	event, err := f.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	want := Event{ProviderPaymentID: "fake_1", Status: StatusSucceeded, Amount: 150000}
+	if *event != want {
+		t.Errorf("event = %+v, want %+v", *event, want)
+	}
+}
+
+func TestFakeWebhookRejectsBadSignature(t *testing.T) {
+	f := NewFake("secret", "http://shop.test")
+	body, signature, err := f.Webhook("fake_1", StatusSucceeded, 150000)
+	if err != nil {
+		t.Fatalf("Webhook: %v", err)
+	}
+	tampered := []byte(strings.Replace(string(body), "150000", "100", 1))
+	other := NewFake("other", "http://shop.test")
+
+	tests := []struct {
+		name      string
+		provider  *Fake
+		body      []byte
+		signature string
+	}{
+		{"подменена сумма", f, tampered, signature},
+		{"подписано чужим секретом", other, body, signature},
+		{"без заголовка", f, body, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := func(string) string { return tt.signature }
+			if _, err := tt.provider.ParseWebhook(header, tt.body); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("ParseWebhook() = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package payments — онлайн-оплата заказов через внешних провайдеров.
+//
+// Каждый провайдер реализует Provider: создаёт платёж у себя и отдаёт ссылку,
+// куда отправить покупателя, а потом присылает подписанный вебхук
+// с результатом. Хендлеры выбирают провайдера по имени через Registry.
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSignature — подпись вебхука не сошлась. Хендлер отдаёт 401.
+var ErrInvalidSignature = errors.New("неверная подпись вебхука")
+
+// Статусы платежа (у нас в БД и в событиях вебхука).
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusCanceled  = "canceled"
+)
+
+// CreateRequest — что нужно провайдеру, чтобы создать платёж.
+type CreateRequest struct {
+	PaymentID   string // наш ID платежа — провайдеры используют его как ключ идемпотентности
+	OrderID     string
+	Amount      int64 // в копейках
+	Currency    string
+	Description string
+	ReturnURL   string // куда провайдер вернёт покупателя после оплаты
+}
+
+// CreateResult — ответ провайдера на создание платежа.
+type CreateResult struct {
+	ProviderPaymentID string
+	ConfirmationURL   string // сюда фронт редиректит покупателя
+}
+
+// Event — разобранный и проверенный вебхук.
+type Event struct {
+	ProviderPaymentID string
+	Status            string // StatusSucceeded | StatusCanceled | StatusPending
+	Amount            int64  // в копейках, сверяется с суммой платежа
+}
+
+// Provider — платёжный провайдер.
+// Name — ключ в Registry и в URL вебхука (/api/payments/webhook/:provider).
+// ParseWebhook проверяет подпись (header — доступ к заголовкам запроса)
+// и возвращает ErrInvalidSignature, если она не сошлась.
+type Provider interface {
+	Name() string
+	Create(ctx context.Context, req CreateRequest) (*CreateResult, error)
+	ParseWebhook(header func(key string) string, body []byte) (*Event, error)
+}
+
+// Registry — включённые провайдеры по имени.
+// Первый добавленный — провайдер по умолчанию.
+type Registry struct {
+	providers map[string]Provider
+	fallback  string
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider, len(providers))}
+	for _, p := range providers {
+		if r.fallback == "" {
+			r.fallback = p.Name()
+		}
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// Get — провайдер по имени; пустое имя — провайдер по умолчанию.
+func (r *Registry) Get(name string) (Provider, bool) {
+	if name == "" {
+		name = r.fallback
+	}
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// sign — HMAC-SHA256 тела в hex. Общая схема подписи вебхуков у наших провайдеров.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify — сравнение подписи за постоянное время.
+func verify(secret string, body []byte, signature string) error {
+	if secret == "" || signature == "" {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sign(secret, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// formatAmount — копейки → "1234.50", как ждут провайдеры.
+func formatAmount(kopecks int64) string {
+	return fmt.Sprintf("%d.%02d", kopecks/100, kopecks%100)
+}
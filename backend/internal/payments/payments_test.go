@@ -0,0 +1,69 @@
+package payments
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"paymentId":"p1","status":"succeeded","amount":150000}`)
+	good := sign("secret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		wantErr   bool
+	}{
+		{"верная подпись", "secret", body, good, false},
+		{"чужой секрет", "other", body, good, true},
+		{"тело изменено", "secret", []byte(`{"paymentId":"p1","status":"succeeded","amount":1}`), good, true},
+		{"нет подписи", "secret", body, "", true},
+		{"пустой секрет не принимает ничего", "", body, sign("", body), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verify(tt.secret, tt.body, tt.signature)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("verify() = %v, want ErrInvalidSignature", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("verify() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := map[int64]string{
+		0:      "0.00",
+		5:      "0.05",
+		150000: "1500.00",
+		123450: "1234.50",
+	}
+	for kopecks, want := range tests {
+		if got := formatAmount(kopecks); got != want {
+			t.Errorf("formatAmount(%d) = %q, want %q", kopecks, got, want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	fake := NewFake("secret", "http://shop.test")
+	yoo := NewYooKassa("shop", "key", "hook", "")
+	r := NewRegistry(yoo, fake)
+
+	if p, ok := r.Get(""); !ok || p != yoo {
+		t.Fatalf("Get(\"\") = %v, %v; want первый провайдер", p, ok)
+	}
+	if p, ok := r.Get("fake"); !ok || p != fake {
+		t.Fatalf("Get(\"fake\") = %v, %v", p, ok)
+	}
+	if _, ok := r.Get("paypal"); ok {
+		t.Fatal("Get(\"paypal\") нашёл невключённого провайдера")
+	}
+	if _, ok := NewRegistry().Get(""); ok {
+		t.Fatal("пустой Registry отдал провайдера по умолчанию")
+	}
+}
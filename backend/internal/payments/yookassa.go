@@ -0,0 +1,174 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultYooKassaURL — боевой адрес API. В тестах подменяется на локальную заглушку через BaseURL.
+const DefaultYooKassaURL = "https://api.yookassa.ru/v3"
+
+// YooKassaSignatureHeader — заголовок с HMAC-SHA256 подписью тела вебхука.
+const YooKassaSignatureHeader = "X-Webhook-Signature"
+
+// YooKassa — провайдер в стиле API ЮKassa.
+//
+// Создание: POST {BaseURL}/payments с Basic-авторизацией ShopID:SecretKey
+// и заголовком Idempotence-Key (наш ID платежа — повторный запрос не создаст второй платёж).
+// Вебхук: { "event": "payment.succeeded", "object": { "id", "status", "amount": { "value" } } },
+// подписанный HMAC-SHA256 с WebhookSecret в заголовке X-Webhook-Signature.
+type YooKassa struct {
+	ShopID        string
+	SecretKey     string
+	WebhookSecret string
+	BaseURL       string       // пусто → DefaultYooKassaURL
+	Client        *http.Client // nil → клиент с таймаутом 15s
+}
+
+func NewYooKassa(shopID, secretKey, webhookSecret, baseURL string) *YooKassa {
+	if baseURL == "" {
+		baseURL = DefaultYooKassaURL
+	}
+	return &YooKassa{
+		ShopID:        shopID,
+		SecretKey:     secretKey,
+		WebhookSecret: webhookSecret,
+		BaseURL:       baseURL,
+		Client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (y *YooKassa) Name() string { return "yookassa" }
+
+type yooAmount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+type yooPayment struct {
+	ID           string    `json:"id"`
+	Status       string    `json:"status"`
+	Amount       yooAmount `json:"amount"`
+	Confirmation struct {
+		ConfirmationURL string `json:"confirmation_url"`
+	} `json:"confirmation"`
+}
+
+func (y *YooKassa) Create(ctx context.Context, req CreateRequest) (*CreateResult, error) {
+	payload := map[string]interface{}{
+		"amount":  yooAmount{Value: formatAmount(req.Amount), Currency: req.Currency},
+		"capture": true,
+		"confirmation": map[string]string{
+			"type":       "redirect",
+			"return_url": req.ReturnURL,
+		},
+		"description": req.Description,
+		"metadata":    map[string]string{"order_id": req.OrderID, "payment_id": req.PaymentID},
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("yookassa marshal: %w", err)
+	}
+
+	baseURL := y.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultYooKassaURL
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(baseURL, "/")+"/payments", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("yookassa request: %w", err)
+	}
+	httpReq.SetBasicAuth(y.ShopID, y.SecretKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Idempotence-Key", req.PaymentID)
+
+	client := y.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("yookassa request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("yookassa read: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("yookassa API error: %d %s", resp.StatusCode, string(body))
+	}
+
+	var p yooPayment
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("yookassa unmarshal: %w", err)
+	}
+	if p.ID == "" || p.Confirmation.ConfirmationURL == "" {
+		return nil, fmt.Errorf("yookassa: в ответе нет id или confirmation_url")
+	}
+
+	return &CreateResult{ProviderPaymentID: p.ID, ConfirmationURL: p.Confirmation.ConfirmationURL}, nil
+}
+
+func (y *YooKassa) ParseWebhook(header func(key string) string, body []byte) (*Event, error) {
+	if err := verify(y.WebhookSecret, body, header(YooKassaSignatureHeader)); err != nil {
+		return nil, err
+	}
+
+	var w struct {
+		Event  string     `json:"event"`
+		Object yooPayment `json:"object"`
+	}
+	if err := json.Unmarshal(body, &w); err != nil {
+		return nil, fmt.Errorf("yookassa webhook: %w", err)
+	}
+
+	amount, err := parseAmount(w.Object.Amount.Value)
+	if err != nil {
+		return nil, fmt.Errorf("yookassa webhook amount: %w", err)
+	}
+
+	// waiting_for_capture у нас не бывает (capture: true), считаем его ещё не оплаченным
+	status := StatusPending
+	switch w.Object.Status {
+	case "succeeded":
+		status = StatusSucceeded
+	case "canceled":
+		status = StatusCanceled
+	}
+
+	return &Event{ProviderPaymentID: w.Object.ID, Status: status, Amount: amount}, nil
+}
+
+// parseAmount — "1234.50" → 123450 копеек. Без float, чтобы не терять копейки.
+// Только цифры и точка: знак ParseInt принял бы, и "-1.50" стало бы -50 копеек.
+func parseAmount(value string) (int64, error) {
+	whole, frac, _ := strings.Cut(value, ".")
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("слишком много знаков после точки: %q", value)
+	}
+	if strings.ContainsAny(value, "+-") {
+		return 0, fmt.Errorf("сумма со знаком: %q", value)
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	rub, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	kop, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return rub*100 + kop, nil
+}
@@ -0,0 +1,184 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// yooStub — локальная заглушка API ЮKassa: проверяет запрос и отвечает status/response.
+func yooStub(t *testing.T, status int, response string, check func(r *http.Request, body map[string]interface{})) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("тело запроса не JSON: %v", err)
+		}
+		if check != nil {
+			check(r, body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestYooKassaCreate(t *testing.T) {
+	srv := yooStub(t, http.StatusOK,
+		`{"id":"yk_1","status":"pending","amount":{"value":"1234.50","currency":"RUB"},
+		  "confirmation":{"type":"redirect","confirmation_url":"https://pay.test/yk_1"}}`,
+		func(r *http.Request, body map[string]interface{}) {
+			if r.Method != http.MethodPost || r.URL.Path != "/payments" {
+				t.Errorf("запрос %s %s, want POST /payments", r.Method, r.URL.Path)
+			}
+			if user, pass, ok := r.BasicAuth(); !ok || user != "shop" || pass != "key" {
+				t.Errorf("Basic-авторизация = %q:%q", user, pass)
+			}
+			if got := r.Header.Get("Idempotence-Key"); got != "p1" {
+				t.Errorf("Idempotence-Key = %q, want наш ID платежа p1", got)
+			}
+			amount, _ := body["amount"].(map[string]interface{})
+			if amount["value"] != "1234.50" || amount["currency"] != "RUB" {
+				t.Errorf("amount = %v", amount)
+			}
+			metadata, _ := body["metadata"].(map[string]interface{})
+			if metadata["order_id"] != "o1" || metadata["payment_id"] != "p1" {
+				t.Errorf("metadata = %v", metadata)
+			}
+		})
+
+	y := NewYooKassa("shop", "key", "hook", srv.URL+"/")
+	res, err := y.Create(context.Background(), CreateRequest{
+		PaymentID: "p1",
+		OrderID:   "o1",
+		Amount:    123450,
+		Currency:  "RUB",
+		ReturnURL: "http://shop.test/orders/o1",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if res.ProviderPaymentID != "yk_1" || res.ConfirmationURL != "https://pay.test/yk_1" {
+		t.Errorf("result = %+v", *res)
+	}
+}
+
+func TestYooKassaCreateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+	}{
+		{"ошибка API", http.StatusUnauthorized, `{"type":"error","code":"invalid_credentials"}`},
+		{"не JSON", http.StatusOK, `<html>`},
+		{"нет confirmation_url", http.StatusOK, `{"id":"yk_1","status":"pending"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := yooStub(t, tt.status, tt.response, nil)
+			y := NewYooKassa("shop", "key", "hook", srv.URL)
+			if _, err := y.Create(context.Background(), CreateRequest{PaymentID: "p1", Amount: 100}); err == nil {
+				t.Fatal("Create() = nil, want ошибку")
+			}
+		})
+	}
+}
+
+func TestYooKassaParseWebhook(t *testing.T) {
+	y := NewYooKassa("shop", "key", "hook", "")
+	webhook := func(status, value string) []byte {
+		return []byte(`{"event":"payment.` + status + `","object":{"id":"yk_1","status":"` + status +
+			`","amount":{"value":"` + value + `","currency":"RUB"}}}`)
+	}
+
+	tests := []struct {
+		name string
+		body []byte
+		want Event
+	}{
+		{"оплачен", webhook("succeeded", "1234.50"), Event{"yk_1", StatusSucceeded, 123450}},
+		{"отменён", webhook("canceled", "10"), Event{"yk_1", StatusCanceled, 1000}},
+		{"ждёт подтверждения", webhook("waiting_for_capture", "10.00"), Event{"yk_1", StatusPending, 1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature := sign("hook", tt.body)
+			header := func(key string) string {
+				if key == YooKassaSignatureHeader {
+					return signature
+				}
+				return ""
+			}
+			event, err := y.ParseWebhook(header, tt.body)
+			if err != nil {
+				t.Fatalf("ParseWebhook: %v", err)
+			}
+			if *event != tt.want {
+				t.Errorf("event = %+v, want %+v", *event, tt.want)
+			}
+		})
+	}
+}
+
+func TestYooKassaParseWebhookRejects(t *testing.T) {
+	y := NewYooKassa("shop", "key", "hook", "")
+	body := []byte(`{"event":"payment.succeeded","object":{"id":"yk_1","status":"succeeded","amount":{"value":"1.00"}}}`)
+
+	// Подпись другим секретом (например, секретом магазина вместо секрета вебхука)
+	header := func(string) string { return sign("key", body) }
+	if _, err := y.ParseWebhook(header, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("ParseWebhook() = %v, want ErrInvalidSignature", err)
+	}
+
+	// Подпись верная, но сумма кривая — это не ошибка подписи, а невалидный вебхук
+	bad := []byte(`{"event":"payment.succeeded","object":{"id":"yk_1","status":"succeeded","amount":{"value":"-1.00"}}}`)
+	header = func(string) string { return sign("hook", bad) }
+	_, err := y.ParseWebhook(header, bad)
+	if err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("ParseWebhook() = %v, want ошибку суммы", err)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"1234.50", 123450, false},
+		{"1234.5", 123450, false},
+		{"1234", 123400, false},
+		{"1234.", 123400, false},
+		{"0.01", 1, false},
+		{"0.00", 0, false},
+		{"1234.567", 0, true},
+		{"", 0, true},
+		{".50", 0, true},
+		{"abc", 0, true},
+		{"12.ab", 0, true},
+		{"-1.50", 0, true},
+		{"1.-5", 0, true},
+		{"+1.50", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAmount(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAmount(%q) = %d, want ошибку", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("parseAmount(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
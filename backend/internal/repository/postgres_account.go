@@ -2,11 +2,16 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"socialsh/backend/internal/models"
 	"strings"
+	"time"
 )
 
+// ErrLastLoginMethod — отвязка Telegram оставила бы аккаунт без способа входа.
+var ErrLastLoginMethod = errors.New("нельзя отвязать единственный способ входа")
+
 // AccountSQLRepo — реализация AccountRepository поверх PostgreSQL.
 //
 // САМЫЙ ХИТРЫЙ РЕПО из всех, потому что:
@@ -45,15 +50,13 @@ func NewAccountSQLRepo(db *sql.DB) *AccountSQLRepo {
 //	if err != nil { return nil, fmt.Errorf("account.GetUserByID: %w", err) }
 //	return &u, nil
 func (r *AccountSQLRepo) GetUserByID(id string) (*models.User, error) {
-	query := `SELECT id, email, name, password_hash, role 
-				FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
-	var u models.User
-	err := r.db.QueryRow(query, id).Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &u.Role)
+	u, err := scanUser(r.db.QueryRow(query, id))
 	if err != nil {
 		return nil, fmt.Errorf("account.GetUserByID: %w", err)
 	}
-	return &u, nil
+	return u, nil
 }
 
 // GetUserByEmail — найти юзера по email (для логина).
@@ -71,15 +74,13 @@ func (r *AccountSQLRepo) GetUserByID(id string) (*models.User, error) {
 //	query := `SELECT id, email, name, password_hash, role FROM users WHERE email = $1`
 //	... (аналогично GetUserByID)
 func (r *AccountSQLRepo) GetUserByEmail(email string) (*models.User, error) {
-	query := `SELECT id, email, name, password_hash, role FROM users WHERE email = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
 
-	var u models.User
-
-	err := r.db.QueryRow(query, email).Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &u.Role)
+	u, err := scanUser(r.db.QueryRow(query, email))
 	if err != nil {
 		return nil, fmt.Errorf("users.GetByEmail: %w", err)
 	}
-	return &u, nil
+	return u, nil
 }
 
 // CreateUser — зарегистрировать нового юзера.
@@ -103,17 +104,63 @@ func (r *AccountSQLRepo) GetUserByEmail(email string) (*models.User, error) {
 //	err := r.db.QueryRow(query, user.Email, user.Name, user.PasswordHash, user.Role).Scan(&user.ID)
 //	if err != nil { return fmt.Errorf("account.CreateUser: %w", err) }
 //	return nil
+//
+// Юзер из Telegram приходит без email и пароля, зато с TelegramID —
+// пустой email пишем как NULL (UNIQUE не мешает нескольким таким аккаунтам).
 func (r *AccountSQLRepo) CreateUser(user *models.User) error {
-	query := `INSERT INTO users (email, name, password_hash, role)
-	           VALUES ($1, $2, $3, $4) RETURNING id`
+	query := `INSERT INTO users (email, name, password_hash, role, telegram_id, telegram_username)
+	           VALUES (NULLIF($1, ''), $2, $3, $4, $5, NULLIF($6, '')) RETURNING id`
 
-	err := r.db.QueryRow(query, user.Email, user.Name, user.PasswordHash, user.Role).Scan(&user.ID)
+	err := r.db.QueryRow(query, user.Email, user.Name, user.PasswordHash, user.Role,
+		user.TelegramID, user.TelegramUsername).Scan(&user.ID)
 	if err != nil {
 		return fmt.Errorf("account.CreateUser: %w", err)
 	}
 	return nil
 }
 
+// GetUserByTelegramID — найти юзера по привязанному Telegram (вход через виджет).
+// Не найден → sql.ErrNoRows (обёрнутая).
+func (r *AccountSQLRepo) GetUserByTelegramID(telegramID int64) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE telegram_id = $1`
+
+	u, err := scanUser(r.db.QueryRow(query, telegramID))
+	if err != nil {
+		return nil, fmt.Errorf("account.GetUserByTelegramID: %w", err)
+	}
+	return u, nil
+}
+
+// LinkTelegram — привязать Telegram к юзеру (или обновить username при повторном входе).
+// Telegram уже привязан к другому аккаунту → ошибка уникальности (utils.IsDuplicateKeyError).
+func (r *AccountSQLRepo) LinkTelegram(userID string, telegramID int64, username string) (*models.User, error) {
+	query := `UPDATE users SET telegram_id = $2, telegram_username = NULLIF($3, '')
+	          WHERE id = $1 RETURNING ` + userColumns
+
+	u, err := scanUser(r.db.QueryRow(query, userID, telegramID, username))
+	if err != nil {
+		return nil, fmt.Errorf("account.LinkTelegram: %w", err)
+	}
+	return u, nil
+}
+
+// UnlinkTelegram — отвязать Telegram. Только если остаётся вход по email и паролю,
+// иначе ErrLastLoginMethod — юзер потерял бы доступ к аккаунту.
+func (r *AccountSQLRepo) UnlinkTelegram(userID string) (*models.User, error) {
+	query := `UPDATE users SET telegram_id = NULL, telegram_username = NULL
+	          WHERE id = $1 AND email IS NOT NULL AND password_hash <> ''
+	          RETURNING ` + userColumns
+
+	u, err := scanUser(r.db.QueryRow(query, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLastLoginMethod
+	}
+	if err != nil {
+		return nil, fmt.Errorf("account.UnlinkTelegram: %w", err)
+	}
+	return u, nil
+}
+
 // UpdateUser — частичное обновление профиля (PATCH-семантика).
 //
 // Как читается:
@@ -176,7 +223,10 @@ func (r *AccountSQLRepo) UpdateUser(id string, req *models.UpdateProfileRequest)
 	}
 
 	if req.Email != nil {
-		setClauses = append(setClauses, fmt.Sprintf("email = $%d", argIdx))
+		// Новый email ещё не подтверждён. В SET справа — старые значения строки,
+		// так что тот же email (без изменений) флаг не сбрасывает.
+		setClauses = append(setClauses, fmt.Sprintf("email = $%d", argIdx),
+			fmt.Sprintf("email_verified = (email_verified AND email = $%d)", argIdx))
 		args = append(args, *req.Email)
 		argIdx++
 	}
@@ -186,18 +236,17 @@ func (r *AccountSQLRepo) UpdateUser(id string, req *models.UpdateProfileRequest)
 	}
 
 	query := fmt.Sprintf(
-		"UPDATE users SET %s WHERE id = $%d RETURNING id, email, name, password_hash, role",
+		"UPDATE users SET %s WHERE id = $%d RETURNING "+userColumns,
 		strings.Join(setClauses, ", "), argIdx,
 	)
 	args = append(args, id)
 
-	var u models.User
-	err := r.db.QueryRow(query, args...).Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &u.Role)
+	u, err := scanUser(r.db.QueryRow(query, args...))
 	if err != nil {
 		return nil, fmt.Errorf("account.UpdateUser: %w", err)
 	}
 
-	return &u, nil
+	return u, nil
 }
 
 // ────────────────────────────────────────────────
@@ -246,11 +295,12 @@ func (r *AccountSQLRepo) UpdateUser(id string, req *models.UpdateProfileRequest)
 //
 //	Не забудь defer rows.Close() и проверки ошибок на каждом этапе.
 //
-// TODO: реализовать по паттерну выше
+// Сейчас этап 2 делает общий хелпер loadOrderDetails (postgres_orders.go):
+// он грузит позиции и историю статусов одним запросом на весь список.
 func (r *AccountSQLRepo) ListOrdersByUser(id string) ([]models.Order, error) {
 	// Этап 1: Получаем все заказы юзера
-	ordersQuery := `SELECT id, user_id, status, total, created_at, updated_at
-	                 FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
+	ordersQuery := `SELECT ` + orderColumns + `
+	                 FROM orders o WHERE o.user_id = $1 ORDER BY o.created_at DESC`
 
 	rows, err := r.db.Query(ordersQuery, id)
 	if err != nil {
@@ -260,47 +310,193 @@ func (r *AccountSQLRepo) ListOrdersByUser(id string) ([]models.Order, error) {
 
 	orders := []models.Order{}
 	for rows.Next() {
-		var o models.Order
-		err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.Total, &o.CreatedAt, &o.UpdatedAt)
+		o, err := scanOrder(rows)
 		if err != nil {
 			return nil, fmt.Errorf("account.ListOrdersByUser scan order: %w", err)
 		}
-		// Инициализируем Items как пустой слайс для каждого заказа
-		o.Items = []models.OrderItem{}
-		orders = append(orders, o)
+		orders = append(orders, *o)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("account.ListOrdersByUser rows: %w", err)
 	}
 
-	// Этап 2: Для каждого заказа подгружаем позиции (order_items)
-	itemsQuery := `SELECT id, order_id, product_id, title, price, quantity
-	                FROM order_items WHERE order_id = $1`
+	// Этап 2: Подгружаем позиции (order_items) и историю статусов для таймлайна
+	if err := loadOrderDetails(r.db, orders); err != nil {
+		return nil, fmt.Errorf("account.ListOrdersByUser: %w", err)
+	}
 
-	for i := range orders {
-		itemRows, err := r.db.Query(itemsQuery, orders[i].ID)
-		if err != nil {
-			return nil, fmt.Errorf("account.ListOrdersByUser query items: %w", err)
-		}
+	return orders, nil
+}
 
-		for itemRows.Next() {
-			var item models.OrderItem
-			err := itemRows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Title, &item.Price, &item.Quantity)
-			if err != nil {
-				itemRows.Close()
-				return nil, fmt.Errorf("account.ListOrdersByUser scan item: %w", err)
-			}
-			orders[i].Items = append(orders[i].Items, item)
-		}
+// MarkEmailVerified — подтвердить email юзера.
+// email — адрес из ссылки: если юзер успел сменить email, строка не обновится
+// и вернётся false (ссылка была на старый адрес).
+func (r *AccountSQLRepo) MarkEmailVerified(id, email string) (bool, error) {
+	result, err := r.db.Exec(`UPDATE users SET email_verified = true
+	                           WHERE id = $1 AND email = $2`, id, email)
+	if err != nil {
+		return false, fmt.Errorf("account.MarkEmailVerified: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("account.MarkEmailVerified rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// TouchVerificationSent — отметить отправку письма подтверждения, если с прошлой
+// прошло не меньше interval. false — слишком рано, письмо слать нельзя.
+// Проверка и запись одним UPDATE, так что два параллельных запроса не отправят два письма.
+func (r *AccountSQLRepo) TouchVerificationSent(id string, interval time.Duration) (bool, error) {
+	result, err := r.db.Exec(`UPDATE users SET email_verification_sent_at = CURRENT_TIMESTAMP
+	                           WHERE id = $1
+	                             AND (email_verification_sent_at IS NULL
+	                                  OR email_verification_sent_at <= CURRENT_TIMESTAMP - $2 * INTERVAL '1 second')`,
+		id, int64(interval/time.Second))
+	if err != nil {
+		return false, fmt.Errorf("account.TouchVerificationSent: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("account.TouchVerificationSent rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ChangePassword — новый хеш пароля и выход со всех устройств, кроме текущего
+// (keepSessionID — семья refresh-токенов текущего входа; пусто — выход везде).
+// Одной транзакцией: пароль сменился — старые сессии гарантированно отозваны.
+func (r *AccountSQLRepo) ChangePassword(id, passwordHash, keepSessionID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("account.ChangePassword begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("account.ChangePassword update: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("account.ChangePassword rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("account.ChangePassword: %w", sql.ErrNoRows)
+	}
+
+	if err := revokeOtherRefreshTokens(tx, id, keepSessionID); err != nil {
+		return fmt.Errorf("account.ChangePassword revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("account.ChangePassword commit: %w", err)
+	}
+	return nil
+}
+
+// DeleteAccount — удаление аккаунта по запросу субъекта данных (152-ФЗ / GDPR).
+// Заказы остаются для бухгалтерии, но контакты в них заменяются псевдонимом:
+// имя — pseudonym, email — pseudonym@deleted.invalid, телефон, Telegram, адрес и комментарий стираются.
+// "Его заказы" — с его user_id, а если email подтверждён, то и гостевые (user_id IS NULL)
+// на этот email: гостевые заказы привязываются к аккаунту только при подтверждении email
+// (ConfirmEmail, вход по ссылке), а оформленные потом без входа так и остались бы
+// с его контактами. Неподтверждённый email ничего не доказывает — иначе любой
+// зарегистрировался бы на чужой адрес и удалением аккаунта стёр чужие заказы.
+//
+// Как читается (одна транзакция):
+//  1. Блокируем строку юзера (SELECT ... FOR UPDATE) — нет юзера → sql.ErrNoRows;
+//     email для поиска гостевых заказов — только подтверждённый
+//  2. discount_redemptions.customer_key его заказов → email-псевдоним (там лежал email);
+//     до шага 3, пока гостевые заказы ещё находятся по email
+//  3. customer_* в его заказах → псевдоним
+//  4. Ключи идемпотентности юзера (в них сохранены ответы с его данными) и счётчик
+//     неудачных входов по его email
+//  5. DELETE FROM users — refresh-токены, ссылки входа, коды 2FA удаляются каскадом,
+//     orders.user_id и order_status_history.changed_by становятся NULL
+func (r *AccountSQLRepo) DeleteAccount(id, pseudonym string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("account.DeleteAccount begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Юзер
+	var email string
+	var emailVerified bool
+	err = tx.QueryRow(`SELECT COALESCE(email, ''), email_verified FROM users WHERE id = $1 FOR UPDATE`, id).
+		Scan(&email, &emailVerified)
+	if err != nil {
+		return fmt.Errorf("account.DeleteAccount lock user: %w", err)
+	}
+	guestEmail := ""
+	if emailVerified {
+		guestEmail = email
+	}
+
+	pseudonymEmail := pseudonym + "@deleted.invalid"
 
-		if err := itemRows.Err(); err != nil {
-			itemRows.Close()
-			return nil, fmt.Errorf("account.ListOrdersByUser itemRows: %w", err)
+	// Его заказы: $1 — id юзера, $2 — подтверждённый email (пустой — только по id)
+	ownOrders := `(user_id = $1 OR (user_id IS NULL AND $2 <> '' AND LOWER(customer_email) = LOWER($2)))`
+
+	// 2. Применения промокодов
+	_, err = tx.Exec(`UPDATE discount_redemptions SET customer_key = $3
+	                   WHERE order_id IN (SELECT id FROM orders WHERE `+ownOrders+`)`, id, guestEmail, pseudonymEmail)
+	if err != nil {
+		return fmt.Errorf("account.DeleteAccount redemptions: %w", err)
+	}
+
+	// 3. Заказы
+	_, err = tx.Exec(`UPDATE orders SET customer_name = $3, customer_email = $4,
+	                          customer_phone = '', customer_telegram = '', customer_address = '', comment = ''
+	                   WHERE `+ownOrders, id, guestEmail, pseudonym, pseudonymEmail)
+	if err != nil {
+		return fmt.Errorf("account.DeleteAccount orders: %w", err)
+	}
+
+	// 4. Прочие следы
+	if _, err := tx.Exec(`DELETE FROM idempotency_keys WHERE scope = $1`, id); err != nil {
+		return fmt.Errorf("account.DeleteAccount idempotency keys: %w", err)
+	}
+	if email != "" {
+		_, err = tx.Exec(`DELETE FROM login_throttles WHERE kind = $1 AND subject = LOWER($2)`,
+			models.LoginThrottleEmail, email)
+		if err != nil {
+			return fmt.Errorf("account.DeleteAccount login throttles: %w", err)
 		}
+	}
 
-		itemRows.Close()
+	// 5. Сам юзер
+	if _, err := tx.Exec(`DELETE FROM users WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("account.DeleteAccount delete user: %w", err)
 	}
 
-	return orders, nil
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("account.DeleteAccount commit: %w", err)
+	}
+	return nil
+}
+
+// userColumns — колонки юзера в порядке, который ждёт scanUser.
+// email у аккаунтов из Telegram — NULL, в Go это пустая строка.
+const userColumns = `id, COALESCE(email, ''), name, password_hash, role, email_verified, blocked_at,
+	totp_enabled_at IS NOT NULL, telegram_id, COALESCE(telegram_username, '')`
+
+func scanUser(scanner interface{ Scan(dest ...any) error }) (*models.User, error) {
+	var u models.User
+	var blockedAt sql.NullTime
+	var telegramID sql.NullInt64
+	err := scanner.Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &u.Role, &u.EmailVerified, &blockedAt,
+		&u.TOTPEnabled, &telegramID, &u.TelegramUsername)
+	if err != nil {
+		return nil, err
+	}
+	if blockedAt.Valid {
+		u.BlockedAt = &blockedAt.Time
+	}
+	if telegramID.Valid {
+		u.TelegramID = &telegramID.Int64
+	}
+	return &u, nil
 }
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// insertGuestOrder — гостевой заказ с контактами покупателя, возвращает id.
+func insertGuestOrder(t *testing.T, conn *sql.DB, email string) string {
+	t.Helper()
+	var id string
+	err := conn.QueryRow(`INSERT INTO orders (total, customer_name, customer_email, customer_phone, customer_address)
+	                      VALUES (1000, 'Иван', $1, '+79990000000', 'Москва') RETURNING id`, email).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert order: %v", err)
+	}
+	return id
+}
+
+func customerName(t *testing.T, conn *sql.DB, orderID string) string {
+	t.Helper()
+	var name string
+	if err := conn.QueryRow(`SELECT customer_name FROM orders WHERE id = $1`, orderID).Scan(&name); err != nil {
+		t.Fatalf("select order: %v", err)
+	}
+	return name
+}
+
+// Гостевые заказы на email аккаунта псевдонимизируются, только если email подтверждён:
+// регистрация на чужой адрес не даёт стереть чужие заказы.
+func TestDeleteAccountGuestOrders(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified bool
+		wantName string
+	}{
+		{"email подтверждён", true, "deleted-1"},
+		{"email не подтверждён", false, "Иван"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := testDB(t)
+			repo := NewAccountSQLRepo(conn)
+
+			var userID string
+			err := conn.QueryRow(`INSERT INTO users (email, name, email_verified) VALUES ('buyer@example.com', 'Иван', $1)
+			                      RETURNING id`, tt.verified).Scan(&userID)
+			if err != nil {
+				t.Fatalf("insert user: %v", err)
+			}
+			guest := insertGuestOrder(t, conn, "Buyer@Example.com")
+			other := insertGuestOrder(t, conn, "other@example.com")
+
+			if err := repo.DeleteAccount(userID, "deleted-1"); err != nil {
+				t.Fatalf("DeleteAccount: %v", err)
+			}
+			if got := customerName(t, conn, guest); got != tt.wantName {
+				t.Errorf("гостевой заказ на его email: имя = %q, want %q", got, tt.wantName)
+			}
+			if got := customerName(t, conn, other); got != "Иван" {
+				t.Errorf("чужой гостевой заказ: имя = %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"socialsh/backend/internal/models"
+)
+
+// ErrDiscountLimitReached — лимит применений промокода (общий или на покупателя) уже выбран.
+// Проверка в хендлере — предварительная, окончательно лимит проверяется
+// в транзакции заказа под блокировкой промокода. Хендлер отдаёт 409.
+var ErrDiscountLimitReached = errors.New("лимит применений промокода исчерпан")
+
+// DiscountSQLRepo — реализация DiscountRepository поверх PostgreSQL.
+//
+// Промокоды лежат в discounts, каждое применение — строка в discount_redemptions
+// (промокод + заказ + email покупателя в нижнем регистре).
+// used_count в discounts — счётчик для быстрого чтения, меняется в той же транзакции,
+// что и discount_redemptions.
+type DiscountSQLRepo struct {
+	db *sql.DB
+}
+
+func NewDiscountSQLRepo(db *sql.DB) *DiscountSQLRepo {
+	return &DiscountSQLRepo{db: db}
+}
+
+// List — все промокоды для админки, новые сверху.
+func (r *DiscountSQLRepo) List() ([]models.Discount, error) {
+	rows, err := r.db.Query(`SELECT ` + discountColumns + ` FROM discounts ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("discounts.List query: %w", err)
+	}
+	defer rows.Close()
+
+	discounts := []models.Discount{}
+	for rows.Next() {
+		d, err := scanDiscount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("discounts.List scan: %w", err)
+		}
+		discounts = append(discounts, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("discounts.List rows: %w", err)
+	}
+
+	return discounts, nil
+}
+
+// GetByID — промокод по UUID. Не найден → sql.ErrNoRows (обёрнутая).
+func (r *DiscountSQLRepo) GetByID(id string) (*models.Discount, error) {
+	d, err := scanDiscount(r.db.QueryRow(`SELECT `+discountColumns+` FROM discounts WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("discounts.GetByID: %w", err)
+	}
+	return d, nil
+}
+
+// GetByCode — промокод по коду без учёта регистра. Не найден → sql.ErrNoRows (обёрнутая).
+func (r *DiscountSQLRepo) GetByCode(code string) (*models.Discount, error) {
+	query := `SELECT ` + discountColumns + ` FROM discounts WHERE code = $1`
+
+	d, err := scanDiscount(r.db.QueryRow(query, strings.ToUpper(code)))
+	if err != nil {
+		return nil, fmt.Errorf("discounts.GetByCode: %w", err)
+	}
+	return d, nil
+}
+
+// Create — новый промокод. Код приводим к верхнему регистру.
+// Дубликат кода → unique_violation (23505).
+func (r *DiscountSQLRepo) Create(discount *models.Discount) error {
+	productIDsJSON, err := marshalProductIDs(discount.ProductIDs)
+	if err != nil {
+		return fmt.Errorf("discounts.Create marshal product ids: %w", err)
+	}
+	discount.Code = strings.ToUpper(discount.Code)
+
+	query := `INSERT INTO discounts (code, kind, value, min_order_total, starts_at, ends_at,
+	                                 usage_limit, per_customer_limit, product_ids, active)
+	           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	           RETURNING id, used_count, created_at`
+
+	err = r.db.QueryRow(query,
+		discount.Code, discount.Kind, discount.Value, discount.MinOrderTotal,
+		discount.StartsAt, discount.EndsAt, discount.UsageLimit, discount.PerCustomerLimit,
+		productIDsJSON, discount.Active,
+	).Scan(&discount.ID, &discount.UsedCount, &discount.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("discounts.Create: %w", err)
+	}
+
+	if discount.ProductIDs == nil {
+		discount.ProductIDs = []string{}
+	}
+	return nil
+}
+
+// Update — перезаписать настройки промокода. used_count не трогаем — его ведут заказы.
+func (r *DiscountSQLRepo) Update(id string, discount *models.Discount) (*models.Discount, error) {
+	productIDsJSON, err := marshalProductIDs(discount.ProductIDs)
+	if err != nil {
+		return nil, fmt.Errorf("discounts.Update marshal product ids: %w", err)
+	}
+
+	query := `UPDATE discounts
+	           SET code = $1, kind = $2, value = $3, min_order_total = $4, starts_at = $5, ends_at = $6,
+	               usage_limit = $7, per_customer_limit = $8, product_ids = $9, active = $10,
+	               updated_at = CURRENT_TIMESTAMP
+	           WHERE id = $11
+	           RETURNING ` + discountColumns
+
+	updated, err := scanDiscount(r.db.QueryRow(query,
+		strings.ToUpper(discount.Code), discount.Kind, discount.Value, discount.MinOrderTotal,
+		discount.StartsAt, discount.EndsAt, discount.UsageLimit, discount.PerCustomerLimit,
+		productIDsJSON, discount.Active, id,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("discounts.Update: %w", err)
+	}
+	return updated, nil
+}
+
+// Delete — удалить промокод. Если 0 строк — ошибка "не найден", как в products.Delete.
+// Заказы не ломаются: код и сумма скидки сохранены в самом заказе,
+// discount_id обнуляется через ON DELETE SET NULL.
+func (r *DiscountSQLRepo) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM discounts WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("discounts.Delete: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("discounts.Delete rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("discounts.Delete: промокод с id=%s не найден", id)
+	}
+
+	return nil
+}
+
+// CountRedemptions — сколько раз покупатель с этим email уже применил промокод.
+func (r *DiscountSQLRepo) CountRedemptions(discountID, customerEmail string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM discount_redemptions
+	                       WHERE discount_id = $1 AND customer_key = LOWER($2)`,
+		discountID, customerEmail,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("discounts.CountRedemptions: %w", err)
+	}
+	return count, nil
+}
+
+// ────────────────────────────────────────────────
+// Хелперы (используются в OrderSQLRepo)
+// ────────────────────────────────────────────────
+
+// discountColumns — колонки промокода в порядке, который ждёт scanDiscount.
+const discountColumns = `id, code, kind, value, min_order_total, starts_at, ends_at,
+	usage_limit, per_customer_limit, product_ids, active, used_count, created_at`
+
+func scanDiscount(scanner interface{ Scan(dest ...any) error }) (*models.Discount, error) {
+	var d models.Discount
+	var startsAt, endsAt sql.NullTime
+	var usageLimit, perCustomerLimit sql.NullInt64
+	var productIDsJSON []byte
+
+	err := scanner.Scan(&d.ID, &d.Code, &d.Kind, &d.Value, &d.MinOrderTotal, &startsAt, &endsAt,
+		&usageLimit, &perCustomerLimit, &productIDsJSON, &d.Active, &d.UsedCount, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if startsAt.Valid {
+		d.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		d.EndsAt = &endsAt.Time
+	}
+	if usageLimit.Valid {
+		n := int(usageLimit.Int64)
+		d.UsageLimit = &n
+	}
+	if perCustomerLimit.Valid {
+		n := int(perCustomerLimit.Int64)
+		d.PerCustomerLimit = &n
+	}
+
+	d.ProductIDs = []string{}
+	if productIDsJSON != nil {
+		if err := json.Unmarshal(productIDsJSON, &d.ProductIDs); err != nil {
+			return nil, fmt.Errorf("unmarshal product ids: %w", err)
+		}
+	}
+	return &d, nil
+}
+
+// marshalProductIDs — []string → jsonb. nil превращаем в [], чтобы в БД не было null.
+func marshalProductIDs(ids []string) ([]byte, error) {
+	if ids == nil {
+		ids = []string{}
+	}
+	return json.Marshal(ids)
+}
+
+// redeemDiscount — записать применение промокода в транзакции заказа.
+//
+// Как читается:
+//  1. SELECT ... FOR UPDATE — блокируем промокод, параллельные заказы с ним ждут.
+//  2. used_count >= usage_limit → ErrDiscountLimitReached.
+//  3. Считаем применения этим email; >= per_customer_limit → ErrDiscountLimitReached.
+//  4. INSERT в discount_redemptions + used_count = used_count + 1.
+//
+// Остальные условия (даты, минимальная сумма, товары) проверяет хендлер —
+// они не зависят от параллельных заказов.
+func redeemDiscount(tx *sql.Tx, discountID, orderID, customerEmail string) error {
+	var usageLimit, perCustomerLimit sql.NullInt64
+	var usedCount int64
+	err := tx.QueryRow(`SELECT usage_limit, per_customer_limit, used_count
+	                     FROM discounts WHERE id = $1 FOR UPDATE`, discountID).
+		Scan(&usageLimit, &perCustomerLimit, &usedCount)
+	if err != nil {
+		return fmt.Errorf("lock discount: %w", err)
+	}
+
+	if usageLimit.Valid && usedCount >= usageLimit.Int64 {
+		return ErrDiscountLimitReached
+	}
+
+	if perCustomerLimit.Valid {
+		var customerCount int64
+		err := tx.QueryRow(`SELECT COUNT(*) FROM discount_redemptions
+		                     WHERE discount_id = $1 AND customer_key = LOWER($2)`,
+			discountID, customerEmail,
+		).Scan(&customerCount)
+		if err != nil {
+			return fmt.Errorf("count redemptions: %w", err)
+		}
+		if customerCount >= perCustomerLimit.Int64 {
+			return ErrDiscountLimitReached
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO discount_redemptions (discount_id, order_id, customer_key)
+	                   VALUES ($1, $2, LOWER($3))`, discountID, orderID, customerEmail)
+	if err != nil {
+		return fmt.Errorf("insert redemption: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE discounts SET used_count = used_count + 1 WHERE id = $1`, discountID)
+	if err != nil {
+		return fmt.Errorf("increment used_count: %w", err)
+	}
+	return nil
+}
+
+// releaseDiscount — вернуть применение промокода при отмене заказа.
+// Заказ без промокода (или уже отпущенный) — ничего не делаем.
+func releaseDiscount(tx *sql.Tx, orderID string) error {
+	var discountID string
+	err := tx.QueryRow(`DELETE FROM discount_redemptions WHERE order_id = $1 RETURNING discount_id`, orderID).
+		Scan(&discountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("delete redemption: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE discounts SET used_count = GREATEST(used_count - 1, 0) WHERE id = $1`, discountID)
+	if err != nil {
+		return fmt.Errorf("decrement used_count: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"socialsh/backend/internal/models"
+)
+
+// IdempotencySQLRepo — ключи идемпотентности поверх PostgreSQL (таблица idempotency_keys).
+type IdempotencySQLRepo struct {
+	db *sql.DB
+}
+
+func NewIdempotencySQLRepo(db *sql.DB) *IdempotencySQLRepo {
+	return &IdempotencySQLRepo{db: db}
+}
+
+// Begin — занять ключ под запрос.
+//
+// Как читается:
+//  1. Удаляем запись этого ключа, если она протухла: прошло больше суток
+//     или запрос "выполняется" дольше 5 минут (процесс упал посреди запроса).
+//  2. INSERT ... ON CONFLICT DO NOTHING RETURNING — если вставилось,
+//     ключ наш: возвращаем nil, запрос можно выполнять.
+//  3. Не вставилось — ключ уже есть, возвращаем существующую запись,
+//     middleware решит: повторить ответ, 409 или 422.
+func (r *IdempotencySQLRepo) Begin(rec *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	_, err := r.db.Exec(`DELETE FROM idempotency_keys
+	                      WHERE scope = $1 AND key = $2
+	                        AND (created_at < CURRENT_TIMESTAMP - INTERVAL '24 hours'
+	                             OR (status = 'in_progress' AND created_at < CURRENT_TIMESTAMP - INTERVAL '5 minutes'))`,
+		rec.Scope, rec.Key)
+	if err != nil {
+		return nil, fmt.Errorf("idempotency.Begin cleanup: %w", err)
+	}
+
+	err = r.db.QueryRow(`INSERT INTO idempotency_keys (scope, key, method, path, request_hash)
+	                      VALUES ($1, $2, $3, $4, $5)
+	                      ON CONFLICT (scope, key) DO NOTHING
+	                      RETURNING status, created_at`,
+		rec.Scope, rec.Key, rec.Method, rec.Path, rec.RequestHash,
+	).Scan(&rec.Status, &rec.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("idempotency.Begin insert: %w", err)
+	}
+
+	var existing models.IdempotencyRecord
+	err = r.db.QueryRow(`SELECT scope, key, method, path, request_hash, status,
+	                             COALESCE(response_status, 0), content_type, response_body, resource_id, created_at
+	                      FROM idempotency_keys WHERE scope = $1 AND key = $2`, rec.Scope, rec.Key,
+	).Scan(&existing.Scope, &existing.Key, &existing.Method, &existing.Path, &existing.RequestHash,
+		&existing.Status, &existing.ResponseStatus, &existing.ContentType, &existing.ResponseBody,
+		&existing.ResourceID, &existing.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("idempotency.Begin select: %w", err)
+	}
+	return &existing, nil
+}
+
+// Complete — сохранить ответ, чтобы повторы получали его же.
+func (r *IdempotencySQLRepo) Complete(scope, key string, status int, contentType string, body []byte) error {
+	_, err := r.db.Exec(`UPDATE idempotency_keys
+	                      SET status = 'completed', response_status = $3, content_type = $4, response_body = $5
+	                      WHERE scope = $1 AND key = $2`, scope, key, status, contentType, body)
+	if err != nil {
+		return fmt.Errorf("idempotency.Complete: %w", err)
+	}
+	return nil
+}
+
+// CompleteResource — завершить ключ, сохранив вместо ответа ID созданного объекта
+// (ответ несёт токены — им не место в БД; повтор соберёт ответ заново).
+func (r *IdempotencySQLRepo) CompleteResource(scope, key string, status int, resourceID string) error {
+	_, err := r.db.Exec(`UPDATE idempotency_keys
+	                      SET status = 'completed', response_status = $3, resource_id = $4
+	                      WHERE scope = $1 AND key = $2`, scope, key, status, resourceID)
+	if err != nil {
+		return fmt.Errorf("idempotency.CompleteResource: %w", err)
+	}
+	return nil
+}
+
+// Delete — освободить ключ (запрос упал с 5xx, повтор должен выполниться заново).
+func (r *IdempotencySQLRepo) Delete(scope, key string) error {
+	_, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		return fmt.Errorf("idempotency.Delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"socialsh/backend/internal/models"
+)
+
+// LoginThrottleSQLRepo — счётчики неудачных входов поверх PostgreSQL (таблица login_throttles).
+// Одна строка на ключ (kind, subject). Все времена берём из БД (CURRENT_TIMESTAMP),
+// чтобы расхождение часов между инстансами не влияло на блокировки.
+type LoginThrottleSQLRepo struct {
+	db *sql.DB
+}
+
+func NewLoginThrottleSQLRepo(db *sql.DB) *LoginThrottleSQLRepo {
+	return &LoginThrottleSQLRepo{db: db}
+}
+
+// CountAttempt — засчитать попытку до проверки пароля.
+//
+// Как читается:
+//
+//	INSERT ... ON CONFLICT DO UPDATE — атомарно и для первой попытки, и для следующих:
+//	строка блокируется на время UPDATE, и параллельные запросы получают разные номера.
+//	Ключ заблокирован → счётчик не трогаем (попытка не состоится).
+//	Последняя попытка была раньше, чем window назад → считаем заново с 1.
+//	Блокировка истекла, а счётчик дошёл до lockAfter → попытка номер lockAfter:
+//	ещё одна проверка пароля, а не новая блокировка до неё (иначе ключ можно
+//	держать закрытым вечно, и правильный пароль не помог бы). locked_until
+//	при этом сбрасываем — параллельные запросы получат уже lockAfter+1.
+//	Иначе failures + 1. RETURNING — номер попытки и остаток блокировки.
+func (r *LoginThrottleSQLRepo) CountAttempt(kind, subject string, window time.Duration, lockAfter int) (int, time.Duration, error) {
+	var attempts int
+	var lockedSeconds float64
+	err := r.db.QueryRow(`INSERT INTO login_throttles (kind, subject, failures, last_failure_at)
+	                       VALUES ($1, $2, 1, CURRENT_TIMESTAMP)
+	                       ON CONFLICT (kind, subject) DO UPDATE
+	                       SET failures = CASE
+	                               WHEN login_throttles.locked_until > CURRENT_TIMESTAMP THEN login_throttles.failures
+	                               WHEN login_throttles.last_failure_at < CURRENT_TIMESTAMP - $3 * INTERVAL '1 second' THEN 1
+	                               WHEN login_throttles.locked_until IS NOT NULL AND login_throttles.failures >= $4 THEN $4
+	                               ELSE login_throttles.failures + 1
+	                           END,
+	                           last_failure_at = CASE
+	                               WHEN login_throttles.locked_until > CURRENT_TIMESTAMP THEN login_throttles.last_failure_at
+	                               ELSE CURRENT_TIMESTAMP
+	                           END,
+	                           locked_until = CASE
+	                               WHEN login_throttles.locked_until > CURRENT_TIMESTAMP THEN login_throttles.locked_until
+	                           END
+	                       RETURNING failures,
+	                                 COALESCE(GREATEST(EXTRACT(EPOCH FROM locked_until - CURRENT_TIMESTAMP), 0), 0)`,
+		kind, subject, int64(window/time.Second), lockAfter,
+	).Scan(&attempts, &lockedSeconds)
+	if err != nil {
+		return 0, 0, fmt.Errorf("loginThrottle.CountAttempt: %w", err)
+	}
+	return attempts, time.Duration(lockedSeconds * float64(time.Second)), nil
+}
+
+// Refund — вернуть засчитанную попытку (она оказалась успешной).
+func (r *LoginThrottleSQLRepo) Refund(kind, subject string) error {
+	_, err := r.db.Exec(`UPDATE login_throttles SET failures = GREATEST(failures - 1, 0)
+	                      WHERE kind = $1 AND subject = $2`, kind, subject)
+	if err != nil {
+		return fmt.Errorf("loginThrottle.Refund: %w", err)
+	}
+	return nil
+}
+
+// Lock — заблокировать ключ на d. Уже действующую более длинную блокировку не укорачиваем.
+func (r *LoginThrottleSQLRepo) Lock(kind, subject string, d time.Duration) error {
+	_, err := r.db.Exec(`UPDATE login_throttles
+	                      SET locked_until = GREATEST(COALESCE(locked_until, CURRENT_TIMESTAMP),
+	                                                  CURRENT_TIMESTAMP + $3 * INTERVAL '1 millisecond')
+	                      WHERE kind = $1 AND subject = $2`,
+		kind, subject, d.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("loginThrottle.Lock: %w", err)
+	}
+	return nil
+}
+
+// Reset — удалить счётчик (успешный вход).
+func (r *LoginThrottleSQLRepo) Reset(kind, subject string) error {
+	_, err := r.db.Exec(`DELETE FROM login_throttles WHERE kind = $1 AND subject = $2`, kind, subject)
+	if err != nil {
+		return fmt.Errorf("loginThrottle.Reset: %w", err)
+	}
+	return nil
+}
+
+// ListActive — для админки: заблокированные сейчас ключи и ключи с неудачами
+// за последние window, свежие сверху. Только ключи видов kinds — в той же таблице
+// живёт и лимит запросов ссылок для входа, а это не блокировка входа.
+func (r *LoginThrottleSQLRepo) ListActive(kinds []string, window time.Duration) ([]models.LoginThrottle, error) {
+	rows, err := r.db.Query(`SELECT id, kind, subject, failures, last_failure_at, locked_until
+	                          FROM login_throttles
+	                          WHERE kind = ANY($1)
+	                            AND (locked_until > CURRENT_TIMESTAMP
+	                                 OR last_failure_at > CURRENT_TIMESTAMP - $2 * INTERVAL '1 second')
+	                          ORDER BY last_failure_at DESC`, pq.Array(kinds), int64(window/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("loginThrottle.ListActive query: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.LoginThrottle{}
+	for rows.Next() {
+		var t models.LoginThrottle
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Kind, &t.Subject, &t.Failures, &t.LastFailureAt, &lockedUntil); err != nil {
+			return nil, fmt.Errorf("loginThrottle.ListActive scan: %w", err)
+		}
+		if lockedUntil.Valid {
+			t.LockedUntil = &lockedUntil.Time
+		}
+		items = append(items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loginThrottle.ListActive rows: %w", err)
+	}
+	return items, nil
+}
+
+// Delete — снять блокировку и обнулить счётчик (админка).
+// Если 0 строк — ошибка "не найдена", как в products.Delete.
+func (r *LoginThrottleSQLRepo) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM login_throttles WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("loginThrottle.Delete: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("loginThrottle.Delete rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("loginThrottle.Delete: блокировка с id=%s не найдена", id)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"socialsh/backend/internal/models"
+)
+
+// Каждый вид ключа из models должен влезать в login_throttles.kind:
+// иначе CountAttempt падает, а лимит по этому ключу молча не действует.
+func TestLoginThrottleKinds(t *testing.T) {
+	repo := NewLoginThrottleSQLRepo(testDB(t))
+
+	kinds := []string{models.LoginThrottleEmail, models.LoginThrottleIP, models.LoginThrottleMagicLinkIP}
+	for _, kind := range kinds {
+		for want := 1; want <= 3; want++ {
+			n, locked, err := repo.CountAttempt(kind, "203.0.113.7", time.Hour, 10)
+			if err != nil {
+				t.Fatalf("CountAttempt(%s): %v", kind, err)
+			}
+			if n != want || locked != 0 {
+				t.Fatalf("CountAttempt(%s) = %d, %v; want %d, 0", kind, n, locked, want)
+			}
+		}
+	}
+
+	items, err := repo.ListActive([]string{models.LoginThrottleMagicLinkIP}, time.Hour)
+	if err != nil {
+		t.Fatalf("ListActive: %v", err)
+	}
+	if len(items) != 1 || items[0].Kind != models.LoginThrottleMagicLinkIP || items[0].Failures != 3 {
+		t.Fatalf("ListActive = %+v, want один ключ magic-link-ip с 3 попытками", items)
+	}
+}
+
+func TestLoginThrottleLock(t *testing.T) {
+	repo := NewLoginThrottleSQLRepo(testDB(t))
+	const kind, subject = models.LoginThrottleEmail, "buyer@example.com"
+
+	if _, _, err := repo.CountAttempt(kind, subject, time.Hour, 10); err != nil {
+		t.Fatalf("CountAttempt: %v", err)
+	}
+	if err := repo.Lock(kind, subject, time.Minute); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	// Пока ключ заблокирован, попытки не засчитываются
+	for i := 0; i < 3; i++ {
+		n, locked, err := repo.CountAttempt(kind, subject, time.Hour, 10)
+		if err != nil {
+			t.Fatalf("CountAttempt: %v", err)
+		}
+		if n != 1 || locked <= 0 || locked > time.Minute {
+			t.Fatalf("CountAttempt под блокировкой = %d, %v; want 1 и остаток до минуты", n, locked)
+		}
+	}
+
+	// Более короткая блокировка не укорачивает текущую
+	if err := repo.Lock(kind, subject, time.Second); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if _, locked, _ := repo.CountAttempt(kind, subject, time.Hour, 10); locked <= time.Second {
+		t.Fatalf("остаток = %v, блокировку укоротили", locked)
+	}
+
+	if err := repo.Reset(kind, subject); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if n, locked, _ := repo.CountAttempt(kind, subject, time.Hour, 10); n != 1 || locked != 0 {
+		t.Fatalf("после Reset = %d, %v; want 1, 0", n, locked)
+	}
+}
+
+// Блокировка истекла — первая попытка получает номер lockAfter (пароль проверяется),
+// а параллельная ей — уже lockAfter+1 и отказ.
+func TestLoginThrottleAfterLockExpires(t *testing.T) {
+	conn := testDB(t)
+	repo := NewLoginThrottleSQLRepo(conn)
+	const kind, subject, lockAfter = models.LoginThrottleEmail, "buyer@example.com", 3
+
+	for i := 0; i < lockAfter+2; i++ {
+		if _, _, err := repo.CountAttempt(kind, subject, time.Hour, lockAfter); err != nil {
+			t.Fatalf("CountAttempt: %v", err)
+		}
+	}
+	if err := repo.Lock(kind, subject, 15*time.Minute); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	// Часы не ждём: переносим блокировку в прошлое
+	if _, err := conn.Exec(`UPDATE login_throttles SET locked_until = CURRENT_TIMESTAMP - INTERVAL '1 second'
+	                        WHERE kind = $1 AND subject = $2`, kind, subject); err != nil {
+		t.Fatalf("expire lock: %v", err)
+	}
+
+	n, locked, err := repo.CountAttempt(kind, subject, time.Hour, lockAfter)
+	if err != nil {
+		t.Fatalf("CountAttempt: %v", err)
+	}
+	if n != lockAfter || locked != 0 {
+		t.Fatalf("после блокировки = %d, %v; want %d, 0", n, locked, lockAfter)
+	}
+	if n, _, _ := repo.CountAttempt(kind, subject, time.Hour, lockAfter); n != lockAfter+1 {
+		t.Fatalf("следующая попытка = %d, want %d", n, lockAfter+1)
+	}
+}
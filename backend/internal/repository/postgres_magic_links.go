@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"socialsh/backend/internal/models"
+)
+
+// ErrMagicLinkInvalid — ссылки нет, она просрочена, уже использована или открыта
+// не в том браузере (nonce не совпал). Хендлер эти случаи не различает.
+var ErrMagicLinkInvalid = errors.New("ссылка для входа недействительна")
+
+// MagicLinkSQLRepo — ссылки для входа без пароля поверх PostgreSQL (таблица magic_links).
+type MagicLinkSQLRepo struct {
+	db *sql.DB
+}
+
+func NewMagicLinkSQLRepo(db *sql.DB) *MagicLinkSQLRepo {
+	return &MagicLinkSQLRepo{db: db}
+}
+
+// Create — сохранить ссылку (jti, хеш nonce браузера, IP запроса), действующую ttl.
+// Срок считает БД — те же часы, по которым Consume его проверяет; link.ExpiresAt заполняется.
+func (r *MagicLinkSQLRepo) Create(link *models.MagicLink, ttl time.Duration) error {
+	err := r.db.QueryRow(`INSERT INTO magic_links (id, user_id, nonce_hash, ip, expires_at)
+	                       VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))
+	                       RETURNING expires_at, created_at`,
+		link.ID, link.UserID, link.NonceHash, link.IP, ttl.Seconds(),
+	).Scan(&link.ExpiresAt, &link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("magicLinks.Create: %w", err)
+	}
+	return nil
+}
+
+// CountRecent — сколько ссылок выдано юзеру за последние window (по часам БД, как created_at).
+func (r *MagicLinkSQLRepo) CountRecent(userID string, window time.Duration) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM magic_links
+	                       WHERE user_id = $1 AND created_at > CURRENT_TIMESTAMP - make_interval(secs => $2)`,
+		userID, window.Seconds()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("magicLinks.CountRecent: %w", err)
+	}
+	return count, nil
+}
+
+// Consume — погасить ссылку и вернуть её юзера.
+//
+// Как читается:
+//  1. UPDATE ... SET used_at WHERE id AND nonce_hash AND used_at IS NULL AND не просрочена
+//     RETURNING user_id. 0 строк → ErrMagicLinkInvalid.
+//     Чужой nonce ссылку не гасит — открыть её в правильном браузере всё ещё можно.
+//  2. Гасим остальные неиспользованные ссылки юзера — старые письма больше не работают.
+func (r *MagicLinkSQLRepo) Consume(id, nonceHash string) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("magicLinks.Consume begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Гасим ссылку
+	var userID string
+	err = tx.QueryRow(`UPDATE magic_links SET used_at = CURRENT_TIMESTAMP
+	                    WHERE id = $1 AND nonce_hash = $2
+	                      AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	                    RETURNING user_id`, id, nonceHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrMagicLinkInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("magicLinks.Consume use link: %w", err)
+	}
+
+	// 2. Остальные ссылки
+	_, err = tx.Exec(`UPDATE magic_links SET used_at = CURRENT_TIMESTAMP
+	                   WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return "", fmt.Errorf("magicLinks.Consume expire others: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("magicLinks.Consume commit: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"socialsh/backend/internal/models"
+)
+
+// NotificationSQLRepo — outbox уведомлений поверх PostgreSQL (таблица notification_outbox).
+type NotificationSQLRepo struct {
+	db *sql.DB
+}
+
+func NewNotificationSQLRepo(db *sql.DB) *NotificationSQLRepo {
+	return &NotificationSQLRepo{db: db}
+}
+
+// Enqueue — положить уведомление в очередь. Отправится при ближайшем проходе воркера.
+// Уведомление о событии, которое само пишется в БД (новый заказ), кладётся
+// в той же транзакции — см. OrderSQLRepo.Create.
+func (r *NotificationSQLRepo) Enqueue(n *models.Notification) error {
+	if err := insertNotification(r.db, n); err != nil {
+		return fmt.Errorf("notifications.Enqueue: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue — забрать до limit уведомлений, которым пора отправляться.
+//
+// Как читается:
+//  1. Внутренний SELECT берёт pending-записи с next_attempt_at <= now()
+//     и без активной аренды (locked_until в прошлом или NULL).
+//     FOR UPDATE SKIP LOCKED — два воркера (две реплики API) не возьмут одну запись.
+//  2. UPDATE ставит locked_until = now() + lease: пока аренда не истекла,
+//     запись никто другой не заберёт, даже если этот воркер упадёт посреди отправки.
+//  3. RETURNING отдаёт взятые записи.
+func (r *NotificationSQLRepo) ClaimDue(limit int, lease time.Duration) ([]models.Notification, error) {
+	query := `UPDATE notification_outbox
+	           SET locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+	           WHERE id IN (
+	               SELECT id FROM notification_outbox
+	               WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+	                 AND (locked_until IS NULL OR locked_until < CURRENT_TIMESTAMP)
+	               ORDER BY next_attempt_at
+	               LIMIT $1
+	               FOR UPDATE SKIP LOCKED
+	           )
+	           RETURNING ` + notificationColumns
+
+	rows, err := r.db.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("notifications.ClaimDue query: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.Channel, &n.Subject, &n.Body, &n.Status, &n.Attempts,
+			&n.LastError, &n.NextAttemptAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("notifications.ClaimDue scan: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("notifications.ClaimDue rows: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// MarkSent — отправлено успешно.
+func (r *NotificationSQLRepo) MarkSent(id string) error {
+	_, err := r.db.Exec(`UPDATE notification_outbox
+	                      SET status = 'sent', attempts = attempts + 1, sent_at = CURRENT_TIMESTAMP,
+	                          locked_until = NULL, last_error = ''
+	                      WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("notifications.MarkSent: %w", err)
+	}
+	return nil
+}
+
+// MarkRetry — отправка не удалась, пробуем снова через delay.
+// Время считает БД, как и в ClaimDue: часы реплик API могут расходиться с её часами.
+func (r *NotificationSQLRepo) MarkRetry(id string, delay time.Duration, lastError string) error {
+	_, err := r.db.Exec(`UPDATE notification_outbox
+	                      SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2),
+	                          last_error = $3, locked_until = NULL
+	                      WHERE id = $1`, id, delay.Seconds(), lastError)
+	if err != nil {
+		return fmt.Errorf("notifications.MarkRetry: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed — ретраи кончились, запись остаётся в таблице для разбора руками.
+func (r *NotificationSQLRepo) MarkFailed(id string, lastError string) error {
+	_, err := r.db.Exec(`UPDATE notification_outbox
+	                      SET status = 'failed', attempts = attempts + 1, last_error = $2, locked_until = NULL
+	                      WHERE id = $1`, id, lastError)
+	if err != nil {
+		return fmt.Errorf("notifications.MarkFailed: %w", err)
+	}
+	return nil
+}
+
+// insertNotification — INSERT в outbox; q — *sql.DB или транзакция вызывающего.
+func insertNotification(q queryer, n *models.Notification) error {
+	query := `INSERT INTO notification_outbox (channel, subject, body)
+	           VALUES ($1, $2, $3)
+	           RETURNING id, status, attempts, next_attempt_at, created_at`
+
+	return q.QueryRow(query, n.Channel, n.Subject, n.Body).
+		Scan(&n.ID, &n.Status, &n.Attempts, &n.NextAttemptAt, &n.CreatedAt)
+}
+
+// notificationColumns — колонки outbox в порядке Scan в ClaimDue.
+const notificationColumns = `id, channel, subject, body, status, attempts, last_error, next_attempt_at, created_at`
@@ -0,0 +1,522 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"socialsh/backend/internal/models"
+)
+
+// ErrInvalidStatusTransition — запрошенный переход статуса не разрешён models.OrderTransitions.
+// Хендлер отличает её через errors.Is и отдаёт 409, а не 500.
+var ErrInvalidStatusTransition = errors.New("недопустимый переход статуса заказа")
+
+// InsufficientStockError — на складе не хватило свободного остатка для позиции заказа.
+// Хендлер достаёт её через errors.As и отдаёт 409 с ID товара (и варианта, если он есть).
+type InsufficientStockError struct {
+	ProductID string
+	VariantID string
+}
+
+func (e *InsufficientStockError) Error() string {
+	if e.VariantID != "" {
+		return fmt.Sprintf("недостаточно варианта %s товара %s на складе", e.VariantID, e.ProductID)
+	}
+	return fmt.Sprintf("недостаточно товара %s на складе", e.ProductID)
+}
+
+// OrderSQLRepo — реализация OrderRepository поверх PostgreSQL.
+//
+// Заказ живёт в трёх таблицах: orders (шапка) + order_items (позиции)
+// + order_status_history (таймлайн статусов), поэтому запись всегда идёт
+// одной транзакцией — либо сохранилось всё, либо ничего.
+type OrderSQLRepo struct {
+	db *sql.DB
+}
+
+func NewOrderSQLRepo(db *sql.DB) *OrderSQLRepo {
+	return &OrderSQLRepo{db: db}
+}
+
+// Create — сохранить заказ вместе с позициями.
+//
+// Как читается:
+//  1. BEGIN — открываем транзакцию.
+//  2. INSERT INTO orders ... RETURNING id, created_at, updated_at
+//     → подхватываем сгенерированные поля в order.
+//  3. Для каждой позиции резервируем товар (или вариант, если позиция с VariantID):
+//     UPDATE products SET reserved = reserved + qty WHERE id = $1 AND stock - reserved >= qty.
+//     Условие в WHERE делает проверку и резерв атомарными — два параллельных
+//     заказа не смогут забрать одну и ту же последнюю штуку.
+//     0 строк обновлено → *InsufficientStockError.
+//  4. Для каждой позиции INSERT INTO order_items ... RETURNING id.
+//  5. Если заказ с промокодом (order.DiscountID) — redeemDiscount:
+//     блокирует промокод, перепроверяет лимиты и пишет discount_redemptions.
+//     Лимит уже выбран → ErrDiscountLimitReached.
+//  6. Первая запись в order_status_history: "" → pending.
+//  7. Уведомления от outbox(order) — INSERT в notification_outbox той же транзакцией.
+//     Заказ и уведомление о нём сохраняются вместе: не бывает ни заказа, о котором
+//     магазин не узнает (процесс упал между COMMIT и записью в outbox),
+//     ни уведомления об откатившемся заказе.
+//  8. COMMIT. Если на любом шаге ошибка — defer сделает ROLLBACK (и резервы откатятся).
+func (r *OrderSQLRepo) Create(order *models.Order, outbox func(order *models.Order) []models.Notification) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("orders.Create begin: %w", err)
+	}
+	// Rollback после Commit ничего не делает, так что defer безопасен
+	defer tx.Rollback()
+
+	if order.Status == "" {
+		order.Status = models.OrderStatusPending
+	}
+
+	// Пустой UserID (гость) → NULL в user_id
+	orderQuery := `INSERT INTO orders (user_id, status, total,
+	                                   customer_name, customer_email, customer_phone,
+	                                   customer_telegram, customer_address, comment,
+	                                   stock_reserved,
+	                                   subtotal, discount_id, discount_code, discount_amount,
+	                                   shipping_method_id, shipping_method, shipping_zone, shipping_cost)
+	                VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, $7, $8, $9, true,
+	                        $10, NULLIF($11, '')::uuid, $12, $13,
+	                        NULLIF($14, '')::uuid, $15, $16, $17)
+	                RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(orderQuery, order.UserID, order.Status, order.Total,
+		order.Customer.Name, order.Customer.Email, order.Customer.Phone,
+		order.Customer.Telegram, order.Customer.Address, order.Comment,
+		order.Subtotal, order.DiscountID, order.DiscountCode, order.DiscountAmount,
+		order.ShippingMethodID, order.ShippingMethod, order.ShippingZone, order.ShippingCost,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("orders.Create insert order: %w", err)
+	}
+
+	reserveProductQuery := `UPDATE products SET reserved = reserved + $1
+	                         WHERE id = $2 AND stock - reserved >= $1`
+	reserveVariantQuery := `UPDATE product_variants SET reserved = reserved + $1
+	                         WHERE id = $2 AND stock - reserved >= $1`
+
+	itemQuery := `INSERT INTO order_items (order_id, product_id, title, price, quantity, variant_id, sku, options)
+	               VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::uuid, $7, $8)
+	               RETURNING id`
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+
+		var result sql.Result
+		if item.VariantID != "" {
+			result, err = tx.Exec(reserveVariantQuery, item.Quantity, item.VariantID)
+		} else {
+			result, err = tx.Exec(reserveProductQuery, item.Quantity, item.ProductID)
+		}
+		if err != nil {
+			return fmt.Errorf("orders.Create reserve stock: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("orders.Create reserve rows affected: %w", err)
+		}
+		if affected == 0 {
+			return &InsufficientStockError{ProductID: item.ProductID, VariantID: item.VariantID}
+		}
+
+		optionsJSON, err := marshalOptions(item.Options)
+		if err != nil {
+			return fmt.Errorf("orders.Create marshal options: %w", err)
+		}
+
+		err = tx.QueryRow(itemQuery, item.OrderID, item.ProductID, item.Title, item.Price, item.Quantity,
+			item.VariantID, item.SKU, optionsJSON,
+		).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("orders.Create insert item: %w", err)
+		}
+	}
+
+	if order.DiscountID != "" {
+		if err := redeemDiscount(tx, order.DiscountID, order.ID, order.Customer.Email); err != nil {
+			return fmt.Errorf("orders.Create redeem discount: %w", err)
+		}
+	}
+
+	change, err := insertStatusChange(tx, order.ID, "", order.Status, "", "")
+	if err != nil {
+		return fmt.Errorf("orders.Create insert history: %w", err)
+	}
+	order.History = []models.OrderStatusChange{*change}
+
+	if outbox != nil {
+		for _, n := range outbox(order) {
+			if err := insertNotification(tx, &n); err != nil {
+				return fmt.Errorf("orders.Create enqueue notification: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("orders.Create commit: %w", err)
+	}
+
+	return nil
+}
+
+// List — список заказов для админки с фильтрами и пагинацией.
+//
+// Как читается:
+//  1. Базовый SELECT с LEFT JOIN users (гостевые заказы без юзера тоже попадают).
+//     Фильтр по покупателю ищет по email/имени аккаунта и по контактам в самом заказе.
+//  2. Фильтры добавляются динамически, как в products.List.
+//  3. ORDER BY created_at DESC + LIMIT/OFFSET.
+//  4. Позиции и историю догружаем двумя запросами на весь список (WHERE order_id = ANY($1)).
+func (r *OrderSQLRepo) List(filter models.OrderFilter) ([]models.Order, error) {
+	query := `SELECT ` + orderColumns + `
+	           FROM orders o
+	           LEFT JOIN users u ON u.id = o.user_id
+	           WHERE 1=1`
+	args := []interface{}{}
+	argIdx := 1
+
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND o.status = $%d", argIdx)
+		args = append(args, filter.Status)
+		argIdx++
+	}
+	if filter.From != nil {
+		query += fmt.Sprintf(" AND o.created_at >= $%d", argIdx)
+		args = append(args, *filter.From)
+		argIdx++
+	}
+	if filter.To != nil {
+		query += fmt.Sprintf(" AND o.created_at < $%d", argIdx)
+		args = append(args, *filter.To)
+		argIdx++
+	}
+	if filter.Customer != "" {
+		query += fmt.Sprintf(` AND (u.email ILIKE $%d OR u.name ILIKE $%d
+		                       OR o.customer_email ILIKE $%d OR o.customer_name ILIKE $%d)`,
+			argIdx, argIdx, argIdx, argIdx)
+		args = append(args, "%"+filter.Customer+"%")
+		argIdx++
+	}
+
+	query += fmt.Sprintf(" ORDER BY o.created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("orders.List query: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("orders.List scan: %w", err)
+		}
+		orders = append(orders, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("orders.List rows: %w", err)
+	}
+
+	if err := loadOrderDetails(r.db, orders); err != nil {
+		return nil, fmt.Errorf("orders.List: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetByID — один заказ с позициями и историей статусов.
+// Если заказа нет — sql.ErrNoRows (обёрнутая).
+func (r *OrderSQLRepo) GetByID(id string) (*models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders o WHERE o.id = $1`
+
+	o, err := scanOrder(r.db.QueryRow(query, id))
+	if err != nil {
+		return nil, fmt.Errorf("orders.GetByID: %w", err)
+	}
+
+	orders := []models.Order{*o}
+	if err := loadOrderDetails(r.db, orders); err != nil {
+		return nil, fmt.Errorf("orders.GetByID: %w", err)
+	}
+
+	return &orders[0], nil
+}
+
+// UpdateStatus — перевести заказ в новый статус.
+//
+// Как читается:
+//  1. BEGIN + SELECT status ... FOR UPDATE — блокируем строку заказа,
+//     чтобы два админа одновременно не провели два разных перехода.
+//  2. Проверяем переход по models.OrderTransitions → ErrInvalidStatusTransition.
+//  3. Двигаем склад, если заказ держит резерв (orders.stock_reserved):
+//     → cancelled: резерв снимается, товар снова доступен;
+//     → shipped: резерв списывается вместе с остатком (товар уехал со склада).
+//     При отмене ещё и возвращаем применение промокода (releaseDiscount),
+//     чтобы отменённый заказ не съедал лимит.
+//  4. UPDATE orders SET status, updated_at.
+//  5. INSERT в order_status_history (кто, когда, откуда → куда).
+//  6. COMMIT и отдаём свежий заказ.
+//
+// changedBy — ID админа; пустая строка = системный переход (пишется NULL).
+func (r *OrderSQLRepo) UpdateStatus(id, status, changedBy, comment string) (*models.Order, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("orders.UpdateStatus begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionOrder(tx, id, status, changedBy, comment); err != nil {
+		return nil, fmt.Errorf("orders.UpdateStatus: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("orders.UpdateStatus commit: %w", err)
+	}
+
+	return r.GetByID(id)
+}
+
+// transitionOrder — шаги 1-5 UpdateStatus (без BEGIN и COMMIT) внутри транзакции вызывающего
+// (ей же пользуется PaymentSQLRepo.Succeed: платёж и заказ меняются вместе).
+func transitionOrder(tx *sql.Tx, id, status, changedBy, comment string) error {
+	var current string
+	var stockReserved bool
+	err := tx.QueryRow(`SELECT status, stock_reserved FROM orders WHERE id = $1 FOR UPDATE`, id).
+		Scan(&current, &stockReserved)
+	if err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+
+	if !canTransition(current, status) {
+		return fmt.Errorf("%s → %s: %w", current, status, ErrInvalidStatusTransition)
+	}
+
+	if stockReserved && (status == models.OrderStatusCancelled || status == models.OrderStatusShipped) {
+		if err := settleReservation(tx, id, status == models.OrderStatusShipped); err != nil {
+			return fmt.Errorf("stock: %w", err)
+		}
+		stockReserved = false
+	}
+
+	if status == models.OrderStatusCancelled {
+		if err := releaseDiscount(tx, id); err != nil {
+			return fmt.Errorf("discount: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE orders SET status = $1, stock_reserved = $2, updated_at = CURRENT_TIMESTAMP
+	                   WHERE id = $3`, status, stockReserved, id)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if _, err := insertStatusChange(tx, id, current, status, changedBy, comment); err != nil {
+		return fmt.Errorf("insert history: %w", err)
+	}
+	return nil
+}
+
+// AttachGuestOrders — привязать гостевые заказы с этим email к аккаунту.
+//
+// Как читается:
+//
+//	UPDATE orders SET user_id = $1 WHERE user_id IS NULL AND LOWER(customer_email) = LOWER($2)
+//	→ RowsAffected = сколько заказов привязали.
+//
+// Трогаем только гостевые заказы (user_id IS NULL) — чужие аккаунты не переезжают.
+func (r *OrderSQLRepo) AttachGuestOrders(userID, email string) (int64, error) {
+	query := `UPDATE orders SET user_id = $1, updated_at = CURRENT_TIMESTAMP
+	           WHERE user_id IS NULL AND LOWER(customer_email) = LOWER($2)`
+
+	result, err := r.db.Exec(query, userID, email)
+	if err != nil {
+		return 0, fmt.Errorf("orders.AttachGuestOrders: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("orders.AttachGuestOrders rows affected: %w", err)
+	}
+	return affected, nil
+}
+
+// ────────────────────────────────────────────────
+// Хелперы (используются и в AccountSQLRepo)
+// ────────────────────────────────────────────────
+
+// orderColumns — колонки заказа в порядке, который ждёт scanOrder.
+// Таблица orders везде идёт под алиасом o.
+const orderColumns = `o.id, COALESCE(o.user_id::text, ''), o.status, o.total,
+	o.customer_name, o.customer_email, o.customer_phone, o.customer_telegram, o.customer_address,
+	o.comment, o.created_at, o.updated_at,
+	o.subtotal, o.discount_code, o.discount_amount,
+	COALESCE(o.shipping_method_id::text, ''), o.shipping_method, o.shipping_zone, o.shipping_cost`
+
+// scanOrder — Scan строки из orderColumns в models.Order.
+// scanner — это *sql.Row или *sql.Rows.
+func scanOrder(scanner interface{ Scan(dest ...any) error }) (*models.Order, error) {
+	var o models.Order
+	err := scanner.Scan(
+		&o.ID, &o.UserID, &o.Status, &o.Total,
+		&o.Customer.Name, &o.Customer.Email, &o.Customer.Phone, &o.Customer.Telegram, &o.Customer.Address,
+		&o.Comment, &o.CreatedAt, &o.UpdatedAt,
+		&o.Subtotal, &o.DiscountCode, &o.DiscountAmount,
+		&o.ShippingMethodID, &o.ShippingMethod, &o.ShippingZone, &o.ShippingCost,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// queryer — общий кусок *sql.DB и *sql.Tx, чтобы хелперы работали в обоих режимах.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// canTransition — разрешён ли переход from → to по models.OrderTransitions.
+func canTransition(from, to string) bool {
+	for _, allowed := range models.OrderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// settleReservation — снять резерв заказа со склада.
+// writeOff = true (отгрузка): уменьшаем и reserved, и stock — товар физически ушёл.
+// writeOff = false (отмена): уменьшаем только reserved — товар вернулся в продажу.
+// Позиции без варианта двигают склад товара, с вариантом — склад варианта.
+// Позиции одного товара/варианта суммируются, удалённые просто пропускаются.
+func settleReservation(tx *sql.Tx, orderID string, writeOff bool) error {
+	stockDelta := "0"
+	if writeOff {
+		stockDelta = "oi.qty"
+	}
+
+	productsQuery := `UPDATE products p
+	                   SET reserved = p.reserved - oi.qty,
+	                       stock = p.stock - ` + stockDelta + `
+	                   FROM (SELECT product_id, SUM(quantity) AS qty
+	                         FROM order_items WHERE order_id = $1 AND variant_id IS NULL
+	                         GROUP BY product_id) oi
+	                   WHERE p.id = oi.product_id`
+
+	if _, err := tx.Exec(productsQuery, orderID); err != nil {
+		return err
+	}
+
+	variantsQuery := `UPDATE product_variants v
+	                   SET reserved = v.reserved - oi.qty,
+	                       stock = v.stock - ` + stockDelta + `
+	                   FROM (SELECT variant_id, SUM(quantity) AS qty
+	                         FROM order_items WHERE order_id = $1 AND variant_id IS NOT NULL
+	                         GROUP BY variant_id) oi
+	                   WHERE v.id = oi.variant_id`
+
+	_, err := tx.Exec(variantsQuery, orderID)
+	return err
+}
+
+// insertStatusChange — добавить запись в order_status_history.
+// Пустые from/changedBy/comment пишутся как NULL.
+func insertStatusChange(q queryer, orderID, from, to, changedBy, comment string) (*models.OrderStatusChange, error) {
+	query := `INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, comment)
+	           VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, '')::uuid, NULLIF($5, ''))
+	           RETURNING id, created_at`
+
+	change := models.OrderStatusChange{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedBy:  changedBy,
+		Comment:    comment,
+	}
+	err := q.QueryRow(query, orderID, from, to, changedBy, comment).Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// loadOrderDetails — догружает Items и History для слайса заказов.
+// Два запроса на весь слайс (WHERE order_id = ANY($1)), а не по запросу на заказ.
+func loadOrderDetails(q queryer, orders []models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(orders))
+	byID := make(map[string]*models.Order, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+		orders[i].Items = []models.OrderItem{}
+		orders[i].History = []models.OrderStatusChange{}
+		byID[orders[i].ID] = &orders[i]
+	}
+
+	// Позиции
+	itemRows, err := q.Query(`SELECT id, order_id, product_id, title, price, quantity,
+	                                 COALESCE(variant_id::text, ''), sku, options
+	                           FROM order_items WHERE order_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query items: %w", err)
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item models.OrderItem
+		var optionsJSON []byte
+		err := itemRows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Title, &item.Price, &item.Quantity,
+			&item.VariantID, &item.SKU, &optionsJSON)
+		if err != nil {
+			return fmt.Errorf("scan item: %w", err)
+		}
+		if len(optionsJSON) > 0 {
+			if err := json.Unmarshal(optionsJSON, &item.Options); err != nil {
+				return fmt.Errorf("unmarshal item options: %w", err)
+			}
+		}
+		byID[item.OrderID].Items = append(byID[item.OrderID].Items, item)
+	}
+	if err := itemRows.Err(); err != nil {
+		return fmt.Errorf("item rows: %w", err)
+	}
+
+	// История статусов (по возрастанию — это таймлайн)
+	historyRows, err := q.Query(`SELECT id, order_id, COALESCE(from_status, ''), to_status,
+	                                    COALESCE(changed_by::text, ''), COALESCE(comment, ''), created_at
+	                              FROM order_status_history
+	                              WHERE order_id = ANY($1)
+	                              ORDER BY created_at`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query history: %w", err)
+	}
+	defer historyRows.Close()
+
+	for historyRows.Next() {
+		var h models.OrderStatusChange
+		err := historyRows.Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus, &h.ChangedBy, &h.Comment, &h.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("scan history: %w", err)
+		}
+		byID[h.OrderID].History = append(byID[h.OrderID].History, h)
+	}
+	if err := historyRows.Err(); err != nil {
+		return fmt.Errorf("history rows: %w", err)
+	}
+
+	return nil
+}
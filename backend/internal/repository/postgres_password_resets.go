@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"socialsh/backend/internal/models"
+)
+
+// ErrResetTokenInvalid — токена сброса нет, он просрочен или уже использован.
+// Хендлер не различает эти случаи — пользователю одна ошибка "ссылка недействительна".
+var ErrResetTokenInvalid = errors.New("токен сброса пароля недействителен")
+
+// PasswordResetSQLRepo — токены сброса пароля поверх PostgreSQL (таблица password_reset_tokens).
+type PasswordResetSQLRepo struct {
+	db *sql.DB
+}
+
+func NewPasswordResetSQLRepo(db *sql.DB) *PasswordResetSQLRepo {
+	return &PasswordResetSQLRepo{db: db}
+}
+
+// Create — сохранить новый токен (хеш, IP запроса), действующий ttl.
+// Срок считает БД — те же часы, по которым Consume его проверяет; token.ExpiresAt заполняется.
+func (r *PasswordResetSQLRepo) Create(token *models.PasswordResetToken, ttl time.Duration) error {
+	err := r.db.QueryRow(`INSERT INTO password_reset_tokens (user_id, token_hash, ip, expires_at)
+	                       VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+	                       RETURNING id, expires_at, created_at`,
+		token.UserID, token.TokenHash, token.IP, ttl.Seconds(),
+	).Scan(&token.ID, &token.ExpiresAt, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("passwordResets.Create: %w", err)
+	}
+	return nil
+}
+
+// CountRecent — сколько токенов выдано юзеру за последние window (по часам БД, как created_at).
+func (r *PasswordResetSQLRepo) CountRecent(userID string, window time.Duration) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM password_reset_tokens
+	                       WHERE user_id = $1 AND created_at > CURRENT_TIMESTAMP - make_interval(secs => $2)`,
+		userID, window.Seconds()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("passwordResets.CountRecent: %w", err)
+	}
+	return count, nil
+}
+
+// Consume — сброс пароля одной транзакцией.
+//
+// Как читается:
+//  1. UPDATE ... SET used_at WHERE token_hash AND used_at IS NULL AND не просрочен
+//     RETURNING user_id — гасим токен. 0 строк → ErrResetTokenInvalid.
+//     Два параллельных запроса с одним токеном: второй будет ждать строку
+//     и после коммита первого уже не пройдёт по used_at IS NULL.
+//  2. Ставим новый хеш пароля.
+//  3. Гасим остальные неиспользованные токены юзера — старые письма больше не работают.
+//  4. Отзываем все refresh-токены юзера — все входы завершены, включая чужие.
+func (r *PasswordResetSQLRepo) Consume(tokenHash, passwordHash string) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("passwordResets.Consume begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Гасим токен
+	var userID string
+	err = tx.QueryRow(`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP
+	                    WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	                    RETURNING user_id`, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrResetTokenInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("passwordResets.Consume use token: %w", err)
+	}
+
+	// 2. Новый пароль
+	if _, err := tx.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID); err != nil {
+		return "", fmt.Errorf("passwordResets.Consume update password: %w", err)
+	}
+
+	// 3. Остальные токены сброса
+	_, err = tx.Exec(`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP
+	                   WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return "", fmt.Errorf("passwordResets.Consume expire others: %w", err)
+	}
+
+	// 4. Все сессии
+	if err := revokeUserRefreshTokens(tx, userID); err != nil {
+		return "", fmt.Errorf("passwordResets.Consume revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("passwordResets.Consume commit: %w", err)
+	}
+	return userID, nil
+}
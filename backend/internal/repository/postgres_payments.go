@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"socialsh/backend/internal/models"
+)
+
+// PaymentSQLRepo — реализация PaymentRepository поверх PostgreSQL (таблица payments).
+type PaymentSQLRepo struct {
+	db *sql.DB
+}
+
+func NewPaymentSQLRepo(db *sql.DB) *PaymentSQLRepo {
+	return &PaymentSQLRepo{db: db}
+}
+
+// Create — завести платёж в статусе pending до обращения к провайдеру.
+// Наш ID уходит провайдеру как ключ идемпотентности.
+// У заказа уже есть pending-платёж → ошибка duplicate key (idx_payments_pending_order).
+func (r *PaymentSQLRepo) Create(p *models.Payment) error {
+	query := `INSERT INTO payments (order_id, provider, amount, currency)
+	           VALUES ($1, $2, $3, $4)
+	           RETURNING id, status, created_at, updated_at`
+
+	err := r.db.QueryRow(query, p.OrderID, p.Provider, p.Amount, p.Currency).
+		Scan(&p.ID, &p.Status, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("payments.Create: %w", err)
+	}
+	return nil
+}
+
+// SetConfirmation — сохранить ID платежа у провайдера и ссылку на оплату.
+func (r *PaymentSQLRepo) SetConfirmation(id, providerPaymentID, confirmationURL string) error {
+	_, err := r.db.Exec(`UPDATE payments
+	                      SET provider_payment_id = $1, confirmation_url = $2, updated_at = CURRENT_TIMESTAMP
+	                      WHERE id = $3`, providerPaymentID, confirmationURL, id)
+	if err != nil {
+		return fmt.Errorf("payments.SetConfirmation: %w", err)
+	}
+	return nil
+}
+
+// GetByProviderID — платёж по ID у провайдера (из вебхука). Не найден → sql.ErrNoRows (обёрнутая).
+func (r *PaymentSQLRepo) GetByProviderID(provider, providerPaymentID string) (*models.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments
+	           WHERE provider = $1 AND provider_payment_id = $2`
+
+	p, err := scanPayment(r.db.QueryRow(query, provider, providerPaymentID))
+	if err != nil {
+		return nil, fmt.Errorf("payments.GetByProviderID: %w", err)
+	}
+	return p, nil
+}
+
+// GetPendingByOrder — незавершённый платёж заказа. Такой у заказа максимум один
+// (уникальный индекс idx_payments_pending_order). Нет → sql.ErrNoRows (обёрнутая).
+func (r *PaymentSQLRepo) GetPendingByOrder(orderID string) (*models.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments
+	           WHERE order_id = $1 AND status = 'pending'`
+
+	p, err := scanPayment(r.db.QueryRow(query, orderID))
+	if err != nil {
+		return nil, fmt.Errorf("payments.GetPendingByOrder: %w", err)
+	}
+	return p, nil
+}
+
+// SetStatus — перевести платёж из pending в конечный статус.
+// Возвращает false, если платёж уже не pending (повторный вебхук) — это не ошибка.
+func (r *PaymentSQLRepo) SetStatus(id, status string) (bool, error) {
+	var updatedID string
+	err := r.db.QueryRow(`UPDATE payments SET status = $1, updated_at = CURRENT_TIMESTAMP
+	                       WHERE id = $2 AND status = 'pending'
+	                       RETURNING id`, status, id).Scan(&updatedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("payments.SetStatus: %w", err)
+	}
+	return true, nil
+}
+
+// Succeed — перевести pending-платёж в succeeded, а его заказ в paid одной транзакцией:
+// не бывает оплаченного платежа при неоплаченном заказе (ошибка на втором шаге
+// откатывает и первый, провайдер повторит вебхук и застанет платёж ещё pending).
+//
+// Как читается:
+//  1. UPDATE payments ... WHERE status = 'pending' RETURNING order_id —
+//     0 строк → false, nil: повторный вебхук, ничего не меняем.
+//  2. transitionOrder(→ paid, changedBy пустой = система, comment в историю).
+//     Переход запрещён (заказ успели отменить) → платёж всё равно succeeded
+//     (деньги пришли), заказ не трогаем, в ответ true и ErrInvalidStatusTransition (обёрнутая).
+//  3. COMMIT.
+func (r *PaymentSQLRepo) Succeed(id, comment string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("payments.Succeed begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	var orderID string
+	err = tx.QueryRow(`UPDATE payments SET status = 'succeeded', updated_at = CURRENT_TIMESTAMP
+	                    WHERE id = $1 AND status = 'pending'
+	                    RETURNING order_id`, id).Scan(&orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("payments.Succeed update payment: %w", err)
+	}
+
+	transitionErr := transitionOrder(tx, orderID, models.OrderStatusPaid, "", comment)
+	if transitionErr != nil && !errors.Is(transitionErr, ErrInvalidStatusTransition) {
+		return false, fmt.Errorf("payments.Succeed order: %w", transitionErr)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("payments.Succeed commit: %w", err)
+	}
+	if transitionErr != nil {
+		return true, fmt.Errorf("payments.Succeed order %s: %w", orderID, transitionErr)
+	}
+	return true, nil
+}
+
+// paymentColumns — колонки платежа в порядке, который ждёт scanPayment.
+const paymentColumns = `id, order_id, provider, provider_payment_id, status, amount, currency,
+	confirmation_url, created_at, updated_at`
+
+func scanPayment(scanner interface{ Scan(dest ...any) error }) (*models.Payment, error) {
+	var p models.Payment
+	err := scanner.Scan(&p.ID, &p.OrderID, &p.Provider, &p.ProviderPaymentID, &p.Status, &p.Amount,
+		&p.Currency, &p.ConfirmationURL, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
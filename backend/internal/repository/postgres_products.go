@@ -3,12 +3,16 @@ package repository
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"socialsh/backend/internal/models"
 )
 
+// ErrStockBelowReserved — попытка выставить остаток меньше уже зарезервированного.
+var ErrStockBelowReserved = errors.New("остаток меньше зарезервированного количества")
+
 // ProductSQLRepo — реализация ProductRepository поверх PostgreSQL.
 // Хранит в себе указатель на пул соединений (*sql.DB).
 type ProductSQLRepo struct {
@@ -40,7 +44,7 @@ func NewProductSQLRepo(db *sql.DB) *ProductSQLRepo {
 //     которые не всплывают в rows.Next().
 func (r *ProductSQLRepo) List(newOnly, saleOnly bool, page, limit int) ([]models.Product, error) {
 	// Базовый запрос
-	query := `SELECT id, slug, title, description, price, currency, images, is_new, is_on_sale
+	query := `SELECT ` + productColumns + `
 	           FROM products WHERE 1=1`
 	// args — слайс для параметризованных значений ($1, $2, ...)
 	args := []interface{}{}
@@ -74,26 +78,12 @@ func (r *ProductSQLRepo) List(newOnly, saleOnly bool, page, limit int) ([]models
 	// Собираем результат
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		var imagesJSON []byte // images хранится как jsonb → читаем в сырые байты
-
-		err := rows.Scan(
-			&p.ID, &p.Slug, &p.Title, &p.Description,
-			&p.Price, &p.Currency, &imagesJSON,
-			&p.IsNew, &p.IsOnSale,
-		)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, fmt.Errorf("products.List scan: %w", err)
 		}
 
-		// Десериализуем jsonb → []string
-		if imagesJSON != nil {
-			if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
-				return nil, fmt.Errorf("products.List unmarshal images: %w", err)
-			}
-		}
-
-		products = append(products, p)
+		products = append(products, *p)
 	}
 
 	// Проверяем ошибки, которые могли возникнуть во время итерации
@@ -101,6 +91,10 @@ func (r *ProductSQLRepo) List(newOnly, saleOnly bool, page, limit int) ([]models
 		return nil, fmt.Errorf("products.List rows: %w", err)
 	}
 
+	if err := loadVariants(r.db, products); err != nil {
+		return nil, fmt.Errorf("products.List: %w", err)
+	}
+
 	return products, nil
 }
 
@@ -111,29 +105,16 @@ func (r *ProductSQLRepo) List(newOnly, saleOnly bool, page, limit int) ([]models
 //  2. QueryRow → Scan. Если строка не найдена, sql вернёт sql.ErrNoRows.
 //  3. Оборачиваем ошибку, чтобы хендлер мог отличить «не найдено» от «БД упала».
 func (r *ProductSQLRepo) GetBySlug(slug string) (*models.Product, error) {
-	query := `SELECT id, slug, title, description, price, currency, images, is_new, is_on_sale
+	query := `SELECT ` + productColumns + `
 	           FROM products WHERE slug = $1 LIMIT 1`
 
-	var p models.Product
-	var imagesJSON []byte
-
-	err := r.db.QueryRow(query, slug).Scan(
-		&p.ID, &p.Slug, &p.Title, &p.Description,
-		&p.Price, &p.Currency, &imagesJSON,
-		&p.IsNew, &p.IsOnSale,
-	)
+	p, err := scanProduct(r.db.QueryRow(query, slug))
 	if err != nil {
 		// sql.ErrNoRows — товар не найден, это не серверная ошибка
 		return nil, fmt.Errorf("products.GetBySlug: %w", err)
 	}
 
-	if imagesJSON != nil {
-		if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
-			return nil, fmt.Errorf("products.GetBySlug unmarshal images: %w", err)
-		}
-	}
-
-	return &p, nil
+	return r.withVariants(p)
 }
 
 // ────────────────────────────────────────────────
@@ -147,7 +128,7 @@ func (r *ProductSQLRepo) GetBySlug(slug string) (*models.Product, error) {
 //	Тот же SELECT, что и List, но без WHERE-фильтров и пагинации.
 //	Админу нужно видеть всё.
 func (r *ProductSQLRepo) ListAll() ([]models.Product, error) {
-	query := `SELECT id, slug, title, description, price, currency, images, is_new, is_on_sale
+	query := `SELECT ` + productColumns + `
 	           FROM products ORDER BY id DESC`
 
 	rows, err := r.db.Query(query)
@@ -158,31 +139,22 @@ func (r *ProductSQLRepo) ListAll() ([]models.Product, error) {
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		var imagesJSON []byte
-
-		err := rows.Scan(
-			&p.ID, &p.Slug, &p.Title, &p.Description,
-			&p.Price, &p.Currency, &imagesJSON,
-			&p.IsNew, &p.IsOnSale,
-		)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, fmt.Errorf("products.ListAll scan: %w", err)
 		}
 
-		if imagesJSON != nil {
-			if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
-				return nil, fmt.Errorf("products.ListAll unmarshal images: %w", err)
-			}
-		}
-
-		products = append(products, p)
+		products = append(products, *p)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("products.ListAll rows: %w", err)
 	}
 
+	if err := loadVariants(r.db, products); err != nil {
+		return nil, fmt.Errorf("products.ListAll: %w", err)
+	}
+
 	return products, nil
 }
 
@@ -192,28 +164,15 @@ func (r *ProductSQLRepo) ListAll() ([]models.Product, error) {
 //
 //	Аналогично GetBySlug, но ищем по id.
 func (r *ProductSQLRepo) GetByID(id string) (*models.Product, error) {
-	query := `SELECT id, slug, title, description, price, currency, images, is_new, is_on_sale
+	query := `SELECT ` + productColumns + `
 	           FROM products WHERE id = $1 LIMIT 1`
 
-	var p models.Product
-	var imagesJSON []byte
-
-	err := r.db.QueryRow(query, id).Scan(
-		&p.ID, &p.Slug, &p.Title, &p.Description,
-		&p.Price, &p.Currency, &imagesJSON,
-		&p.IsNew, &p.IsOnSale,
-	)
+	p, err := scanProduct(r.db.QueryRow(query, id))
 	if err != nil {
 		return nil, fmt.Errorf("products.GetByID: %w", err)
 	}
 
-	if imagesJSON != nil {
-		if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
-			return nil, fmt.Errorf("products.GetByID unmarshal images: %w", err)
-		}
-	}
-
-	return &p, nil
+	return r.withVariants(p)
 }
 
 // Create — вставить новый товар в БД.
@@ -231,20 +190,25 @@ func (r *ProductSQLRepo) Create(product *models.Product) error {
 		return fmt.Errorf("products.Create marshal images: %w", err)
 	}
 
-	query := `INSERT INTO products (slug, title, description, price, currency, images, is_new, is_on_sale)
-	           VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	query := `INSERT INTO products (slug, title, description, price, currency, images, is_new, is_on_sale, stock, weight_grams)
+	           VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	           RETURNING id`
 
 	// Scan сразу пишет сгенерированный id в структуру
 	err = r.db.QueryRow(query,
 		product.Slug, product.Title, product.Description,
 		product.Price, product.Currency, imagesJSON,
-		product.IsNew, product.IsOnSale,
+		product.IsNew, product.IsOnSale, product.Stock, product.WeightGrams,
 	).Scan(&product.ID)
 	if err != nil {
 		return fmt.Errorf("products.Create: %w", err)
 	}
 
+	// У нового товара резервов и вариантов нет
+	product.Reserved = 0
+	product.InStock = product.Stock > 0
+	product.Variants = []models.ProductVariant{}
+
 	return nil
 }
 
@@ -252,10 +216,13 @@ func (r *ProductSQLRepo) Create(product *models.Product) error {
 //
 // Как читается:
 //  1. Сериализуем images → JSON (только если images не nil и не пустой).
-//  2. UPDATE ... WHERE id = $9 RETURNING ... — обновляем строку и сразу
+//  2. UPDATE ... WHERE id = $10 RETURNING ... — обновляем строку и сразу
 //     получаем обновлённые данные обратно (не делаем второй SELECT).
 //  3. Scan в новый Product и возвращаем указатель.
 //  4. Если строка не найдена (id не существует), вернётся sql.ErrNoRows.
+//
+// stock и reserved тут НЕ трогаем: остаток меняется только через SetStock,
+// а резерв — только заказами. Иначе PATCH без поля stock обнулил бы склад.
 func (r *ProductSQLRepo) Update(id string, product *models.Product) (*models.Product, error) {
 	// Если images не передан (nil) или пустой массив, не обновляем его
 	// Сначала получаем текущий товар, чтобы сохранить существующие images
@@ -277,34 +244,49 @@ func (r *ProductSQLRepo) Update(id string, product *models.Product) (*models.Pro
 	query := `UPDATE products
 	           SET slug = $1, title = $2, description = $3,
 	               price = $4, currency = $5, images = $6,
-	               is_new = $7, is_on_sale = $8
-	           WHERE id = $9
-	           RETURNING id, slug, title, description, price, currency, images, is_new, is_on_sale`
-
-	var updated models.Product
-	var updatedImagesJSON []byte
+	               is_new = $7, is_on_sale = $8, weight_grams = $9
+	           WHERE id = $10
+	           RETURNING ` + productColumns
 
-	err = r.db.QueryRow(query,
+	updated, err := scanProduct(r.db.QueryRow(query,
 		product.Slug, product.Title, product.Description,
 		product.Price, product.Currency, imagesJSON,
-		product.IsNew, product.IsOnSale,
+		product.IsNew, product.IsOnSale, product.WeightGrams,
 		id,
-	).Scan(
-		&updated.ID, &updated.Slug, &updated.Title, &updated.Description,
-		&updated.Price, &updated.Currency, &updatedImagesJSON,
-		&updated.IsNew, &updated.IsOnSale,
-	)
+	))
 	if err != nil {
 		return nil, fmt.Errorf("products.Update: %w", err)
 	}
 
-	if updatedImagesJSON != nil {
-		if err := json.Unmarshal(updatedImagesJSON, &updated.Images); err != nil {
-			return nil, fmt.Errorf("products.Update unmarshal images: %w", err)
-		}
+	return r.withVariants(updated)
+}
+
+// SetStock — выставить остаток товара на складе.
+//
+// Как читается:
+//
+//	UPDATE products SET stock = $1 WHERE id = $2 AND reserved <= $1 RETURNING ...
+//	Нельзя поставить остаток меньше, чем уже зарезервировано заказами,
+//	иначе нечем будет отгружать. В этом случае строка не обновится →
+//	проверяем что товар вообще есть и возвращаем ErrStockBelowReserved.
+func (r *ProductSQLRepo) SetStock(id string, stock int) (*models.Product, error) {
+	query := `UPDATE products SET stock = $1, updated_at = CURRENT_TIMESTAMP
+	           WHERE id = $2 AND reserved <= $1
+	           RETURNING ` + productColumns
+
+	p, err := scanProduct(r.db.QueryRow(query, stock, id))
+	if err == nil {
+		return r.withVariants(p)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("products.SetStock: %w", err)
 	}
 
-	return &updated, nil
+	// Строка не обновилась: либо товара нет, либо остаток меньше резерва
+	if _, err := r.GetByID(id); err != nil {
+		return nil, fmt.Errorf("products.SetStock: %w", err)
+	}
+	return nil, fmt.Errorf("products.SetStock: %w", ErrStockBelowReserved)
 }
 
 // Delete — удалить товар по id.
@@ -334,29 +316,44 @@ func (r *ProductSQLRepo) Delete(id string) error {
 }
 
 // ────────────────────────────────────────────────
-// Хелпер: scanProduct — вынести повторяющийся Scan в одно место.
-// Пока не используется (всё inline), но если задолбает копипаста —
-// раскомментируй и замени все Scan-блоки вызовом scanProduct.
+// Хелпер: scanProduct — повторяющийся Scan в одном месте.
+// Колонки берутся из productColumns, порядок должен совпадать.
 // ────────────────────────────────────────────────
 
-// func scanProduct(scanner interface{ Scan(dest ...any) error }) (*models.Product, error) {
-// 	var p models.Product
-// 	var imagesJSON []byte
-// 	err := scanner.Scan(
-// 		&p.ID, &p.Slug, &p.Title, &p.Description,
-// 		&p.Price, &p.Currency, &imagesJSON,
-// 		&p.IsNew, &p.IsOnSale,
-// 	)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	if imagesJSON != nil {
-// 		if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
-// 			return nil, err
-// 		}
-// 	}
-// 	return &p, nil
-// }
+// productColumns — колонки товара в порядке, который ждёт scanProduct.
+const productColumns = `id, slug, title, description, price, currency, images, is_new, is_on_sale, stock, reserved, weight_grams`
+
+func scanProduct(scanner interface{ Scan(dest ...any) error }) (*models.Product, error) {
+	var p models.Product
+	var imagesJSON []byte // images хранится как jsonb → читаем в сырые байты
+	err := scanner.Scan(
+		&p.ID, &p.Slug, &p.Title, &p.Description,
+		&p.Price, &p.Currency, &imagesJSON,
+		&p.IsNew, &p.IsOnSale,
+		&p.Stock, &p.Reserved, &p.WeightGrams,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// Десериализуем jsonb → []string
+	if imagesJSON != nil {
+		if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
+			return nil, fmt.Errorf("unmarshal images: %w", err)
+		}
+	}
+	// Свободный остаток = на складе минус зарезервировано неотгруженными заказами
+	p.InStock = p.Stock-p.Reserved > 0
+	return &p, nil
+}
+
+// withVariants — догрузить варианты одному товару (обёртка над loadVariants).
+func (r *ProductSQLRepo) withVariants(p *models.Product) (*models.Product, error) {
+	products := []models.Product{*p}
+	if err := loadVariants(r.db, products); err != nil {
+		return nil, fmt.Errorf("products load variants: %w", err)
+	}
+	return &products[0], nil
+}
 
 // Search — поиск товаров по названию (без учёта регистра, частичное совпадение).
 //
@@ -370,7 +367,7 @@ func (r *ProductSQLRepo) Search(query string, page, limit int) ([]models.Product
 	searchTerm := "%" + strings.ReplaceAll(query, "%", "\\%") + "%"
 	offset := (page - 1) * limit
 
-	querySQL := `SELECT id, slug, title, description, price, currency, images, is_new, is_on_sale
+	querySQL := `SELECT ` + productColumns + `
 	              FROM products
 	              WHERE title ILIKE $1 OR description ILIKE $1
 	              ORDER BY 
@@ -393,31 +390,22 @@ func (r *ProductSQLRepo) Search(query string, page, limit int) ([]models.Product
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		var imagesJSON []byte
-
-		err := rows.Scan(
-			&p.ID, &p.Slug, &p.Title, &p.Description,
-			&p.Price, &p.Currency, &imagesJSON,
-			&p.IsNew, &p.IsOnSale,
-		)
+		p, err := scanProduct(rows)
 		if err != nil {
 			return nil, fmt.Errorf("products.Search scan: %w", err)
 		}
 
-		if imagesJSON != nil {
-			if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
-				return nil, fmt.Errorf("products.Search unmarshal images: %w", err)
-			}
-		}
-
-		products = append(products, p)
+		products = append(products, *p)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("products.Search rows: %w", err)
 	}
 
+	if err := loadVariants(r.db, products); err != nil {
+		return nil, fmt.Errorf("products.Search: %w", err)
+	}
+
 	return products, nil
 }
 
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"socialsh/backend/internal/models"
+)
+
+var (
+	// ErrRefreshTokenInvalid — токена нет в БД, хеш не совпал, он отозван или просрочен.
+	ErrRefreshTokenInvalid = errors.New("refresh-токен недействителен")
+	// ErrRefreshTokenReused — предъявлен уже обменянный токен. Значит, его копия
+	// у кого-то ещё: вся семья к этому моменту отозвана.
+	ErrRefreshTokenReused = errors.New("refresh-токен уже был использован")
+	// ErrSessionNotFound — у юзера нет активной сессии с таким id.
+	ErrSessionNotFound = errors.New("сессия не найдена")
+)
+
+// RefreshTokenSQLRepo — refresh-токены поверх PostgreSQL (таблица refresh_tokens).
+type RefreshTokenSQLRepo struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenSQLRepo(db *sql.DB) *RefreshTokenSQLRepo {
+	return &RefreshTokenSQLRepo{db: db}
+}
+
+// Create — сохранить первый токен новой семьи (логин/регистрация).
+// ID и FamilyID задаёт вызывающий: они уже зашиты в подписанный JWT.
+func (r *RefreshTokenSQLRepo) Create(token *models.RefreshToken) error {
+	if err := insertRefreshToken(r.db, token); err != nil {
+		return fmt.Errorf("refreshTokens.Create: %w", err)
+	}
+	return nil
+}
+
+// Rotate — обменять токен oldID на next.
+//
+// Как читается:
+//  1. SELECT ... FOR UPDATE — два параллельных рефреша одним токеном
+//     выполняются по очереди, второй увидит rotated_at.
+//  2. Нет строки, хеш не совпал, отозван или просрочен → ErrRefreshTokenInvalid.
+//  3. Уже обменян (rotated_at) → повторное использование: отзываем всю семью,
+//     коммитим и возвращаем ErrRefreshTokenReused.
+//  4. Иначе помечаем старый rotated и вставляем next. next должен быть
+//     той же семьи и того же юзера, что и старый, — иначе ErrRefreshTokenInvalid.
+func (r *RefreshTokenSQLRepo) Rotate(oldID, oldHash string, next *models.RefreshToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("refreshTokens.Rotate begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Блокируем старый токен
+	var familyID, userID, tokenHash string
+	var expiresAt time.Time
+	var rotatedAt, revokedAt sql.NullTime
+	err = tx.QueryRow(`SELECT family_id, user_id, token_hash, expires_at, rotated_at, revoked_at
+	                    FROM refresh_tokens WHERE id = $1 FOR UPDATE`, oldID).
+		Scan(&familyID, &userID, &tokenHash, &expiresAt, &rotatedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("refreshTokens.Rotate lock: %w", err)
+	}
+
+	// 2. Проверки
+	if subtle.ConstantTimeCompare([]byte(tokenHash), []byte(oldHash)) != 1 {
+		return ErrRefreshTokenInvalid
+	}
+	if revokedAt.Valid || time.Now().After(expiresAt) {
+		return ErrRefreshTokenInvalid
+	}
+	if next.FamilyID != familyID || next.UserID != userID {
+		return ErrRefreshTokenInvalid
+	}
+
+	// 3. Повторное использование обменянного токена
+	if rotatedAt.Valid {
+		if err := revokeFamily(tx, familyID); err != nil {
+			return fmt.Errorf("refreshTokens.Rotate revoke family: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("refreshTokens.Rotate commit: %w", err)
+		}
+		return ErrRefreshTokenReused
+	}
+
+	// 4. Обмен
+	_, err = tx.Exec(`UPDATE refresh_tokens
+	                   SET rotated_at = CURRENT_TIMESTAMP, last_used_at = CURRENT_TIMESTAMP
+	                   WHERE id = $1`, oldID)
+	if err != nil {
+		return fmt.Errorf("refreshTokens.Rotate mark rotated: %w", err)
+	}
+
+	if err := insertRefreshToken(tx, next); err != nil {
+		return fmt.Errorf("refreshTokens.Rotate insert: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("refreshTokens.Rotate commit: %w", err)
+	}
+	return nil
+}
+
+// RevokeFamily — отозвать все токены семьи (logout одного входа).
+// Уже отозванные не трогаем — revoked_at остаётся временем первого отзыва.
+func (r *RefreshTokenSQLRepo) RevokeFamily(familyID string) error {
+	if err := revokeFamily(r.db, familyID); err != nil {
+		return fmt.Errorf("refreshTokens.RevokeFamily: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser — отозвать все токены пользователя (выход со всех устройств).
+func (r *RefreshTokenSQLRepo) RevokeAllForUser(userID string) error {
+	if err := revokeUserRefreshTokens(r.db, userID); err != nil {
+		return fmt.Errorf("refreshTokens.RevokeAllForUser: %w", err)
+	}
+	return nil
+}
+
+// ────────────────────────────────────────────────
+// Сессии
+// Сессия = семья refresh-токенов. Активна, пока у семьи есть последний
+// (ещё не обменянный) токен, не отозванный и не просроченный.
+// ────────────────────────────────────────────────
+
+// ListSessions — активные сессии юзера, последние использованные сверху.
+//
+// Как читается:
+//
+//	head — актуальный токен каждой семьи (rotated_at IS NULL): из него берём
+//	устройство, IP, срок и время последнего рефреша (created_at).
+//	Время логина — created_at самого первого токена семьи.
+func (r *RefreshTokenSQLRepo) ListSessions(userID string) ([]models.Session, error) {
+	rows, err := r.db.Query(`SELECT head.family_id, head.user_agent, head.ip,
+	                                (SELECT MIN(created_at) FROM refresh_tokens f WHERE f.family_id = head.family_id),
+	                                head.created_at, head.expires_at
+	                          FROM refresh_tokens head
+	                          WHERE head.user_id = $1
+	                            AND head.rotated_at IS NULL AND head.revoked_at IS NULL
+	                            AND head.expires_at > CURRENT_TIMESTAMP
+	                          ORDER BY head.created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("refreshTokens.ListSessions query: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.SignedInAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("refreshTokens.ListSessions scan: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("refreshTokens.ListSessions rows: %w", err)
+	}
+	return sessions, nil
+}
+
+// IsSessionActive — не отозвана ли сессия (семья). Вызывается на каждый
+// защищённый запрос, поэтому только EXISTS по индексу family_id.
+func (r *RefreshTokenSQLRepo) IsSessionActive(familyID string) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(`SELECT EXISTS (
+	                           SELECT 1 FROM refresh_tokens
+	                           WHERE family_id = $1 AND revoked_at IS NULL
+	                             AND expires_at > CURRENT_TIMESTAMP
+	                       )`, familyID).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("refreshTokens.IsSessionActive: %w", err)
+	}
+	return active, nil
+}
+
+// RevokeSession — отозвать одну сессию юзера. Чужую или уже отозванную не найдём → ErrSessionNotFound.
+func (r *RefreshTokenSQLRepo) RevokeSession(userID, familyID string) error {
+	result, err := r.db.Exec(`UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+	                           WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL`, userID, familyID)
+	if err != nil {
+		return fmt.Errorf("refreshTokens.RevokeSession: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("refreshTokens.RevokeSession rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions — выйти на всех устройствах, кроме текущего.
+func (r *RefreshTokenSQLRepo) RevokeOtherSessions(userID, keepFamilyID string) error {
+	if err := revokeOtherRefreshTokens(r.db, userID, keepFamilyID); err != nil {
+		return fmt.Errorf("refreshTokens.RevokeOtherSessions: %w", err)
+	}
+	return nil
+}
+
+// ────────────────────────────────────────────────
+// Хелперы
+// ────────────────────────────────────────────────
+
+// execer — общее у *sql.DB и *sql.Tx, чтобы хелперы работали и в транзакции, и без.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func insertRefreshToken(db execer, token *models.RefreshToken) error {
+	return db.QueryRow(`INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, user_agent, ip, auth_time, mfa, expires_at)
+	                    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	                    RETURNING created_at`,
+		token.ID, token.FamilyID, token.UserID, token.TokenHash, token.UserAgent, token.IP, token.AuthTime, token.MFA, token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+}
+
+func revokeFamily(db execer, familyID string) error {
+	_, err := db.Exec(`UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+	                    WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	return err
+}
+
+// revokeUserRefreshTokens — отозвать все токены юзера (используется и при сбросе пароля).
+func revokeUserRefreshTokens(db execer, userID string) error {
+	_, err := db.Exec(`UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+	                    WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	return err
+}
+
+// revokeOtherRefreshTokens — отозвать все токены юзера, кроме семьи keepFamilyID
+// (текущий вход остаётся, остальные устройства разлогиниваются).
+func revokeOtherRefreshTokens(db execer, userID, keepFamilyID string) error {
+	_, err := db.Exec(`UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+	                    WHERE user_id = $1 AND family_id::text <> $2 AND revoked_at IS NULL`, userID, keepFamilyID)
+	return err
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"socialsh/backend/internal/models"
+)
+
+// ShippingSQLRepo — реализация ShippingRepository поверх PostgreSQL.
+// Правила цены (rules) хранятся в jsonb прямо в строке способа доставки —
+// их немного, и читаются они всегда вместе со способом.
+type ShippingSQLRepo struct {
+	db *sql.DB
+}
+
+func NewShippingSQLRepo(db *sql.DB) *ShippingSQLRepo {
+	return &ShippingSQLRepo{db: db}
+}
+
+// ListActive — включённые способы для витрины, в порядке sort_order.
+func (r *ShippingSQLRepo) ListActive() ([]models.ShippingMethod, error) {
+	return r.list(`SELECT `+shippingColumns+` FROM shipping_methods
+	                WHERE active = true ORDER BY sort_order, title`, "shipping.ListActive")
+}
+
+// ListAll — все способы для админки.
+func (r *ShippingSQLRepo) ListAll() ([]models.ShippingMethod, error) {
+	return r.list(`SELECT `+shippingColumns+` FROM shipping_methods
+	                ORDER BY sort_order, title`, "shipping.ListAll")
+}
+
+func (r *ShippingSQLRepo) list(query, op string) ([]models.ShippingMethod, error) {
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s query: %w", op, err)
+	}
+	defer rows.Close()
+
+	methods := []models.ShippingMethod{}
+	for rows.Next() {
+		m, err := scanShippingMethod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s scan: %w", op, err)
+		}
+		methods = append(methods, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s rows: %w", op, err)
+	}
+
+	return methods, nil
+}
+
+// GetByID — способ доставки по UUID. Не найден → sql.ErrNoRows (обёрнутая).
+func (r *ShippingSQLRepo) GetByID(id string) (*models.ShippingMethod, error) {
+	m, err := scanShippingMethod(r.db.QueryRow(`SELECT `+shippingColumns+` FROM shipping_methods WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("shipping.GetByID: %w", err)
+	}
+	return m, nil
+}
+
+// Create — новый способ доставки. Дубликат code → unique_violation (23505).
+func (r *ShippingSQLRepo) Create(method *models.ShippingMethod) error {
+	rulesJSON, err := marshalShippingRules(method.Rules)
+	if err != nil {
+		return fmt.Errorf("shipping.Create marshal rules: %w", err)
+	}
+
+	query := `INSERT INTO shipping_methods (code, title, kind, rules, free_from, active, sort_order)
+	           VALUES ($1, $2, $3, $4, $5, $6, $7)
+	           RETURNING id`
+
+	err = r.db.QueryRow(query,
+		method.Code, method.Title, method.Kind, rulesJSON, method.FreeFrom, method.Active, method.SortOrder,
+	).Scan(&method.ID)
+	if err != nil {
+		return fmt.Errorf("shipping.Create: %w", err)
+	}
+
+	if method.Rules == nil {
+		method.Rules = []models.ShippingRule{}
+	}
+	return nil
+}
+
+// Update — перезаписать способ доставки целиком.
+func (r *ShippingSQLRepo) Update(id string, method *models.ShippingMethod) (*models.ShippingMethod, error) {
+	rulesJSON, err := marshalShippingRules(method.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("shipping.Update marshal rules: %w", err)
+	}
+
+	query := `UPDATE shipping_methods
+	           SET code = $1, title = $2, kind = $3, rules = $4, free_from = $5, active = $6, sort_order = $7,
+	               updated_at = CURRENT_TIMESTAMP
+	           WHERE id = $8
+	           RETURNING ` + shippingColumns
+
+	updated, err := scanShippingMethod(r.db.QueryRow(query,
+		method.Code, method.Title, method.Kind, rulesJSON, method.FreeFrom, method.Active, method.SortOrder, id,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("shipping.Update: %w", err)
+	}
+	return updated, nil
+}
+
+// Delete — удалить способ доставки. Если 0 строк — ошибка "не найден", как в products.Delete.
+// Заказы не ломаются: название и стоимость доставки сохранены в самом заказе.
+func (r *ShippingSQLRepo) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM shipping_methods WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("shipping.Delete: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("shipping.Delete rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("shipping.Delete: способ доставки с id=%s не найден", id)
+	}
+
+	return nil
+}
+
+// ────────────────────────────────────────────────
+// Хелперы
+// ────────────────────────────────────────────────
+
+// shippingColumns — колонки способа доставки в порядке, который ждёт scanShippingMethod.
+const shippingColumns = `id, code, title, kind, rules, free_from, active, sort_order`
+
+func scanShippingMethod(scanner interface{ Scan(dest ...any) error }) (*models.ShippingMethod, error) {
+	var m models.ShippingMethod
+	var rulesJSON []byte
+	var freeFrom sql.NullInt64
+
+	err := scanner.Scan(&m.ID, &m.Code, &m.Title, &m.Kind, &rulesJSON, &freeFrom, &m.Active, &m.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Rules = []models.ShippingRule{}
+	if rulesJSON != nil {
+		if err := json.Unmarshal(rulesJSON, &m.Rules); err != nil {
+			return nil, fmt.Errorf("unmarshal rules: %w", err)
+		}
+	}
+	if freeFrom.Valid {
+		m.FreeFrom = &freeFrom.Int64
+	}
+	return &m, nil
+}
+
+// marshalShippingRules — []ShippingRule → jsonb. nil превращаем в [], чтобы в БД не было null.
+func marshalShippingRules(rules []models.ShippingRule) ([]byte, error) {
+	if rules == nil {
+		rules = []models.ShippingRule{}
+	}
+	return json.Marshal(rules)
+}
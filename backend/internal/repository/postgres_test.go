@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"socialsh/backend/internal/db"
+)
+
+// testDB — чистая схема из sql.sql в отдельном search_path настоящего Postgres.
+// Адрес берётся из TEST_DATABASE_URL; без него тесты репозиториев пропускаются
+// (go test ./... должен проходить и без базы).
+//
+// Как читается:
+//  1. CREATE SCHEMA со случайным именем — тесты не трогают чужие таблицы.
+//  2. Второе подключение с search_path=<схема> — все запросы репозитория идут туда.
+//  3. sql.sql целиком одним Exec, после теста DROP SCHEMA ... CASCADE.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL не задан — тест репозитория на Postgres пропущен")
+	}
+
+	admin, err := db.OpenPostgres(dsn)
+	if err != nil {
+		t.Fatalf("OpenPostgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	// lib/pq передаёт незнакомые параметры DSN серверу как run-time параметры
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	conn, err := db.OpenPostgres(dsn)
+	if err != nil {
+		t.Fatalf("OpenPostgres(%s): %v", schema, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ddl, err := os.ReadFile("../../../sql.sql")
+	if err != nil {
+		t.Fatalf("read sql.sql: %v", err)
+	}
+	if _, err := conn.Exec(string(ddl)); err != nil {
+		t.Fatalf("apply sql.sql: %v", err)
+	}
+	return conn
+}
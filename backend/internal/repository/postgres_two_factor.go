@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"socialsh/backend/internal/models"
+)
+
+// TwoFactorSQLRepo — TOTP (колонки totp_* в users) и коды восстановления (таблица recovery_codes).
+type TwoFactorSQLRepo struct {
+	db *sql.DB
+}
+
+func NewTwoFactorSQLRepo(db *sql.DB) *TwoFactorSQLRepo {
+	return &TwoFactorSQLRepo{db: db}
+}
+
+// Get — состояние 2FA юзера. Нет юзера — sql.ErrNoRows (обёрнутая).
+func (r *TwoFactorSQLRepo) Get(userID string) (*models.TwoFactorState, error) {
+	var st models.TwoFactorState
+	var secret sql.NullString
+	var lastStep sql.NullInt64
+	err := r.db.QueryRow(`SELECT totp_secret, totp_enabled_at IS NOT NULL, totp_last_step
+	                       FROM users WHERE id = $1`, userID).
+		Scan(&secret, &st.Enabled, &lastStep)
+	if err != nil {
+		return nil, fmt.Errorf("twoFactor.Get: %w", err)
+	}
+	st.Secret = secret.String
+	st.LastStep = lastStep.Int64
+	return &st, nil
+}
+
+// SetPendingSecret — новый секрет для настройки. Если 2FA уже включена — не трогаем
+// (выключать надо явно через Disable), вернётся sql.ErrNoRows.
+func (r *TwoFactorSQLRepo) SetPendingSecret(userID, secret string) error {
+	result, err := r.db.Exec(`UPDATE users SET totp_secret = $2, totp_last_step = NULL
+	                           WHERE id = $1 AND totp_enabled_at IS NULL`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("twoFactor.SetPendingSecret: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("twoFactor.SetPendingSecret rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("twoFactor.SetPendingSecret: %w", sql.ErrNoRows)
+	}
+	return nil
+}
+
+// UseStep — принять шаг TOTP, если он новее последнего принятого.
+// Условие в WHERE делает проверку атомарной: два запроса с одним кодом
+// одновременно — пройдёт только один.
+func (r *TwoFactorSQLRepo) UseStep(userID string, step int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE users SET totp_last_step = $2
+	                           WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("twoFactor.UseStep: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("twoFactor.UseStep rows affected: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Enable — включить 2FA и выдать новые коды восстановления одной транзакцией.
+func (r *TwoFactorSQLRepo) Enable(userID string, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("twoFactor.Enable begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP
+	                   WHERE id = $1 AND totp_secret IS NOT NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("twoFactor.Enable update: %w", err)
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return fmt.Errorf("twoFactor.Enable codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("twoFactor.Enable commit: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes — новые коды восстановления, старые (в том числе неиспользованные) больше не работают.
+func (r *TwoFactorSQLRepo) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("twoFactor.ReplaceRecoveryCodes begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return fmt.Errorf("twoFactor.ReplaceRecoveryCodes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("twoFactor.ReplaceRecoveryCodes commit: %w", err)
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode — погасить код. UPDATE ... WHERE used_at IS NULL — код срабатывает ровно один раз.
+func (r *TwoFactorSQLRepo) ConsumeRecoveryCode(userID, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+	                           WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("twoFactor.ConsumeRecoveryCode: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("twoFactor.ConsumeRecoveryCode rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// Disable — выключить 2FA: секрет и коды восстановления удаляются.
+func (r *TwoFactorSQLRepo) Disable(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("twoFactor.Disable begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+	                   WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("twoFactor.Disable update: %w", err)
+	}
+	if err := replaceRecoveryCodes(tx, userID, nil); err != nil {
+		return fmt.Errorf("twoFactor.Disable codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("twoFactor.Disable commit: %w", err)
+	}
+	return nil
+}
+
+// replaceRecoveryCodes — удалить все коды юзера и вставить новые.
+func replaceRecoveryCodes(db execer, userID string, codeHashes []string) error {
+	if _, err := db.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := db.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
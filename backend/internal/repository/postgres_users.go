@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/models"
+)
+
+// ErrLastOwner — изменение оставило бы магазин без единого активного owner.
+var ErrLastOwner = errors.New("нельзя понизить или заблокировать последнего владельца")
+
+// UserSQLRepo — пользователи для админки поверх PostgreSQL (таблица users).
+// Свой профиль юзер меняет через AccountSQLRepo, здесь — то, что делает админ.
+type UserSQLRepo struct {
+	db *sql.DB
+}
+
+func NewUserSQLRepo(db *sql.DB) *UserSQLRepo {
+	return &UserSQLRepo{db: db}
+}
+
+// adminUserColumns — userColumns + дата регистрации и число заказов, порядок ждёт scanAdminUser.
+const adminUserColumns = userColumns + `, created_at,
+	(SELECT COUNT(*) FROM orders WHERE orders.user_id = users.id)`
+
+func scanAdminUser(scanner interface{ Scan(dest ...any) error }) (*models.AdminUser, error) {
+	var u models.AdminUser
+	var blockedAt sql.NullTime
+	var telegramID sql.NullInt64
+	err := scanner.Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &u.Role, &u.EmailVerified, &blockedAt,
+		&u.TOTPEnabled, &telegramID, &u.TelegramUsername, &u.CreatedAt, &u.OrderCount)
+	if err != nil {
+		return nil, err
+	}
+	if blockedAt.Valid {
+		u.BlockedAt = &blockedAt.Time
+	}
+	if telegramID.Valid {
+		u.TelegramID = &telegramID.Int64
+	}
+	return &u, nil
+}
+
+// List — поиск по email/имени/Telegram-username и роли, свежие регистрации сверху.
+// Фильтры собираются так же, как в orders.List.
+func (r *UserSQLRepo) List(filter models.UserFilter) ([]models.AdminUser, error) {
+	query := `SELECT ` + adminUserColumns + ` FROM users WHERE 1=1`
+	args := []interface{}{}
+	argIdx := 1
+
+	if filter.Query != "" {
+		query += fmt.Sprintf(" AND (email ILIKE $%d OR name ILIKE $%d OR telegram_username ILIKE $%d)", argIdx, argIdx, argIdx)
+		args = append(args, "%"+filter.Query+"%")
+		argIdx++
+	}
+	if filter.Role != "" {
+		query += fmt.Sprintf(" AND role = $%d", argIdx)
+		args = append(args, filter.Role)
+		argIdx++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("users.List query: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.AdminUser{}
+	for rows.Next() {
+		u, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("users.List scan: %w", err)
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("users.List rows: %w", err)
+	}
+	return users, nil
+}
+
+// GetByID — один юзер. Если нет — sql.ErrNoRows (обёрнутая).
+func (r *UserSQLRepo) GetByID(id string) (*models.AdminUser, error) {
+	u, err := scanAdminUser(r.db.QueryRow(`SELECT `+adminUserColumns+` FROM users WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("users.GetByID: %w", err)
+	}
+	return u, nil
+}
+
+// SetRole — сменить роль. Валидность роли проверяет хендлер (auth.ValidRole).
+func (r *UserSQLRepo) SetRole(id, role string) (*models.AdminUser, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("users.SetRole begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := lockUserForOwnerCheck(tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("users.SetRole: %w", err)
+	}
+	if current.isActiveOwner() && !auth.IsOwner(role) {
+		if err := ensureAnotherOwner(tx); err != nil {
+			return nil, fmt.Errorf("users.SetRole: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, id); err != nil {
+		return nil, fmt.Errorf("users.SetRole update: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("users.SetRole commit: %w", err)
+	}
+	return r.GetByID(id)
+}
+
+// SetBlocked — заблокировать или разблокировать.
+//
+// Как читается:
+//  1. Блокируем строку юзера; блокировка последнего активного owner → ErrLastOwner.
+//  2. Ставим/снимаем blocked_at. Уже заблокированному время блокировки не переписываем.
+//  3. При блокировке в той же транзакции отзываем все refresh-токены —
+//     Protected сразу перестаёт пускать его access-токены.
+func (r *UserSQLRepo) SetBlocked(id string, blocked bool) (*models.AdminUser, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("users.SetBlocked begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Последний owner
+	current, err := lockUserForOwnerCheck(tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("users.SetBlocked: %w", err)
+	}
+	if blocked && current.isActiveOwner() {
+		if err := ensureAnotherOwner(tx); err != nil {
+			return nil, fmt.Errorf("users.SetBlocked: %w", err)
+		}
+	}
+
+	// 2-3. Флаг и сессии
+	if blocked {
+		_, err = tx.Exec(`UPDATE users SET blocked_at = COALESCE(blocked_at, CURRENT_TIMESTAMP) WHERE id = $1`, id)
+		if err == nil {
+			err = revokeUserRefreshTokens(tx, id)
+		}
+	} else {
+		_, err = tx.Exec(`UPDATE users SET blocked_at = NULL WHERE id = $1`, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("users.SetBlocked update: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("users.SetBlocked commit: %w", err)
+	}
+	return r.GetByID(id)
+}
+
+// ────────────────────────────────────────────────
+// Хелперы проверки "последнего владельца"
+// ────────────────────────────────────────────────
+
+type ownerCheckRow struct {
+	role    string
+	blocked bool
+}
+
+func (u ownerCheckRow) isActiveOwner() bool {
+	return auth.IsOwner(u.role) && !u.blocked
+}
+
+// lockUserForOwnerCheck — роль и статус юзера под FOR UPDATE. Нет юзера — sql.ErrNoRows.
+func lockUserForOwnerCheck(tx *sql.Tx, id string) (ownerCheckRow, error) {
+	var row ownerCheckRow
+	err := tx.QueryRow(`SELECT role, blocked_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE`, id).
+		Scan(&row.role, &row.blocked)
+	return row, err
+}
+
+// ensureAnotherOwner — есть ли кроме текущего ещё активные owner.
+// Строки всех owner блокируются (FOR UPDATE): два админа, одновременно
+// понижающие друг друга, выполнятся по очереди, и второй получит ErrLastOwner.
+func ensureAnotherOwner(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id FROM users
+	                        WHERE role IN ($1, $2) AND blocked_at IS NULL
+	                        FOR UPDATE`, auth.RoleOwner, auth.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("lock owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := 0
+	for rows.Next() {
+		owners++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("lock owners: %w", err)
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
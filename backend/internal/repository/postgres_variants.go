@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"socialsh/backend/internal/models"
+)
+
+// VariantSQLRepo — реализация VariantRepository поверх PostgreSQL.
+// Варианты лежат в product_variants, options хранится как jsonb (как images у товара).
+type VariantSQLRepo struct {
+	db *sql.DB
+}
+
+func NewVariantSQLRepo(db *sql.DB) *VariantSQLRepo {
+	return &VariantSQLRepo{db: db}
+}
+
+// ListByProduct — все варианты товара, в порядке создания.
+func (r *VariantSQLRepo) ListByProduct(productID string) ([]models.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + `
+	           FROM product_variants WHERE product_id = $1
+	           ORDER BY created_at, sku`
+
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("variants.ListByProduct query: %w", err)
+	}
+	defer rows.Close()
+
+	variants := []models.ProductVariant{}
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("variants.ListByProduct scan: %w", err)
+		}
+		variants = append(variants, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("variants.ListByProduct rows: %w", err)
+	}
+
+	return variants, nil
+}
+
+// GetByID — один вариант по UUID. Не найден → sql.ErrNoRows (обёрнутая).
+func (r *VariantSQLRepo) GetByID(id string) (*models.ProductVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM product_variants WHERE id = $1`
+
+	v, err := scanVariant(r.db.QueryRow(query, id))
+	if err != nil {
+		return nil, fmt.Errorf("variants.GetByID: %w", err)
+	}
+	return v, nil
+}
+
+// Create — добавить вариант товару.
+// INSERT ... RETURNING id — как products.Create. Дубликат SKU → unique_violation (23505).
+func (r *VariantSQLRepo) Create(variant *models.ProductVariant) error {
+	optionsJSON, err := marshalOptions(variant.Options)
+	if err != nil {
+		return fmt.Errorf("variants.Create marshal options: %w", err)
+	}
+
+	query := `INSERT INTO product_variants (product_id, sku, options, price, stock)
+	           VALUES ($1, $2, $3, $4, $5)
+	           RETURNING id`
+
+	err = r.db.QueryRow(query,
+		variant.ProductID, variant.SKU, optionsJSON, variant.Price, variant.Stock,
+	).Scan(&variant.ID)
+	if err != nil {
+		return fmt.Errorf("variants.Create: %w", err)
+	}
+
+	variant.Reserved = 0
+	variant.InStock = variant.Stock > 0
+	return nil
+}
+
+// Update — обновить SKU, опции и цену варианта.
+// Остаток меняется только через SetStock — по той же причине, что и у товаров.
+func (r *VariantSQLRepo) Update(id string, variant *models.ProductVariant) (*models.ProductVariant, error) {
+	optionsJSON, err := marshalOptions(variant.Options)
+	if err != nil {
+		return nil, fmt.Errorf("variants.Update marshal options: %w", err)
+	}
+
+	query := `UPDATE product_variants
+	           SET sku = $1, options = $2, price = $3, updated_at = CURRENT_TIMESTAMP
+	           WHERE id = $4
+	           RETURNING ` + variantColumns
+
+	updated, err := scanVariant(r.db.QueryRow(query, variant.SKU, optionsJSON, variant.Price, id))
+	if err != nil {
+		return nil, fmt.Errorf("variants.Update: %w", err)
+	}
+	return updated, nil
+}
+
+// SetStock — выставить остаток варианта. Меньше резерва нельзя → ErrStockBelowReserved.
+// Логика та же, что в ProductSQLRepo.SetStock.
+func (r *VariantSQLRepo) SetStock(id string, stock int) (*models.ProductVariant, error) {
+	query := `UPDATE product_variants SET stock = $1, updated_at = CURRENT_TIMESTAMP
+	           WHERE id = $2 AND reserved <= $1
+	           RETURNING ` + variantColumns
+
+	v, err := scanVariant(r.db.QueryRow(query, stock, id))
+	if err == nil {
+		return v, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("variants.SetStock: %w", err)
+	}
+
+	if _, err := r.GetByID(id); err != nil {
+		return nil, fmt.Errorf("variants.SetStock: %w", err)
+	}
+	return nil, fmt.Errorf("variants.SetStock: %w", ErrStockBelowReserved)
+}
+
+// Delete — удалить вариант. Если 0 строк — ошибка "не найден", как в products.Delete.
+// Старые заказы не ломаются: в order_items лежит снапшот SKU и опций.
+func (r *VariantSQLRepo) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM product_variants WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("variants.Delete: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("variants.Delete rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("variants.Delete: вариант с id=%s не найден", id)
+	}
+
+	return nil
+}
+
+// ────────────────────────────────────────────────
+// Хелперы
+// ────────────────────────────────────────────────
+
+// variantColumns — колонки варианта в порядке, который ждёт scanVariant.
+const variantColumns = `id, product_id, sku, options, price, stock, reserved`
+
+func scanVariant(scanner interface{ Scan(dest ...any) error }) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	var optionsJSON []byte
+	var price sql.NullInt64
+
+	err := scanner.Scan(&v.ID, &v.ProductID, &v.SKU, &optionsJSON, &price, &v.Stock, &v.Reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Options = map[string]string{}
+	if optionsJSON != nil {
+		if err := json.Unmarshal(optionsJSON, &v.Options); err != nil {
+			return nil, fmt.Errorf("unmarshal options: %w", err)
+		}
+	}
+	if price.Valid {
+		v.Price = &price.Int64
+	}
+	v.InStock = v.Stock-v.Reserved > 0
+	return &v, nil
+}
+
+// marshalOptions — map → jsonb. nil превращаем в {}, чтобы в БД не было null.
+func marshalOptions(options map[string]string) ([]byte, error) {
+	if options == nil {
+		options = map[string]string{}
+	}
+	return json.Marshal(options)
+}
+
+// loadVariants — догружает Variants для слайса товаров одним запросом
+// и пересчитывает InStock: у товара с вариантами он в наличии,
+// если в наличии хотя бы один вариант.
+func loadVariants(q queryer, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(products))
+	byID := make(map[string]*models.Product, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+		products[i].Variants = []models.ProductVariant{}
+		byID[products[i].ID] = &products[i]
+	}
+
+	rows, err := q.Query(`SELECT `+variantColumns+`
+	                       FROM product_variants WHERE product_id = ANY($1)
+	                       ORDER BY created_at, sku`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query variants: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return fmt.Errorf("scan variant: %w", err)
+		}
+		byID[v.ProductID].Variants = append(byID[v.ProductID].Variants, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("variant rows: %w", err)
+	}
+
+	for i := range products {
+		if len(products[i].Variants) == 0 {
+			continue
+		}
+		products[i].InStock = false
+		for _, v := range products[i].Variants {
+			if v.InStock {
+				products[i].InStock = true
+				break
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"database/sql"
+	"time"
+
 	"socialsh/backend/internal/models"
 )
 
@@ -17,6 +19,17 @@ type ProductRepository interface {
 	GetByID(id string) (*models.Product, error)
 	Create(product *models.Product) error
 	Update(id string, product *models.Product) (*models.Product, error)
+	SetStock(id string, stock int) (*models.Product, error)
+	Delete(id string) error
+}
+
+type VariantRepository interface {
+	ListByProduct(productID string) ([]models.ProductVariant, error)
+	GetByID(id string) (*models.ProductVariant, error)
+	// Админские
+	Create(variant *models.ProductVariant) error
+	Update(id string, variant *models.ProductVariant) (*models.ProductVariant, error)
+	SetStock(id string, stock int) (*models.ProductVariant, error)
 	Delete(id string) error
 }
 
@@ -42,16 +55,185 @@ type AccountRepository interface {
 	GetUserByID(id string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	CreateUser(user *models.User) error
+	// Вход через Telegram (см. handlers/telegram_auth.go)
+	GetUserByTelegramID(telegramID int64) (*models.User, error)
+	LinkTelegram(userID string, telegramID int64, username string) (*models.User, error)
+	// UnlinkTelegram не оставит аккаунт без входа (ErrLastLoginMethod)
+	UnlinkTelegram(userID string) (*models.User, error)
 	UpdateUser(id string, req *models.UpdateProfileRequest) (*models.User, error)
 	ListOrdersByUser(id string) ([]models.Order, error)
+	// ChangePassword меняет пароль и отзывает refresh-токены всех входов, кроме keepSessionID
+	ChangePassword(id, passwordHash, keepSessionID string) error
+	// Подтверждение email
+	MarkEmailVerified(id, email string) (bool, error)
+	TouchVerificationSent(id string, interval time.Duration) (bool, error)
+	// DeleteAccount удаляет юзера, а контакты в его заказах заменяет псевдонимом
+	DeleteAccount(id, pseudonym string) error
+}
+
+// UserRepository — управление пользователями из админки.
+type UserRepository interface {
+	List(filter models.UserFilter) ([]models.AdminUser, error)
+	GetByID(id string) (*models.AdminUser, error)
+	// SetRole и SetBlocked не дают оставить магазин без owner (ErrLastOwner)
+	SetRole(id, role string) (*models.AdminUser, error)
+	// SetBlocked при блокировке сразу отзывает все сессии юзера
+	SetBlocked(id string, blocked bool) (*models.AdminUser, error)
+}
+
+type DiscountRepository interface {
+	// Публичные (проверка промокода на чекауте)
+	GetByCode(code string) (*models.Discount, error)
+	CountRedemptions(discountID, customerEmail string) (int, error)
+	// Админские
+	List() ([]models.Discount, error)
+	GetByID(id string) (*models.Discount, error)
+	Create(discount *models.Discount) error
+	Update(id string, discount *models.Discount) (*models.Discount, error)
+	Delete(id string) error
+}
+
+type ShippingRepository interface {
+	// Публичные
+	ListActive() ([]models.ShippingMethod, error)
+	GetByID(id string) (*models.ShippingMethod, error)
+	// Админские
+	ListAll() ([]models.ShippingMethod, error)
+	Create(method *models.ShippingMethod) error
+	Update(id string, method *models.ShippingMethod) (*models.ShippingMethod, error)
+	Delete(id string) error
+}
+
+type OrderRepository interface {
+	// Create сохраняет заказ и его позиции одной транзакцией, заполняет order.ID.
+	// outbox (может быть nil) получает сохранённый заказ и возвращает уведомления,
+	// которые пишутся в notification_outbox в той же транзакции
+	Create(order *models.Order, outbox func(order *models.Order) []models.Notification) error
+	// AttachGuestOrders привязывает гостевые заказы с этим email к аккаунту, возвращает сколько привязано
+	AttachGuestOrders(userID, email string) (int64, error)
+	// Админские
+	List(filter models.OrderFilter) ([]models.Order, error)
+	GetByID(id string) (*models.Order, error)
+	UpdateStatus(id, status, changedBy, comment string) (*models.Order, error)
+}
+
+type PaymentRepository interface {
+	Create(payment *models.Payment) error
+	SetConfirmation(id, providerPaymentID, confirmationURL string) error
+	GetByProviderID(provider, providerPaymentID string) (*models.Payment, error)
+	// GetPendingByOrder — незавершённый платёж заказа (не больше одного на заказ)
+	GetPendingByOrder(orderID string) (*models.Payment, error)
+	// SetStatus меняет статус только у pending-платежа, false — платёж уже обработан
+	SetStatus(id, status string) (bool, error)
+	// Succeed — pending-платёж → succeeded и его заказ → paid одной транзакцией;
+	// заказ в paid перевести нельзя → true и ErrInvalidStatusTransition
+	Succeed(id, comment string) (bool, error)
+}
+
+// IdempotencyRepository — ключи Idempotency-Key (см. middleware.Idempotency).
+type IdempotencyRepository interface {
+	// Begin занимает ключ; nil — ключ свободен и теперь наш, иначе — уже существующая запись
+	Begin(rec *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(scope, key string, status int, contentType string, body []byte) error
+	// CompleteResource — вместо ответа сохраняет ID созданного объекта (ответ нельзя хранить)
+	CompleteResource(scope, key string, status int, resourceID string) error
+	Delete(scope, key string) error
+}
+
+// RefreshTokenRepository — сохранённые refresh-токены (ротация и отзыв семей).
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	// Rotate меняет токен oldID (с хешем oldHash) на next той же семьи
+	Rotate(oldID, oldHash string, next *models.RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID string) error
+	// Сессии (семьи) для личного кабинета и middleware.Protected
+	ListSessions(userID string) ([]models.Session, error)
+	IsSessionActive(familyID string) (bool, error)
+	RevokeSession(userID, familyID string) error
+	RevokeOtherSessions(userID, keepFamilyID string) error
+}
+
+// PasswordResetRepository — одноразовые токены сброса пароля.
+type PasswordResetRepository interface {
+	// Create сохраняет токен со сроком ttl от текущего времени БД
+	Create(token *models.PasswordResetToken, ttl time.Duration) error
+	// CountRecent — сколько токенов выдано юзеру за последние window (защита от спама письмами)
+	CountRecent(userID string, window time.Duration) (int, error)
+	// Consume гасит токен, ставит новый пароль и отзывает все refresh-токены юзера
+	Consume(tokenHash, passwordHash string) (userID string, err error)
+}
+
+// MagicLinkRepository — одноразовые ссылки для входа без пароля.
+type MagicLinkRepository interface {
+	// Create сохраняет ссылку со сроком ttl от текущего времени БД
+	Create(link *models.MagicLink, ttl time.Duration) error
+	// CountRecent — сколько ссылок выдано юзеру за последние window (защита от спама письмами)
+	CountRecent(userID string, window time.Duration) (int, error)
+	// Consume гасит ссылку (только с nonce того же браузера) и остальные ссылки юзера
+	Consume(id, nonceHash string) (userID string, err error)
+}
+
+// TwoFactorRepository — TOTP и коды восстановления.
+type TwoFactorRepository interface {
+	Get(userID string) (*models.TwoFactorState, error)
+	// SetPendingSecret — начать настройку: новый секрет, 2FA ещё выключена
+	SetPendingSecret(userID, secret string) error
+	// UseStep запоминает принятый шаг TOTP; false — этот или более новый шаг уже был
+	UseStep(userID string, step int64) (bool, error)
+	// Enable включает 2FA и заменяет коды восстановления (храним только хеши)
+	Enable(userID string, codeHashes []string) error
+	ReplaceRecoveryCodes(userID string, codeHashes []string) error
+	// ConsumeRecoveryCode гасит код; false — такого неиспользованного кода нет
+	ConsumeRecoveryCode(userID, codeHash string) (bool, error)
+	Disable(userID string) error
+}
+
+// LoginThrottleRepository — неудачные попытки входа и блокировки (см. handlers/login_throttle.go).
+type LoginThrottleRepository interface {
+	// CountAttempt атомарно +1 к счётчику (счётчик старше window начинается заново) и возвращает
+	// номер попытки; у заблокированного ключа счётчик не растёт, возвращается остаток блокировки.
+	// После истёкшей блокировки номер не больше lockAfter — одна проверка пароля до новой блокировки
+	CountAttempt(kind, subject string, window time.Duration, lockAfter int) (attempts int, locked time.Duration, err error)
+	// Refund −1 к счётчику: засчитанная заранее попытка оказалась успешной
+	Refund(kind, subject string) error
+	Lock(kind, subject string, d time.Duration) error
+	Reset(kind, subject string) error
+	// Админские
+	ListActive(kinds []string, window time.Duration) ([]models.LoginThrottle, error)
+	Delete(id string) error
+}
+
+// NotificationRepository — outbox уведомлений (см. пакет notify).
+type NotificationRepository interface {
+	Enqueue(n *models.Notification) error
+	// ClaimDue забирает готовые к отправке записи и арендует их на lease
+	ClaimDue(limit int, lease time.Duration) ([]models.Notification, error)
+	MarkSent(id string) error
+	// MarkRetry откладывает следующую попытку на delay от текущего времени БД
+	MarkRetry(id string, delay time.Duration, lastError string) error
+	MarkFailed(id string, lastError string) error
 }
 
 // Store агрегирует все репозитории, чтобы было удобно прокидывать зависимости.
 type Store struct {
-	Products ProductRepository
-	Gallery  GalleryRepository
-	Pages    PageRepository
-	Account  AccountRepository
+	Products       ProductRepository
+	Variants       VariantRepository
+	Gallery        GalleryRepository
+	Pages          PageRepository
+	Account        AccountRepository
+	Users          UserRepository
+	Orders         OrderRepository
+	Discounts      DiscountRepository
+	Shipping       ShippingRepository
+	Payments       PaymentRepository
+	Notifications  NotificationRepository
+	Idempotency    IdempotencyRepository
+	RefreshTokens  RefreshTokenRepository
+	PasswordResets PasswordResetRepository
+	MagicLinks     MagicLinkRepository
+	LoginThrottle  LoginThrottleRepository
+	TwoFactor      TwoFactorRepository
 }
 
 // TODO: сделай конструктор под свою реализацию, например:
@@ -59,9 +241,22 @@ type Store struct {
 
 func NewStore(db *sql.DB) *Store {
 	return &Store{
-		Products: NewProductSQLRepo(db),
-		Gallery:  NewGallerySQLRepo(db),
-		Pages:    NewPageSQLRepo(db),
-		Account:  NewAccountSQLRepo(db),
+		Products:       NewProductSQLRepo(db),
+		Variants:       NewVariantSQLRepo(db),
+		Gallery:        NewGallerySQLRepo(db),
+		Pages:          NewPageSQLRepo(db),
+		Account:        NewAccountSQLRepo(db),
+		Users:          NewUserSQLRepo(db),
+		Orders:         NewOrderSQLRepo(db),
+		Discounts:      NewDiscountSQLRepo(db),
+		Shipping:       NewShippingSQLRepo(db),
+		Payments:       NewPaymentSQLRepo(db),
+		Notifications:  NewNotificationSQLRepo(db),
+		Idempotency:    NewIdempotencySQLRepo(db),
+		RefreshTokens:  NewRefreshTokenSQLRepo(db),
+		PasswordResets: NewPasswordResetSQLRepo(db),
+		MagicLinks:     NewMagicLinkSQLRepo(db),
+		LoginThrottle:  NewLoginThrottleSQLRepo(db),
+		TwoFactor:      NewTwoFactorSQLRepo(db),
 	}
 }
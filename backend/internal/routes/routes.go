@@ -3,14 +3,37 @@ package routes
 import (
 	"github.com/gofiber/fiber/v2"
 
+	"socialsh/backend/internal/auth"
+	"socialsh/backend/internal/config"
 	"socialsh/backend/internal/handlers"
 	"socialsh/backend/internal/middleware"
 )
 
 // Register — единая точка входа для регистрации ВСЕХ маршрутов приложения.
-// Принимает Fiber-приложение и jwtSecret (нужен для middleware авторизации).
+// Принимает Fiber-приложение и конфиг (секреты JWT для middleware авторизации,
+// адреса для платежей и т.п.).
 // Внутри создаёт группу /api и раскидывает роуты по уровням доступа.
-func Register(app *fiber.App, jwtSecret, refreshSecret string) {
+func Register(app *fiber.App, cfg *config.Config) {
+	// Idempotency-Key для POST, которые что-то создают: повтор запроса
+	// (ретрай фронта после обрыва сети) получит тот же ответ, а не второй заказ.
+	// Ставится после Protected/OptionalAuth — ключи разделены по пользователям.
+	idem := middleware.Idempotency(handlers.Repo.Idempotency)
+
+	// Токен покупателя необязателен (гостевой заказ), но если он есть — сессия должна быть жива
+	optionalAuth := middleware.OptionalAuth(handlers.Keys, handlers.Repo.RefreshTokens)
+
+	// Фичи, закрытые до подтверждения email (REQUIRE_VERIFIED_EMAIL_FOR).
+	// Не в списке — middleware просто пропускает запрос дальше.
+	verified := func(feature string) fiber.Handler {
+		if !cfg.RequiresVerifiedEmail(feature) {
+			return func(c *fiber.Ctx) error { return c.Next() }
+		}
+		return middleware.RequireVerifiedEmail(handlers.Repo.Account)
+	}
+
+	// Открытые ключи подписи access-токенов — стандартный адрес, поэтому вне /api
+	app.Get("/.well-known/jwks.json", handlers.JWKS) // GET /.well-known/jwks.json → {keys: [...]}
+
 	// Все эндпоинты живут под /api — фронт ходит на /api/products, /api/auth/sign-in и т.д.
 	api := app.Group("/api")
 
@@ -27,72 +50,191 @@ func Register(app *fiber.App, jwtSecret, refreshSecret string) {
 	// Инфо-страницы — оплата, доставка, возврат, контакты
 	api.Get("/pages/:slug", handlers.GetPage) // GET /api/pages/payment | delivery | returns | contacts
 
-	// Заказы — создание заказа (публичный, но можно добавить защиту)
-	api.Post("/orders", handlers.CreateOrder) // POST /api/orders
+	// Заказы — создание заказа (публичный роут, но userID берём из токена, если он есть)
+	api.Post("/orders", optionalAuth, verified("checkout"), idem, handlers.CreateOrder) // POST /api/orders
+
+	// Промокоды — предпросмотр скидки для корзины на чекауте
+	api.Post("/discounts/preview", handlers.PreviewDiscount) // POST /api/discounts/preview
+
+	// Доставка — способы и расчёт стоимости для корзины
+	api.Get("/shipping/methods", handlers.GetShippingMethods) // GET /api/shipping/methods
+	api.Post("/shipping/quote", handlers.QuoteShipping)       // POST /api/shipping/quote
+
+	// Оплата — создание платежа по заказу и вебхуки провайдеров (подпись проверяет провайдер)
+	api.Post("/payments", optionalAuth, verified("payments"), idem, handlers.CreatePayment(cfg.PaymentReturnURL)) // POST /api/payments
+	api.Post("/payments/webhook/:provider", handlers.PaymentWebhook)                                              // POST /api/payments/webhook/yookassa
 
 	// ──── 2. Auth-роуты (регистрация/логин/рефреш) ────
-	authRoutes(api, jwtSecret, refreshSecret)
+	authRoutes(api, cfg)
 
 	// ──── 3. Личный кабинет (нужна авторизация) ────
-	accountRoutes(api, jwtSecret)
+	accountRoutes(api, cfg)
 
-	// ──── 4. Админка (авторизация + роль admin) ────
-	adminRoutes(api, jwtSecret)
+	// ──── 4. Админка (авторизация + права роли) ────
+	adminRoutes(api, cfg, idem)
 }
 
 // authRoutes — группа маршрутов аутентификации.
 // sign-up и sign-in — публичные (токена ещё нет).
 // logout и is-admin — защищённые (нужен валидный JWT).
-func authRoutes(api fiber.Router, jwtSecret, refreshSecret string) {
+// password/forgot и password/reset — сброс пароля по ссылке из письма.
+// 2fa/verify — второй шаг входа, если у юзера включена двухфакторная аутентификация.
+// telegram — вход через Telegram (подпись данных виджета проверяется токеном бота).
+// magic-link — вход без пароля по одноразовой ссылке из письма.
+// В режиме AUTH_MODE=cookie токены уходят куками; изменяющие запросы с куками
+// сверяют X-CSRF-Token (Protected/OptionalAuth, а на refresh — middleware.CSRF).
+func authRoutes(api fiber.Router, cfg *config.Config) {
+	jwtSecret, refreshSecret := cfg.JwtSecret, cfg.RefreshSecret
+	protected := middleware.Protected(handlers.Keys, handlers.Repo.RefreshTokens)
 	a := api.Group("/auth")
 
 	// sign-up/sign-in/refresh — публичные (токена ещё нет)
-	a.Post("/sign-up", handlers.SignUp(jwtSecret, refreshSecret))       // регистрация → {access, refresh}
-	a.Post("/sign-in", handlers.SignIn(jwtSecret, refreshSecret))       // логин → {access, refresh}
-	a.Post("/refresh", handlers.RefreshToken(jwtSecret, refreshSecret)) // обновить access по refresh
+	// Idempotency-Key на sign-up: ответ несёт токены, поэтому хранится только ID юзера,
+	// а повтор с тем же ключом получает новую сессию (handlers.SignUpReplay), а не 409
+	signUpIdem := middleware.IdempotencyReplay(handlers.Repo.Idempotency, handlers.SignUpReplay(jwtSecret, refreshSecret))
+	a.Post("/sign-up", signUpIdem, handlers.SignUp(jwtSecret, refreshSecret, cfg.FrontendURL)) // регистрация → {access, refresh} + письмо подтверждения
+	a.Post("/sign-in", handlers.SignIn(jwtSecret, refreshSecret))                              // логин → {access, refresh}; после серии неудач — 429
+	a.Post("/refresh", middleware.CSRF(), handlers.RefreshToken(refreshSecret))                // новая пара по refresh (старый гасится); refresh из куки — только с X-CSRF-Token
+	a.Post("/2fa/verify", handlers.VerifyMFA(jwtSecret, refreshSecret))                        // второй шаг входа: challenge + код → {access, refresh}
+
+	// Вход через Telegram Login Widget — аккаунт создаётся при первом входе, дальше как sign-in
+	a.Post("/telegram", handlers.TelegramSignIn(jwtSecret, refreshSecret, cfg.TelegramLoginBotToken))
+
+	// Вход по ссылке из письма — ответ одинаковый, есть аккаунт или нет; ссылка работает только в том же браузере
+	a.Post("/magic-link", handlers.RequestMagicLink(jwtSecret, cfg.FrontendURL))     // письмо со ссылкой (лимит по IP — 429)
+	a.Post("/magic-link/verify", handlers.VerifyMagicLink(jwtSecret, refreshSecret)) // токен из ссылки + nonce браузера → {access, refresh}
+
+	// Сброс пароля — публичные: ответ forgot одинаковый, есть аккаунт или нет
+	a.Post("/password/forgot", handlers.ForgotPassword(cfg.FrontendURL)) // письмо со ссылкой
+	a.Post("/password/reset", handlers.ResetPassword)                    // новый пароль по токену из письма
+
+	// Подтверждение email: confirm — по ссылке из письма (без токена входа), resend — из кабинета
+	a.Post("/email/confirm", handlers.ConfirmEmail(jwtSecret))                                  // подтвердить по токену из письма
+	a.Post("/email/resend", protected, handlers.ResendVerification(jwtSecret, cfg.FrontendURL)) // письмо ещё раз (не чаще раза в минуту)
 
 	// logout и is-admin — защищённые (нужен валидный JWT)
-	a.Post("/logout", middleware.Protected(jwtSecret), handlers.Logout)   // инвалидировать refresh
-	a.Get("/is-admin", middleware.Protected(jwtSecret), handlers.IsAdmin) // {isAdmin: true/false}
+	a.Post("/logout", protected, handlers.Logout)   // отозвать refresh-токены текущего входа
+	a.Get("/is-admin", protected, handlers.IsAdmin) // {isAdmin: true/false}
+
+	// Повторный ввод пароля перед сменой email и т.п. → новая пара токенов со свежим auth_time
+	a.Post("/reauth", protected, handlers.Reauthenticate(refreshSecret))
 }
 
 // accountRoutes — личный кабинет пользователя.
 // Вся группа /account защищена middleware.Protected — без токена сюда не попасть.
-func accountRoutes(api fiber.Router, jwtSecret string) {
-	acc := api.Group("/account", middleware.Protected(jwtSecret))
-
-	acc.Get("/me", handlers.GetAccountMe)    // GET /api/account/me → профиль текущего юзера
-	acc.Get("/orders", handlers.GetOrders)   // GET /api/account/orders → список заказов юзера
-	acc.Patch("/me", handlers.UpdateProfile) // PATCH /api/account/me → обновить имя/email/и т.д.
+// Смена email требует недавнего ввода пароля (POST /api/auth/reauth), смена пароля — текущего пароля.
+// Выгрузка и удаление персональных данных — /export и /delete (удаление тоже после reauth).
+func accountRoutes(api fiber.Router, cfg *config.Config) {
+	acc := api.Group("/account", middleware.Protected(handlers.Keys, handlers.Repo.RefreshTokens))
+
+	acc.Get("/me", handlers.GetAccountMe)                                    // GET /api/account/me → профиль текущего юзера
+	acc.Get("/orders", handlers.GetOrders)                                   // GET /api/account/orders → список заказов юзера
+	acc.Patch("/me", handlers.UpdateProfile(cfg.JwtSecret, cfg.FrontendURL)) // PATCH /api/account/me → обновить имя/email/и т.д.
+	acc.Post("/password", handlers.ChangePassword)                           // POST /api/account/password → сменить пароль (нужен текущий)
+
+	// Активные входы (семьи refresh-токенов) и выход на других устройствах
+	acc.Get("/sessions", handlers.GetSessions)            // GET /api/account/sessions → где выполнен вход
+	acc.Delete("/sessions", handlers.RevokeOtherSessions) // DELETE /api/account/sessions → выйти везде, кроме текущего
+	acc.Delete("/sessions/:id", handlers.RevokeSession)   // DELETE /api/account/sessions/:id → завершить одну сессию
+
+	// Telegram как второй способ входа в аккаунт
+	acc.Post("/telegram", handlers.LinkTelegram(cfg.TelegramLoginBotToken)) // POST /api/account/telegram → привязать (нужен недавний ввод пароля)
+	acc.Delete("/telegram", handlers.UnlinkTelegram)                        // DELETE /api/account/telegram → отвязать (если есть email и пароль)
+
+	// Двухфакторная аутентификация (TOTP): setup → enable с кодом из приложения
+	acc.Post("/2fa/setup", handlers.TwoFactorSetup)                          // секрет + otpauth-ссылка (нужен недавний ввод пароля)
+	acc.Post("/2fa/enable", handlers.TwoFactorEnable(cfg.RefreshSecret))     // включить → коды восстановления + токены с mfa
+	acc.Post("/2fa/recovery-codes", handlers.TwoFactorRecoveryCodes)         // новые коды восстановления
+	acc.Post("/2fa/disable", handlers.TwoFactorDisable(cfg.RequireAdmin2FA)) // выключить (сотрудникам — нельзя при REQUIRE_ADMIN_2FA)
+
+	// Персональные данные (152-ФЗ / GDPR)
+	acc.Get("/export", handlers.ExportAccountData) // GET /api/account/export?format=json|zip → всё, что о юзере хранится
+	acc.Post("/delete", handlers.DeleteAccount)    // POST /api/account/delete → удалить аккаунт, заказы обезличить (нужен недавний ввод пароля)
 }
 
 // adminRoutes — админская панель, полный CRUD для контента.
-// Два middleware подряд: Protected (проверка JWT) → AdminOnly (проверка role == "admin").
-// Если юзер не админ — получит 403 Forbidden.
-func adminRoutes(api fiber.Router, jwtSecret string) {
+// Два middleware подряд: Protected (проверка JWT) → AdminOnly (есть ли у роли хоть какие-то права).
+// Дальше каждый роут проверяет своё право через RequirePermission (роли и права — пакет auth):
+// content-editor не удалит товар, catalog-manager не увидит заказы и т.д.
+// Если прав не хватает — 403 Forbidden.
+// REQUIRE_ADMIN_2FA=true — третьим идёт RequireMFA: без входа со вторым фактором 403.
+// idem — middleware.Idempotency, ставится на POST-создания.
+func adminRoutes(api fiber.Router, cfg *config.Config, idem fiber.Handler) {
 	adm := api.Group("/admin",
-		middleware.Protected(jwtSecret), // сначала проверяем что JWT валидный
-		middleware.AdminOnly(),          // потом что role == "admin"
+		middleware.Protected(handlers.Keys, handlers.Repo.RefreshTokens), // сначала JWT и сессия
+		middleware.AdminOnly(), // потом что роль вообще админская
 	)
+	if cfg.RequireAdmin2FA {
+		adm.Use(middleware.RequireMFA())
+	}
+
+	products := middleware.RequirePermission(auth.ProductsWrite)
+	gallery := middleware.RequirePermission(auth.GalleryWrite)
+	pages := middleware.RequirePermission(auth.PagesWrite)
+	ordersRead := middleware.RequirePermission(auth.OrdersRead)
+	ordersWrite := middleware.RequirePermission(auth.OrdersWrite)
+	discounts := middleware.RequirePermission(auth.DiscountsWrite)
+	shipping := middleware.RequirePermission(auth.ShippingWrite)
+	users := middleware.RequirePermission(auth.UsersManage)
 
 	// ── Товары (полный CRUD) ──
-	adm.Get("/products", handlers.AdminListProducts)         // список всех товаров для админки
-	adm.Post("/products", handlers.AdminCreateProduct)       // создать новый товар
-	adm.Get("/products/:id", handlers.AdminGetProduct)       // один товар по ID (не slug!)
-	adm.Patch("/products/:id", handlers.AdminUpdateProduct)  // обновить поля товара
-	adm.Delete("/products/:id", handlers.AdminDeleteProduct) // удалить товар
+	adm.Get("/products", products, handlers.AdminListProducts)                // список всех товаров для админки
+	adm.Post("/products", products, idem, handlers.AdminCreateProduct)        // создать новый товар
+	adm.Get("/products/:id", products, handlers.AdminGetProduct)              // один товар по ID (не slug!)
+	adm.Patch("/products/:id", products, handlers.AdminUpdateProduct)         // обновить поля товара
+	adm.Patch("/products/:id/stock", products, handlers.AdminSetProductStock) // выставить остаток на складе
+	adm.Delete("/products/:id", products, handlers.AdminDeleteProduct)        // удалить товар
+
+	// ── Варианты товаров (размер/цвет) ──
+	adm.Get("/products/:id/variants", products, handlers.AdminListVariants)         // варианты товара
+	adm.Post("/products/:id/variants", products, idem, handlers.AdminCreateVariant) // добавить вариант
+	adm.Patch("/variants/:id", products, handlers.AdminUpdateVariant)               // sku/опции/цена
+	adm.Patch("/variants/:id/stock", products, handlers.AdminSetVariantStock)       // остаток варианта
+	adm.Delete("/variants/:id", products, handlers.AdminDeleteVariant)              // удалить вариант
 
 	// ── Галерея ──
-	adm.Get("/gallery", handlers.AdminListGalleryItems)         // список элементов галереи
-	adm.Post("/gallery", handlers.AdminCreateGalleryItem)       // добавить фото в галерею
-	adm.Patch("/gallery/:id", handlers.AdminUpdateGalleryItem)  // изменить элемент
-	adm.Delete("/gallery/:id", handlers.AdminDeleteGalleryItem) // удалить элемент
+	adm.Get("/gallery", gallery, handlers.AdminListGalleryItems)         // список элементов галереи
+	adm.Post("/gallery", gallery, idem, handlers.AdminCreateGalleryItem) // добавить фото в галерею
+	adm.Patch("/gallery/:id", gallery, handlers.AdminUpdateGalleryItem)  // изменить элемент
+	adm.Delete("/gallery/:id", gallery, handlers.AdminDeleteGalleryItem) // удалить элемент
 
 	// ── Инфо-страницы ──
-	adm.Get("/pages", handlers.AdminListPages)          // список всех страниц
-	adm.Patch("/pages/:slug", handlers.AdminUpdatePage) // обновить контент страницы
+	adm.Get("/pages", pages, handlers.AdminListPages)          // список всех страниц
+	adm.Patch("/pages/:slug", pages, handlers.AdminUpdatePage) // обновить контент страницы
+
+	// ── Заказы ──
+	adm.Get("/orders", ordersRead, handlers.AdminListOrders)                      // список с фильтрами status/from/to/customer
+	adm.Get("/orders/:id", ordersRead, handlers.AdminGetOrder)                    // один заказ с позициями и историей
+	adm.Patch("/orders/:id/status", ordersWrite, handlers.AdminUpdateOrderStatus) // сменить статус (только разрешённые переходы)
+
+	// ── Промокоды ──
+	adm.Get("/discounts", discounts, handlers.AdminListDiscounts)         // список промокодов
+	adm.Post("/discounts", discounts, idem, handlers.AdminCreateDiscount) // создать промокод
+	adm.Get("/discounts/:id", discounts, handlers.AdminGetDiscount)       // один промокод
+	adm.Patch("/discounts/:id", discounts, handlers.AdminUpdateDiscount)  // изменить промокод
+	adm.Delete("/discounts/:id", discounts, handlers.AdminDeleteDiscount) // удалить промокод
+
+	// ── Способы доставки ──
+	adm.Get("/shipping-methods", shipping, handlers.AdminListShippingMethods)         // список способов
+	adm.Post("/shipping-methods", shipping, idem, handlers.AdminCreateShippingMethod) // создать способ
+	adm.Patch("/shipping-methods/:id", shipping, handlers.AdminUpdateShippingMethod)  // изменить способ и правила
+	adm.Delete("/shipping-methods/:id", shipping, handlers.AdminDeleteShippingMethod) // удалить способ
+
+	// ── Пользователи (роли, блокировка, выход со всех устройств, персональные данные) ──
+	adm.Get("/users", users, handlers.AdminListUsers)                 // поиск ?q=&role=&page=&limit=
+	adm.Get("/users/:id", users, handlers.AdminGetUser)               // профиль + число заказов
+	adm.Patch("/users/:id/role", users, handlers.AdminUpdateUserRole) // сменить роль (последнего owner — нельзя)
+	adm.Post("/users/:id/block", users, handlers.AdminBlockUser)      // заблокировать и завершить сессии
+	adm.Post("/users/:id/unblock", users, handlers.AdminUnblockUser)  // разблокировать
+	adm.Post("/users/:id/logout", users, handlers.AdminLogoutUser)    // выйти на всех устройствах
+	adm.Get("/users/:id/export", users, handlers.AdminExportUserData) // выгрузка данных по запросу юзера (?format=json|zip)
+	adm.Post("/users/:id/delete", users, handlers.AdminDeleteUser)    // удалить аккаунт по запросу юзера, заказы обезличить
+
+	// ── Блокировки входа (перебор паролей) ──
+	adm.Get("/login-lockouts", users, handlers.AdminListLoginLockouts)        // заблокированные email/IP и недавние неудачи
+	adm.Delete("/login-lockouts/:id", users, handlers.AdminClearLoginLockout) // снять блокировку
 
 	// ── Загрузка файлов ──
-	adm.Post("/upload/product", handlers.UploadProductImage)  // загрузить изображение товара
-	adm.Post("/upload/gallery", handlers.UploadGalleryImage)  // загрузить изображение галереи
+	adm.Post("/upload/product", products, handlers.UploadProductImage) // загрузить изображение товара
+	adm.Post("/upload/gallery", gallery, handlers.UploadGalleryImage)  // загрузить изображение галереи
 }
@@ -57,3 +57,14 @@ func FormatDuplicateError(err error) string {
 	}
 	return "значение уже существует"
 }
+
+// IsInvalidInputError проверяет, что Postgres не смог разобрать значение параметра.
+// Код 22P02 — например, строка "abc" вместо UUID в WHERE id = $1.
+// Для хендлера это то же самое, что "не найдено", а не ошибка сервера.
+func IsInvalidInputError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "22P02" // invalid_text_representation
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// HashToken — sha256 от токена в hex.
+// В БД храним только хеш: утёкшая таблица не даёт рабочих токенов,
+// а найти запись по предъявленному токену всё равно можно.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewUUID — случайный UUID v4 (для ID, которые нужны до INSERT, например jti токена).
+func NewUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("генерация uuid: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // версия 4
+	b[8] = (b[8] & 0x3f) | 0x80 // вариант RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// RandomToken — случайный токен из n байт в hex (для ссылок из писем).
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("генерация токена: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
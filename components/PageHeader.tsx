@@ -4,6 +4,7 @@ import { useState, useEffect } from 'react'
 import Link from 'next/link'
 import { usePathname } from 'next/navigation'
 import { CartButton } from './CartButton'
+import { hasSession } from '@/lib/api'
 import styles from './PageHeader.module.css'
 
 function isActive(pathname: string, href: string) {
@@ -16,8 +17,7 @@ export function PageHeader() {
   const [isLoggedIn, setIsLoggedIn] = useState(false)
 
   useEffect(() => {
-    const token = localStorage.getItem('access_token')
-    setIsLoggedIn(!!token)
+    setIsLoggedIn(hasSession())
   }, [])
 
   const shopSectionActive = pathname.startsWith('/shop')
@@ -0,0 +1,40 @@
+'use client'
+
+import { useEffect, useRef } from 'react'
+import type { TelegramAuthData } from '@/lib/api'
+
+// Username бота, к которому в @BotFather привязан домен сайта (тот же, чей токен у бэкенда)
+const TELEGRAM_BOT = process.env.NEXT_PUBLIC_TELEGRAM_BOT
+
+// Кнопка Telegram Login Widget. Виджет сам открывает окно Telegram и после подтверждения
+// отдаёт подписанный объект юзера в onAuth. Без NEXT_PUBLIC_TELEGRAM_BOT кнопки нет.
+export function TelegramLoginButton({ onAuth }: { onAuth: (data: TelegramAuthData) => void }) {
+  const containerRef = useRef<HTMLDivElement>(null)
+  // Виджет зовёт глобальную функцию — держим в ref свежий onAuth, чтобы не пересоздавать скрипт
+  const onAuthRef = useRef(onAuth)
+  onAuthRef.current = onAuth
+
+  useEffect(() => {
+    const container = containerRef.current
+    if (!TELEGRAM_BOT || !container) return
+
+    const w = window as unknown as { onTelegramAuth?: (data: TelegramAuthData) => void }
+    w.onTelegramAuth = (data) => onAuthRef.current(data)
+
+    const script = document.createElement('script')
+    script.src = 'https://telegram.org/js/telegram-widget.js?22'
+    script.async = true
+    script.setAttribute('data-telegram-login', TELEGRAM_BOT)
+    script.setAttribute('data-size', 'large')
+    script.setAttribute('data-onauth', 'onTelegramAuth(user)')
+    container.appendChild(script)
+
+    return () => {
+      container.innerHTML = ''
+      delete w.onTelegramAuth
+    }
+  }, [])
+
+  if (!TELEGRAM_BOT) return null
+  return <div ref={containerRef} />
+}
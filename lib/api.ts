@@ -31,6 +31,20 @@ export type Product = {
   images: string[]
   isNew: boolean
   isOnSale: boolean
+  stock?: number
+  weightGrams?: number
+  inStock?: boolean
+  variants?: ProductVariant[]
+}
+
+export type ProductVariant = {
+  id: string
+  productId: string
+  sku: string
+  options: Record<string, string>
+  price: number | null // null = цена товара
+  stock: number
+  inStock: boolean
 }
 
 export type GalleryItem = {
@@ -49,18 +63,79 @@ export type Page = {
 
 export type User = {
   id: string
-  email: string
+  email: string // пусто — аккаунт создан входом через Telegram
   name: string
   role: string
+  emailVerified: boolean
+  totpEnabled: boolean
+  telegramId?: number
+  telegramUsername?: string
+}
+
+// Объект юзера от Telegram Login Widget — отправляем на сервер как есть (по нему проверяется подпись)
+export type TelegramAuthData = {
+  id: number
+  first_name?: string
+  last_name?: string
+  username?: string
+  photo_url?: string
+  auth_date: number
+  hash: string
+}
+
+// Активный вход (устройство) в личном кабинете
+export type Session = {
+  id: string
+  userAgent: string
+  ip: string
+  signedInAt: string
+  lastUsedAt: string
+  expiresAt: string
+  current: boolean
 }
 
 export type Order = {
   id: string
   userId: string
   status: string
+  subtotal: number
+  discountCode?: string
+  discountAmount: number
+  shippingMethod?: string
+  shippingZone?: string
+  shippingCost: number
   total: number
   createdAt: string
   items: OrderItem[]
+  history: OrderStatusChange[]
+}
+
+export type ShippingMethod = {
+  id: string
+  code: string
+  title: string
+  kind: 'courier' | 'pickup' | 'post'
+  rules: Array<{ zone: string; maxWeightGrams: number; price: number }>
+  freeFrom: number | null
+  active: boolean
+  sortOrder: number
+}
+
+export type ShippingQuote = {
+  methodId: string
+  code: string
+  title: string
+  kind: string
+  cost: number
+  available: boolean
+}
+
+export type OrderStatusChange = {
+  id: string
+  fromStatus: string
+  toStatus: string
+  comment?: string
+  createdAt: string
 }
 
 export type OrderItem = {
@@ -69,6 +144,9 @@ export type OrderItem = {
   title: string
   price: number
   quantity: number
+  variantId?: string
+  sku?: string
+  options?: Record<string, string>
 }
 
 export type ProductsResponse = {
@@ -87,47 +165,108 @@ export type OrdersResponse = {
   items: Order[]
 }
 
+// Режим сессии (как AUTH_MODE на бэкенде):
+//   bearer — access-токен лежит в localStorage и уходит в Authorization
+//   cookie — токены в HttpOnly-куках (JS их не видит), в localStorage только CSRF-токен,
+//            который уходит в X-CSRF-Token на каждом запросе
+const COOKIE_AUTH = process.env.NEXT_PUBLIC_AUTH_MODE === 'cookie'
+
+// Ответ на вход: токены (bearer) или CSRF-токен (cookie) — сервер решает сам
+export type AuthTokens = {
+  access?: string
+  refresh?: string
+  csrfToken?: string
+}
+
+// Запомнить вход после sign-in, sign-up, reauth и т.п.
+export function saveSession(tokens: AuthTokens) {
+  if (tokens.csrfToken) localStorage.setItem('csrf_token', tokens.csrfToken)
+  if (tokens.access) localStorage.setItem('access_token', tokens.access)
+}
+
+// Забыть вход (куки сервер стирает на logout, здесь — только наше)
+export function clearSession() {
+  localStorage.removeItem('access_token')
+  localStorage.removeItem('csrf_token')
+}
+
+// Есть ли вход в этом браузере (без запроса к серверу)
+export function hasSession(): boolean {
+  if (typeof window === 'undefined') return false
+  return !!localStorage.getItem(COOKIE_AUTH ? 'csrf_token' : 'access_token')
+}
+
 // Получить токен из localStorage
 function getToken(): string | null {
-  if (typeof window === 'undefined') return null
+  if (typeof window === 'undefined' || COOKIE_AUTH) return null
   return localStorage.getItem('access_token')
 }
 
-// Базовый fetch с обработкой ошибок
-async function fetchAPI<T>(
-  endpoint: string,
-  options: RequestInit = {}
-): Promise<T> {
-  const token = getToken()
+// Запрос с авторизацией текущего режима: Bearer-заголовок или куки + X-CSRF-Token
+function authFetch(endpoint: string, options: RequestInit): Promise<Response> {
+  // FormData (загрузка файлов) — Content-Type с boundary браузер выставит сам
   const headers: Record<string, string> = {
-    'Content-Type': 'application/json',
+    ...(options.body instanceof FormData ? {} : { 'Content-Type': 'application/json' }),
     ...(options.headers as Record<string, string> || {}),
   }
 
+  const token = getToken()
   if (token) {
     headers['Authorization'] = `Bearer ${token}`
   }
+  if (COOKIE_AUTH && typeof window !== 'undefined') {
+    const csrf = localStorage.getItem('csrf_token')
+    if (csrf) headers['X-CSRF-Token'] = csrf
+  }
 
   // Отключаем кеширование для всех запросов - всегда получаем свежие данные
-  const response = await fetch(`${API_URL}${endpoint}`, {
+  return fetch(`${API_URL}${endpoint}`, {
     ...options,
     headers,
     cache: 'no-store', // Не кешируем запросы
+    credentials: COOKIE_AUTH ? 'include' : options.credentials,
   })
+}
+
+// В режиме cookie access-кука живёт 15 минут — по истечении обмениваем refresh-куку
+// на новую пару (сервер ставит куки сам) и повторяем запрос один раз
+async function refreshCookieSession(): Promise<boolean> {
+  const response = await authFetch('/api/auth/refresh', { method: 'POST' })
+  if (!response.ok) return false
+  saveSession(await response.json())
+  return true
+}
+
+// Базовый fetch с обработкой ошибок
+async function fetchAPI<T>(
+  endpoint: string,
+  options: RequestInit = {}
+): Promise<T> {
+  let response = await authFetch(endpoint, options)
+
+  if (response.status === 401 && COOKIE_AUTH && hasSession() && !endpoint.startsWith('/api/auth/refresh')) {
+    if (await refreshCookieSession()) {
+      response = await authFetch(endpoint, options)
+    }
+  }
 
   if (!response.ok) {
     let errorMessage = `HTTP ${response.status}`
+    // reauthRequired — токен в порядке, но для действия нужно заново ввести пароль;
+    // mfaSetupRequired — токен в порядке, но для админки нужно включить 2FA
+    let keepToken = false
     try {
       const error = await response.json()
       errorMessage = error.error || error.message || errorMessage
+      keepToken = error.reauthRequired === true || error.mfaSetupRequired === true
     } catch {
       errorMessage = response.statusText || errorMessage
     }
     
     // Если 401 или 403 - удаляем токен (он невалидный или недостаточно прав)
-    if (response.status === 401 || response.status === 403) {
+    if ((response.status === 401 || response.status === 403) && !keepToken) {
       if (typeof window !== 'undefined') {
-        localStorage.removeItem('access_token')
+        clearSession()
       }
     }
     
@@ -171,27 +310,108 @@ export const api = {
   },
 
   // Авторизация
+  // При включённой 2FA вместо токенов приходит challenge — его обменивает verifyMfa
   signIn: (email: string, password: string) => {
-    return fetchAPI<{ access: string; refresh: string }>('/api/auth/sign-in', {
+    return fetchAPI<AuthTokens & { mfaRequired?: boolean; challenge?: string }>('/api/auth/sign-in', {
       method: 'POST',
       body: JSON.stringify({ email, password }),
     })
   },
 
-  signUp: (email: string, password: string, name: string) => {
-    return fetchAPI<{ access: string; refresh: string }>('/api/auth/sign-up', {
+  // Второй шаг входа: код из приложения или код восстановления
+  verifyMfa: (challenge: string, code: string) => {
+    return fetchAPI<AuthTokens>('/api/auth/2fa/verify', {
+      method: 'POST',
+      body: JSON.stringify({ challenge, code }),
+    })
+  },
+
+  // Вход через Telegram: аккаунт создаётся при первом входе; при 2FA — challenge, как у signIn
+  telegramSignIn: (data: TelegramAuthData) => {
+    return fetchAPI<AuthTokens & { mfaRequired?: boolean; challenge?: string }>('/api/auth/telegram', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  },
+
+  // С idempotencyKey повтор после обрыва сети получит новую сессию того же аккаунта, а не 409
+  signUp: (email: string, password: string, name: string, idempotencyKey?: string) => {
+    return fetchAPI<AuthTokens>('/api/auth/sign-up', {
       method: 'POST',
       body: JSON.stringify({ email, password, name }),
+      headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : undefined,
+    })
+  },
+
+  // Сброс пароля: письмо со ссылкой (ответ одинаковый, даже если аккаунта нет)
+  forgotPassword: (email: string) => {
+    return fetchAPI<{ message: string }>('/api/auth/password/forgot', {
+      method: 'POST',
+      body: JSON.stringify({ email }),
+    })
+  },
+
+  // Вход без пароля: письмо со ссылкой. nonce остаётся в этом браузере — ссылка сработает только здесь
+  requestMagicLink: (email: string, nonce: string) => {
+    return fetchAPI<{ message: string }>('/api/auth/magic-link', {
+      method: 'POST',
+      body: JSON.stringify({ email, nonce }),
+    })
+  },
+
+  // Обмен токена из ссылки (+ nonce этого браузера) на токены; при 2FA — challenge, как у signIn
+  verifyMagicLink: (token: string, nonce: string) => {
+    return fetchAPI<AuthTokens & { mfaRequired?: boolean; challenge?: string }>('/api/auth/magic-link/verify', {
+      method: 'POST',
+      body: JSON.stringify({ token, nonce }),
+    })
+  },
+
+  // Новый пароль по токену из письма
+  resetPassword: (token: string, password: string) => {
+    return fetchAPI<{ message: string }>('/api/auth/password/reset', {
+      method: 'POST',
+      body: JSON.stringify({ token, password }),
+    })
+  },
+
+  // Подтверждение email по токену из письма
+  confirmEmail: (token: string) => {
+    return fetchAPI<{ message: string }>('/api/auth/email/confirm', {
+      method: 'POST',
+      body: JSON.stringify({ token }),
+    })
+  },
+
+  // Отправить письмо подтверждения ещё раз (не чаще раза в минуту)
+  resendVerification: () => {
+    return fetchAPI<{ message: string }>('/api/auth/email/resend', {
+      method: 'POST',
+    })
+  },
+
+  // Повторный ввод пароля перед сменой email — сервер выдаёт новую пару токенов
+  reauthenticate: (password: string) => {
+    return fetchAPI<AuthTokens>('/api/auth/reauth', {
+      method: 'POST',
+      body: JSON.stringify({ password }),
+    })
+  },
+
+  // Завершить вход на сервере: refresh-токены этой сессии перестают работать
+  logout: () => {
+    return fetchAPI<{ message: string }>('/api/auth/logout', {
+      method: 'POST',
     })
   },
 
   isAdmin: async () => {
     try {
-      return await fetchAPI<{ isAdmin: boolean }>('/api/auth/is-admin')
+      return await fetchAPI<{ isAdmin: boolean; role: string; permissions: string[] }>('/api/auth/is-admin')
     } catch (err) {
       // Если 401 - токен невалидный, возвращаем false
       if (err instanceof Error && err.message.includes('401')) {
-        return { isAdmin: false }
+        return { isAdmin: false, role: '', permissions: [] as string[] }
       }
       throw err
     }
@@ -206,6 +426,86 @@ export const api = {
     return fetchAPI<{ items: Order[] }>('/api/account/orders')
   },
 
+  // Смена пароля: нужен текущий, остальные устройства разлогиниваются
+  changePassword: (currentPassword: string, newPassword: string) => {
+    return fetchAPI<{ message: string }>('/api/account/password', {
+      method: 'POST',
+      body: JSON.stringify({ currentPassword, newPassword }),
+    })
+  },
+
+  // Где выполнен вход: активные сессии и выход на других устройствах
+  getSessions: () => {
+    return fetchAPI<{ items: Session[] }>('/api/account/sessions')
+  },
+
+  revokeSession: (id: string) => {
+    return fetchAPI<{ message: string }>(`/api/account/sessions/${id}`, {
+      method: 'DELETE',
+    })
+  },
+
+  revokeOtherSessions: () => {
+    return fetchAPI<{ message: string }>('/api/account/sessions', {
+      method: 'DELETE',
+    })
+  },
+
+  // Двухфакторная аутентификация (TOTP)
+  setupTwoFactor: () => {
+    return fetchAPI<{ secret: string; uri: string }>('/api/account/2fa/setup', {
+      method: 'POST',
+    })
+  },
+
+  enableTwoFactor: (code: string) => {
+    return fetchAPI<AuthTokens & { recoveryCodes: string[] }>('/api/account/2fa/enable', {
+      method: 'POST',
+      body: JSON.stringify({ code }),
+    })
+  },
+
+  // Новые коды и выключение 2FA — только после недавнего ввода пароля (сначала reauthenticate)
+  regenerateRecoveryCodes: (code: string) => {
+    return fetchAPI<{ recoveryCodes: string[] }>('/api/account/2fa/recovery-codes', {
+      method: 'POST',
+      body: JSON.stringify({ code }),
+    })
+  },
+
+  disableTwoFactor: (code: string) => {
+    return fetchAPI<{ message: string }>('/api/account/2fa/disable', {
+      method: 'POST',
+      body: JSON.stringify({ code }),
+    })
+  },
+
+  // Привязка Telegram как второго способа входа (нужен недавний ввод пароля — сначала reauthenticate)
+  linkTelegram: (data: TelegramAuthData) => {
+    return fetchAPI<{ user: User }>('/api/account/telegram', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  },
+
+  unlinkTelegram: () => {
+    return fetchAPI<{ user: User }>('/api/account/telegram', {
+      method: 'DELETE',
+    })
+  },
+
+  // Персональные данные: выгрузка всего, что о юзере хранится (JSON), и удаление аккаунта.
+  // Удаление требует недавнего ввода пароля — сначала reauthenticate
+  exportAccountData: () => {
+    return fetchAPI<Record<string, unknown>>('/api/account/export')
+  },
+
+  deleteAccount: () => {
+    return fetchAPI<{ message: string }>('/api/account/delete', {
+      method: 'POST',
+    })
+  },
+
   updateProfile: (data: { name?: string; email?: string }) => {
     return fetchAPI<{ user: User }>('/api/account/me', {
       method: 'PATCH',
@@ -213,6 +513,16 @@ export const api = {
     })
   },
 
+  // Админка - загрузка изображений (товары / галерея) → { url: "/uploads/..." }
+  adminUploadImage: (kind: 'product' | 'gallery', file: File) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    return fetchAPI<{ url: string }>(`/api/admin/upload/${kind}`, {
+      method: 'POST',
+      body: formData,
+    })
+  },
+
   // Админка - Товары
   adminListProducts: () => {
     return fetchAPI<{ items: Product[] }>('/api/admin/products')
@@ -281,7 +591,7 @@ export const api = {
 
   // Создание заказа
   createOrder: (order: {
-    items: Array<{ productId: string; quantity: number; price: number }>
+    items: Array<{ productId: string; variantId?: string; quantity: number; price: number }>
     customer: {
       name: string
       email: string
@@ -290,13 +600,57 @@ export const api = {
       address?: string
     }
     comment?: string
+    promoCode?: string
+    shipping?: { methodId: string; zone: string }
     total: number
-  }) => {
+  }, idempotencyKey?: string) => {
     return fetchAPI<{ message: string; orderId?: string }>('/api/orders', {
       method: 'POST',
       body: JSON.stringify(order),
+      headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : undefined,
     })
   },
+
+  // Доставка
+  getShippingMethods: () => {
+    return fetchAPI<{ items: ShippingMethod[] }>('/api/shipping/methods')
+  },
+
+  quoteShipping: (req: {
+    zone: string
+    items: Array<{ productId: string; variantId?: string; quantity: number }>
+  }) => {
+    return fetchAPI<{ subtotal: number; weightGrams: number; items: ShippingQuote[] }>(
+      '/api/shipping/quote',
+      {
+        method: 'POST',
+        body: JSON.stringify(req),
+      }
+    )
+  },
+
+  // Оплата заказа: вернёт ссылку, куда отправить покупателя
+  createPayment: (orderId: string, provider?: string) => {
+    return fetchAPI<{ paymentId: string; confirmationUrl: string }>('/api/payments', {
+      method: 'POST',
+      body: JSON.stringify({ orderId, provider }),
+    })
+  },
+
+  // Предпросмотр скидки по промокоду
+  previewDiscount: (req: {
+    promoCode: string
+    email?: string
+    items: Array<{ productId: string; variantId?: string; quantity: number }>
+  }) => {
+    return fetchAPI<{ code: string; subtotal: number; discountAmount: number; total: number }>(
+      '/api/discounts/preview',
+      {
+        method: 'POST',
+        body: JSON.stringify(req),
+      }
+    )
+  },
 }
 
 // Форматирование цены
@@ -2,6 +2,9 @@
 
 export interface CartItem {
   productId: string
+  variantId?: string // если у товара есть варианты (размер/цвет)
+  sku?: string
+  options?: Record<string, string> // { size: 'M', color: 'black' } — для отображения в корзине
   slug: string
   title: string
   price: number
@@ -33,10 +36,15 @@ export function saveCart(cart: CartItem[]): void {
   }
 }
 
+// Позиция корзины = товар + вариант (разные размеры одного товара — разные строки)
+function isSameItem(item: CartItem, productId: string, variantId?: string): boolean {
+  return item.productId === productId && (item.variantId || '') === (variantId || '')
+}
+
 // Добавить товар в корзину
 export function addToCart(item: Omit<CartItem, 'quantity'>): void {
   const cart = getCart()
-  const existing = cart.find((i) => i.productId === item.productId)
+  const existing = cart.find((i) => isSameItem(i, item.productId, item.variantId))
 
   if (existing) {
     existing.quantity += 1
@@ -48,20 +56,20 @@ export function addToCart(item: Omit<CartItem, 'quantity'>): void {
 }
 
 // Удалить товар из корзины
-export function removeFromCart(productId: string): void {
-  const cart = getCart().filter((i) => i.productId !== productId)
+export function removeFromCart(productId: string, variantId?: string): void {
+  const cart = getCart().filter((i) => !isSameItem(i, productId, variantId))
   saveCart(cart)
 }
 
 // Изменить количество товара
-export function updateCartItemQuantity(productId: string, quantity: number): void {
+export function updateCartItemQuantity(productId: string, quantity: number, variantId?: string): void {
   if (quantity <= 0) {
-    removeFromCart(productId)
+    removeFromCart(productId, variantId)
     return
   }
 
   const cart = getCart()
-  const item = cart.find((i) => i.productId === productId)
+  const item = cart.find((i) => isSameItem(i, productId, variantId))
   if (item) {
     item.quantity = quantity
     saveCart(cart)
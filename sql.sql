@@ -9,8 +9,12 @@ CREATE TABLE products (
     images JSONB DEFAULT '[]'::jsonb, -- массив URL изображений
     is_new BOOLEAN DEFAULT false,
     is_on_sale BOOLEAN DEFAULT false,
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0), -- всего на складе
+    reserved INTEGER NOT NULL DEFAULT 0 CHECK (reserved >= 0), -- зарезервировано неотгруженными заказами
+    weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (weight_grams >= 0), -- вес для расчёта доставки
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (reserved <= stock)
 );
 
 -- Индексы для товаров
@@ -18,6 +22,22 @@ CREATE INDEX idx_products_slug ON products(slug);
 CREATE INDEX idx_products_is_new ON products(is_new);
 CREATE INDEX idx_products_is_on_sale ON products(is_on_sale);
 
+-- Варианты товара (размер/цвет) — свой SKU, цена и склад
+CREATE TABLE product_variants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(100) UNIQUE NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}'::jsonb, -- {"size": "M", "color": "black"}
+    price BIGINT, -- NULL = цена товара, иначе своя цена в копейках
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    reserved INTEGER NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (reserved <= stock)
+);
+
+CREATE INDEX idx_product_variants_product_id ON product_variants(product_id);
+
 -- Таблица элементов галереи
 CREATE TABLE gallery_items (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -43,19 +63,76 @@ CREATE TABLE pages (
 
 CREATE TABLE users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    email VARCHAR(255) UNIQUE NOT NULL,
+    email VARCHAR(255) UNIQUE, -- NULL — аккаунт создан входом через Telegram
     name VARCHAR(255) NOT NULL,
-    password_hash VARCHAR(255) NOT NULL,
-    role VARCHAR(50) DEFAULT 'user', -- 'user' или 'admin'
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    password_hash VARCHAR(255) NOT NULL DEFAULT '', -- '' — пароля нет (вход только через Telegram)
+    role VARCHAR(50) DEFAULT 'user', -- 'user', 'content-editor', 'catalog-manager', 'order-manager', 'owner' ('admin' = owner)
+    email_verified BOOLEAN NOT NULL DEFAULT false, -- перешёл по ссылке из письма
+    email_verification_sent_at TIMESTAMP, -- последнее письмо подтверждения (не чаще раза в минуту)
+    blocked_at TIMESTAMP, -- заблокирован админом; NULL — активен
+    totp_secret VARCHAR(64), -- base32-секрет TOTP; есть, а totp_enabled_at NULL — настройка не завершена
+    totp_enabled_at TIMESTAMP, -- 2FA включена
+    totp_last_step BIGINT, -- последний принятый шаг TOTP (защита от повтора кода)
+    telegram_id BIGINT UNIQUE, -- привязанный Telegram (Login Widget)
+    telegram_username VARCHAR(64), -- @username на момент последнего входа
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (email IS NOT NULL OR telegram_id IS NOT NULL) -- хоть какой-то способ войти
+);
+
+-- Способы доставки (курьер, пункт выдачи, почта)
+CREATE TABLE shipping_methods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) UNIQUE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('courier', 'pickup', 'post')),
+    rules JSONB NOT NULL DEFAULT '[]'::jsonb, -- [{"zone": "moscow", "maxWeightGrams": 5000, "price": 39000}, ...]
+    free_from BIGINT, -- сумма товаров в копейках, с которой доставка бесплатна; NULL = никогда
+    active BOOLEAN NOT NULL DEFAULT true,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Промокоды
+CREATE TABLE discounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) UNIQUE NOT NULL, -- всегда в верхнем регистре
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    value BIGINT NOT NULL CHECK (value > 0), -- процент (1..100) или сумма в копейках
+    min_order_total BIGINT NOT NULL DEFAULT 0, -- минимальная сумма заказа в копейках
+    starts_at TIMESTAMP, -- NULL = действует сразу
+    ends_at TIMESTAMP, -- NULL = бессрочно
+    usage_limit INTEGER, -- NULL = без общего лимита
+    per_customer_limit INTEGER, -- NULL = без лимита на покупателя (по email)
+    product_ids JSONB NOT NULL DEFAULT '[]'::jsonb, -- пусто = вся корзина
+    active BOOLEAN NOT NULL DEFAULT true,
+    used_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Таблица заказов
 CREATE TABLE orders (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL = гостевой заказ
     status VARCHAR(50) NOT NULL DEFAULT 'pending', -- pending | paid | shipped | delivered | cancelled
     total BIGINT NOT NULL, -- сумма в копейках (4990 = 49.90 ₽)
+    -- Контакты покупателя на момент заказа (для гостей — единственная связь с ними)
+    customer_name VARCHAR(255) NOT NULL DEFAULT '',
+    customer_email VARCHAR(255) NOT NULL DEFAULT '',
+    customer_phone VARCHAR(50) NOT NULL DEFAULT '',
+    customer_telegram VARCHAR(255) NOT NULL DEFAULT '',
+    customer_address TEXT NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    stock_reserved BOOLEAN NOT NULL DEFAULT false, -- держит ли заказ резерв на складе (снимается при отмене/отгрузке)
+    subtotal BIGINT NOT NULL DEFAULT 0, -- сумма позиций до скидки (total = subtotal - discount_amount)
+    discount_id UUID REFERENCES discounts(id) ON DELETE SET NULL,
+    discount_code VARCHAR(50) NOT NULL DEFAULT '', -- код НА МОМЕНТ заказа
+    discount_amount BIGINT NOT NULL DEFAULT 0,
+    shipping_method_id UUID REFERENCES shipping_methods(id) ON DELETE SET NULL,
+    shipping_method VARCHAR(255) NOT NULL DEFAULT '', -- название способа НА МОМЕНТ заказа
+    shipping_zone VARCHAR(50) NOT NULL DEFAULT '',
+    shipping_cost BIGINT NOT NULL DEFAULT 0, -- в копейках, входит в total
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -67,13 +144,155 @@ CREATE TABLE order_items (
     product_id UUID NOT NULL, -- ссылка на products.id (можно добавить FOREIGN KEY)
     title VARCHAR(255) NOT NULL, -- название товара НА МОМЕНТ покупки
     price BIGINT NOT NULL, -- цена за 1 шт. на момент покупки (в копейках)
-    quantity INTEGER NOT NULL DEFAULT 1
+    quantity INTEGER NOT NULL DEFAULT 1,
+    variant_id UUID, -- ссылка на product_variants.id (без FK — вариант могут удалить)
+    sku VARCHAR(100) NOT NULL DEFAULT '', -- SKU варианта НА МОМЕНТ покупки
+    options JSONB NOT NULL DEFAULT '{}'::jsonb -- опции варианта НА МОМЕНТ покупки
+);
+
+-- История статусов заказа (таймлайн для личного кабинета и аудит для админки)
+CREATE TABLE order_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50), -- NULL у первой записи (создание заказа)
+    to_status VARCHAR(50) NOT NULL,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL, -- админ; NULL = система
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Применения промокодов (по ним считаются лимиты; при отмене заказа строка удаляется)
+CREATE TABLE discount_redemptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    discount_id UUID NOT NULL REFERENCES discounts(id) ON DELETE CASCADE,
+    order_id UUID UNIQUE NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    customer_key VARCHAR(255) NOT NULL, -- LOWER(email) покупателя
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Онлайн-платежи по заказам (на один заказ может быть несколько попыток)
+CREATE TABLE payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL, -- yookassa | fake
+    provider_payment_id VARCHAR(255) NOT NULL DEFAULT '', -- ID платежа у провайдера
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending | succeeded | canceled
+    amount BIGINT NOT NULL, -- в копейках
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    confirmation_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Ключи Idempotency-Key: повтор POST с тем же ключом получает сохранённый ответ
+CREATE TABLE idempotency_keys (
+    scope VARCHAR(64) NOT NULL DEFAULT '', -- userID или '' для анонимных запросов
+    key VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL, -- sha256 метода, пути и тела
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress', -- in_progress | completed
+    response_status INTEGER,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    response_body BYTEA,
+    resource_id VARCHAR(64) NOT NULL DEFAULT '', -- ID созданного объекта вместо ответа, если ответ хранить нельзя (sign-up: в нём токены)
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (scope, key)
+);
+
+-- Refresh-токены: храним только sha256, каждый рефреш выдаёт новый токен той же семьи
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY, -- jti из JWT
+    family_id UUID NOT NULL, -- один вход (логин); logout отзывает всю семью
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    auth_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- последний ввод пароля в этом входе (логин или reauth)
+    mfa BOOLEAN NOT NULL DEFAULT false, -- вход подтверждён вторым фактором
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP, -- обменян на новый; повторное предъявление = кража, отзываем семью
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Токены сброса пароля: одноразовые, живут час, храним только sha256
+CREATE TABLE password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- NULL = ещё не использован
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Ссылки для входа без пароля: id — jti подписанного токена из письма
+CREATE TABLE magic_links (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    nonce_hash VARCHAR(64) NOT NULL, -- sha256 nonce браузера, запросившего ссылку
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- NULL = ещё не использована
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Коды восстановления 2FA: одноразовые, храним только sha256
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Неудачные попытки входа: одна строка на email или IP, общая для всех инстансов
+CREATE TABLE login_throttles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(20) NOT NULL, -- email | ip | magic-link-ip (лимит запросов ссылок для входа)
+    subject VARCHAR(255) NOT NULL, -- email в нижнем регистре или IP
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP, -- до этого момента вход по ключу закрыт
+    UNIQUE (kind, subject)
+);
+
+-- Outbox уведомлений: хендлеры пишут сюда, фоновый воркер отправляет с ретраями
+CREATE TABLE notification_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel VARCHAR(50) NOT NULL, -- telegram | email
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending | sent | failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP, -- аренда воркера, чтобы две реплики не отправили одно и то же
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Индексы для производительности
 CREATE INDEX idx_orders_user_id ON orders(user_id);
 CREATE INDEX idx_orders_created_at ON orders(created_at DESC);
+CREATE INDEX idx_orders_status ON orders(status);
+CREATE INDEX idx_orders_guest_email ON orders(LOWER(customer_email)) WHERE user_id IS NULL;
 CREATE INDEX idx_order_items_order_id ON order_items(order_id);
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id, created_at);
+CREATE INDEX idx_payments_order_id ON payments(order_id);
+CREATE UNIQUE INDEX idx_payments_provider_id ON payments(provider, provider_payment_id) WHERE provider_payment_id <> '';
+-- Не больше одного незавершённого платежа на заказ: две живые ссылки на оплату = двойное списание
+CREATE UNIQUE INDEX idx_payments_pending_order ON payments(order_id) WHERE status = 'pending';
+CREATE INDEX idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_discount_redemptions_customer ON discount_redemptions(discount_id, customer_key);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id, created_at);
+CREATE INDEX idx_magic_links_user_id ON magic_links(user_id, created_at);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes(user_id, code_hash);
+CREATE INDEX idx_login_throttles_last_failure_at ON login_throttles(last_failure_at DESC);
 
 -- Начальные данные
 INSERT INTO pages (slug, title, content) VALUES